@@ -0,0 +1,227 @@
+package main
+
+//go test -benchmem -run=^$ -bench ^Benchmark_Fluent_Forward_Go_Local.*$ github.com/IBM/fluent-forward-go/cmd/bm/fluent_forward_go
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IBM/fluent-forward-go/cmd/bm"
+	"github.com/IBM/fluent-forward-go/fluent/client"
+	"github.com/IBM/fluent-forward-go/fluent/client/ws"
+	"github.com/IBM/fluent-forward-go/fluent/protocol"
+	"github.com/gorilla/websocket"
+)
+
+// The Local benchmarks send to in-process sinks that discard everything they
+// read, so they measure the client alone and need no Fluent endpoint. The
+// sinks read raw bytes without parsing them, so they add no allocations of
+// their own.
+
+func discard(conn net.Conn) {
+	_, _ = io.Copy(io.Discard, conn)
+	conn.Close()
+}
+
+func newTCPSink(b *testing.B) net.Listener {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			go discard(conn)
+		}
+	}()
+
+	return ln
+}
+
+func newWSSink() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var upgrader websocket.Upgrader
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+
+		discard(conn.UnderlyingConn())
+	}))
+}
+
+func wsURL(svr *httptest.Server) string {
+	return "ws" + strings.TrimPrefix(svr.URL, "http")
+}
+
+func newLocalWSClient(tb testing.TB, svr *httptest.Server) *client.WSClient {
+	c := client.NewWS(client.WSConnectionOptions{
+		Factory: &client.DefaultWSConnectionFactory{
+			URL: wsURL(svr),
+		},
+		ConnectionOptions: ws.ConnectionOptions{
+			// the sink never answers the closing handshake
+			CloseDeadline: 10 * time.Millisecond,
+		},
+	})
+
+	if err := c.Connect(); err != nil {
+		tb.Fatal(err)
+	}
+
+	return c
+}
+
+// Test_Fluent_Forward_Go_Local_WS_Allocs checks that the websocket client
+// adds no allocations to the one gorilla/websocket makes for every frame a
+// client writes.
+func Test_Fluent_Forward_Go_Local_WS_Allocs(t *testing.T) {
+	svr := newWSSink()
+	defer svr.Close()
+
+	c := newLocalWSClient(t, svr)
+	defer c.Disconnect()
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL(svr), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	mne := protocol.NewMessage("bar", bm.MakeRecord(12))
+
+	bits, err := mne.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	frame := testing.AllocsPerRun(100, func() {
+		w, _ := conn.NextWriter(websocket.BinaryMessage)
+		_, _ = w.Write(bits)
+		_ = w.Close()
+	})
+
+	send := testing.AllocsPerRun(100, func() {
+		_ = c.Send(mne)
+	})
+
+	raw := testing.AllocsPerRun(100, func() {
+		_ = c.SendRaw(bits)
+	})
+
+	if send != frame || raw != frame {
+		t.Errorf("Send makes %v and SendRaw %v allocs/op beyond the %v of gorilla/websocket", send-frame, raw-frame, frame)
+	}
+}
+
+func Benchmark_Fluent_Forward_Go_Local_SendOnly(b *testing.B) {
+	ln := newTCPSink(b)
+	defer ln.Close()
+
+	c := client.New(client.ConnectionOptions{
+		Factory: &client.ConnFactory{
+			Address: ln.Addr().String(),
+		},
+		ConnectionTimeout: 3 * time.Second,
+	})
+
+	err := c.Connect()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	defer c.Disconnect()
+
+	record := bm.MakeRecord(12)
+	mne := protocol.NewMessage("bar", record)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		err = c.Send(mne)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func Benchmark_Fluent_Forward_Go_Local_WS_SendOnly(b *testing.B) {
+	svr := newWSSink()
+	defer svr.Close()
+
+	c := newLocalWSClient(b, svr)
+	defer c.Disconnect()
+
+	record := bm.MakeRecord(12)
+	mne := protocol.NewMessage("bar", record)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if err := c.Send(mne); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func Benchmark_Fluent_Forward_Go_Local_WS_Bytes(b *testing.B) {
+	svr := newWSSink()
+	defer svr.Close()
+
+	c := newLocalWSClient(b, svr)
+	defer c.Disconnect()
+
+	record := bm.MakeRecord(12)
+	mne := protocol.NewMessage("bar", record)
+
+	bits, _ := mne.MarshalMsg(nil)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if err := c.SendRaw(bits); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func Benchmark_Fluent_Forward_Go_Local_WS_Batch(b *testing.B) {
+	svr := newWSSink()
+	defer svr.Close()
+
+	c := newLocalWSClient(b, svr)
+	defer c.Disconnect()
+
+	batcher := client.NewWSBatcher(c, client.WSBatchOptions{
+		MaxMessages:   100,
+		FlushInterval: -1,
+	})
+	defer batcher.Close()
+
+	record := bm.MakeRecord(12)
+	mne := protocol.NewMessage("bar", record)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if err := batcher.Send(mne); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+// Package example shows the code generated by fluentgen.
+package example
+
+import (
+	"time"
+
+	"github.com/tinylib/msgp/msgp"
+)
+
+//go:generate go run github.com/IBM/fluent-forward-go/cmd/fluentgen -type AccessLog,Client
+
+type Level string
+
+// AccessLog is a web server access log record.
+type AccessLog struct {
+	Level    Level              `msg:"level"`
+	Method   string             `msg:"method"`
+	Path     string             `msg:"path"`
+	Status   int                `msg:"status"`
+	Bytes    uint64             `msg:"bytes"`
+	Duration time.Duration      `msg:"duration"`
+	Start    time.Time          `msg:"start"`
+	Body     []byte             `msg:"body,omitempty"`
+	Tags     []string           `msg:"tags"`
+	Headers  map[string]string  `msg:"headers"`
+	Timings  map[string]float64 `msg:"timings"`
+	Extra    interface{}        `msg:"extra"`
+	Client   *Client            `msg:"client"`
+	Upstream []Client           `msg:"upstream"`
+	Raw      msgp.Raw           `msg:"raw"`
+	Secret   string             `msg:"-"`
+}
+
+// Client identifies the client of a request.
+type Client struct {
+	Addr      string
+	UserAgent string `msg:"user_agent"`
+}
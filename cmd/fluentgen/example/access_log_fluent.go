@@ -0,0 +1,1004 @@
+// Code generated by fluentgen. DO NOT EDIT.
+
+package example
+
+import (
+	"context"
+	"time"
+
+	"github.com/IBM/fluent-forward-go/fluent/client"
+	"github.com/IBM/fluent-forward-go/fluent/protocol"
+	"github.com/IBM/fluent-forward-go/fluent/server"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// DecodeMsg implements msgp.Decodable
+func (z *AccessLog) DecodeMsg(dc *msgp.Reader) (err error) {
+	var field []byte
+	_ = field
+	var zb0001 uint32
+	zb0001, err = dc.ReadMapHeader()
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	for zb0001 > 0 {
+		zb0001--
+		field, err = dc.ReadMapKeyPtr()
+		if err != nil {
+			err = msgp.WrapError(err)
+			return
+		}
+		switch msgp.UnsafeString(field) {
+		case "level":
+			var zb0002 string
+			zb0002, err = dc.ReadString()
+			if err != nil {
+				err = msgp.WrapError(err, "Level")
+				return
+			}
+			z.Level = Level(zb0002)
+		case "method":
+			z.Method, err = dc.ReadString()
+			if err != nil {
+				err = msgp.WrapError(err, "Method")
+				return
+			}
+		case "path":
+			z.Path, err = dc.ReadString()
+			if err != nil {
+				err = msgp.WrapError(err, "Path")
+				return
+			}
+		case "status":
+			z.Status, err = dc.ReadInt()
+			if err != nil {
+				err = msgp.WrapError(err, "Status")
+				return
+			}
+		case "bytes":
+			z.Bytes, err = dc.ReadUint64()
+			if err != nil {
+				err = msgp.WrapError(err, "Bytes")
+				return
+			}
+		case "duration":
+			z.Duration, err = dc.ReadDuration()
+			if err != nil {
+				err = msgp.WrapError(err, "Duration")
+				return
+			}
+		case "start":
+			z.Start, err = dc.ReadTime()
+			if err != nil {
+				err = msgp.WrapError(err, "Start")
+				return
+			}
+		case "body":
+			z.Body, err = dc.ReadBytes(z.Body)
+			if err != nil {
+				err = msgp.WrapError(err, "Body")
+				return
+			}
+		case "tags":
+			var zb0003 uint32
+			zb0003, err = dc.ReadArrayHeader()
+			if err != nil {
+				err = msgp.WrapError(err, "Tags")
+				return
+			}
+			if cap(z.Tags) >= int(zb0003) {
+				z.Tags = (z.Tags)[:zb0003]
+			} else {
+				z.Tags = make([]string, zb0003)
+			}
+			for zb0004 := range z.Tags {
+				z.Tags[zb0004], err = dc.ReadString()
+				if err != nil {
+					err = msgp.WrapError(err, "Tags")
+					return
+				}
+			}
+		case "headers":
+			var zb0005 uint32
+			zb0005, err = dc.ReadMapHeader()
+			if err != nil {
+				err = msgp.WrapError(err, "Headers")
+				return
+			}
+			if z.Headers == nil {
+				z.Headers = make(map[string]string, zb0005)
+			} else if len(z.Headers) > 0 {
+				for zb0008 := range z.Headers {
+					delete(z.Headers, zb0008)
+				}
+			}
+			for zb0005 > 0 {
+				zb0005--
+				var zb0006 string
+				var zb0007 string
+				zb0006, err = dc.ReadString()
+				if err != nil {
+					err = msgp.WrapError(err, "Headers")
+					return
+				}
+				zb0007, err = dc.ReadString()
+				if err != nil {
+					err = msgp.WrapError(err, "Headers")
+					return
+				}
+				z.Headers[zb0006] = zb0007
+			}
+		case "timings":
+			var zb0009 uint32
+			zb0009, err = dc.ReadMapHeader()
+			if err != nil {
+				err = msgp.WrapError(err, "Timings")
+				return
+			}
+			if z.Timings == nil {
+				z.Timings = make(map[string]float64, zb0009)
+			} else if len(z.Timings) > 0 {
+				for zb0012 := range z.Timings {
+					delete(z.Timings, zb0012)
+				}
+			}
+			for zb0009 > 0 {
+				zb0009--
+				var zb0010 string
+				var zb0011 float64
+				zb0010, err = dc.ReadString()
+				if err != nil {
+					err = msgp.WrapError(err, "Timings")
+					return
+				}
+				zb0011, err = dc.ReadFloat64()
+				if err != nil {
+					err = msgp.WrapError(err, "Timings")
+					return
+				}
+				z.Timings[zb0010] = zb0011
+			}
+		case "extra":
+			z.Extra, err = dc.ReadIntf()
+			if err != nil {
+				err = msgp.WrapError(err, "Extra")
+				return
+			}
+		case "client":
+			if dc.IsNil() {
+				err = dc.ReadNil()
+				if err != nil {
+					err = msgp.WrapError(err, "Client")
+					return
+				}
+				z.Client = nil
+			} else {
+				if z.Client == nil {
+					z.Client = new(Client)
+				}
+				err = z.Client.DecodeMsg(dc)
+				if err != nil {
+					err = msgp.WrapError(err, "Client")
+					return
+				}
+			}
+		case "upstream":
+			var zb0013 uint32
+			zb0013, err = dc.ReadArrayHeader()
+			if err != nil {
+				err = msgp.WrapError(err, "Upstream")
+				return
+			}
+			if cap(z.Upstream) >= int(zb0013) {
+				z.Upstream = (z.Upstream)[:zb0013]
+			} else {
+				z.Upstream = make([]Client, zb0013)
+			}
+			for zb0014 := range z.Upstream {
+				err = z.Upstream[zb0014].DecodeMsg(dc)
+				if err != nil {
+					err = msgp.WrapError(err, "Upstream")
+					return
+				}
+			}
+		case "raw":
+			err = z.Raw.DecodeMsg(dc)
+			if err != nil {
+				err = msgp.WrapError(err, "Raw")
+				return
+			}
+		default:
+			err = dc.Skip()
+			if err != nil {
+				err = msgp.WrapError(err)
+				return
+			}
+		}
+	}
+	return
+}
+
+// EncodeMsg implements msgp.Encodable
+func (z *AccessLog) EncodeMsg(en *msgp.Writer) (err error) {
+	err = en.WriteMapHeader(15)
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	err = en.WriteString("level")
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	err = en.WriteString(string(z.Level))
+	if err != nil {
+		err = msgp.WrapError(err, "Level")
+		return
+	}
+	err = en.WriteString("method")
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	err = en.WriteString(z.Method)
+	if err != nil {
+		err = msgp.WrapError(err, "Method")
+		return
+	}
+	err = en.WriteString("path")
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	err = en.WriteString(z.Path)
+	if err != nil {
+		err = msgp.WrapError(err, "Path")
+		return
+	}
+	err = en.WriteString("status")
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	err = en.WriteInt(z.Status)
+	if err != nil {
+		err = msgp.WrapError(err, "Status")
+		return
+	}
+	err = en.WriteString("bytes")
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	err = en.WriteUint64(z.Bytes)
+	if err != nil {
+		err = msgp.WrapError(err, "Bytes")
+		return
+	}
+	err = en.WriteString("duration")
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	err = en.WriteDuration(z.Duration)
+	if err != nil {
+		err = msgp.WrapError(err, "Duration")
+		return
+	}
+	err = en.WriteString("start")
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	err = en.WriteTime(z.Start)
+	if err != nil {
+		err = msgp.WrapError(err, "Start")
+		return
+	}
+	err = en.WriteString("body")
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	err = en.WriteBytes(z.Body)
+	if err != nil {
+		err = msgp.WrapError(err, "Body")
+		return
+	}
+	err = en.WriteString("tags")
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	err = en.WriteArrayHeader(uint32(len(z.Tags)))
+	if err != nil {
+		err = msgp.WrapError(err, "Tags")
+		return
+	}
+	for _, zb0015 := range z.Tags {
+		err = en.WriteString(zb0015)
+		if err != nil {
+			err = msgp.WrapError(err, "Tags")
+			return
+		}
+	}
+	err = en.WriteString("headers")
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	err = en.WriteMapHeader(uint32(len(z.Headers)))
+	if err != nil {
+		err = msgp.WrapError(err, "Headers")
+		return
+	}
+	for zb0016, zb0017 := range z.Headers {
+		err = en.WriteString(zb0016)
+		if err != nil {
+			err = msgp.WrapError(err, "Headers")
+			return
+		}
+		err = en.WriteString(zb0017)
+		if err != nil {
+			err = msgp.WrapError(err, "Headers")
+			return
+		}
+	}
+	err = en.WriteString("timings")
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	err = en.WriteMapHeader(uint32(len(z.Timings)))
+	if err != nil {
+		err = msgp.WrapError(err, "Timings")
+		return
+	}
+	for zb0018, zb0019 := range z.Timings {
+		err = en.WriteString(zb0018)
+		if err != nil {
+			err = msgp.WrapError(err, "Timings")
+			return
+		}
+		err = en.WriteFloat64(zb0019)
+		if err != nil {
+			err = msgp.WrapError(err, "Timings")
+			return
+		}
+	}
+	err = en.WriteString("extra")
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	err = en.WriteIntf(z.Extra)
+	if err != nil {
+		err = msgp.WrapError(err, "Extra")
+		return
+	}
+	err = en.WriteString("client")
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	if z.Client == nil {
+		err = en.WriteNil()
+	} else {
+		err = z.Client.EncodeMsg(en)
+	}
+	if err != nil {
+		err = msgp.WrapError(err, "Client")
+		return
+	}
+	err = en.WriteString("upstream")
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	err = en.WriteArrayHeader(uint32(len(z.Upstream)))
+	if err != nil {
+		err = msgp.WrapError(err, "Upstream")
+		return
+	}
+	for _, zb0020 := range z.Upstream {
+		err = zb0020.EncodeMsg(en)
+		if err != nil {
+			err = msgp.WrapError(err, "Upstream")
+			return
+		}
+	}
+	err = en.WriteString("raw")
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	err = z.Raw.EncodeMsg(en)
+	if err != nil {
+		err = msgp.WrapError(err, "Raw")
+		return
+	}
+	return
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (z *AccessLog) MarshalMsg(b []byte) (o []byte, err error) {
+	o = msgp.Require(b, z.Msgsize())
+	o = msgp.AppendMapHeader(o, 15)
+	o = msgp.AppendString(o, "level")
+	o = msgp.AppendString(o, string(z.Level))
+	o = msgp.AppendString(o, "method")
+	o = msgp.AppendString(o, z.Method)
+	o = msgp.AppendString(o, "path")
+	o = msgp.AppendString(o, z.Path)
+	o = msgp.AppendString(o, "status")
+	o = msgp.AppendInt(o, z.Status)
+	o = msgp.AppendString(o, "bytes")
+	o = msgp.AppendUint64(o, z.Bytes)
+	o = msgp.AppendString(o, "duration")
+	o = msgp.AppendDuration(o, z.Duration)
+	o = msgp.AppendString(o, "start")
+	o = msgp.AppendTime(o, z.Start)
+	o = msgp.AppendString(o, "body")
+	o = msgp.AppendBytes(o, z.Body)
+	o = msgp.AppendString(o, "tags")
+	o = msgp.AppendArrayHeader(o, uint32(len(z.Tags)))
+	for _, zb0021 := range z.Tags {
+		o = msgp.AppendString(o, zb0021)
+	}
+	o = msgp.AppendString(o, "headers")
+	o = msgp.AppendMapHeader(o, uint32(len(z.Headers)))
+	for zb0022, zb0023 := range z.Headers {
+		o = msgp.AppendString(o, zb0022)
+		o = msgp.AppendString(o, zb0023)
+	}
+	o = msgp.AppendString(o, "timings")
+	o = msgp.AppendMapHeader(o, uint32(len(z.Timings)))
+	for zb0024, zb0025 := range z.Timings {
+		o = msgp.AppendString(o, zb0024)
+		o = msgp.AppendFloat64(o, zb0025)
+	}
+	o = msgp.AppendString(o, "extra")
+	o, err = msgp.AppendIntf(o, z.Extra)
+	if err != nil {
+		err = msgp.WrapError(err, "Extra")
+		return
+	}
+	o = msgp.AppendString(o, "client")
+	if z.Client == nil {
+		o = msgp.AppendNil(o)
+	} else {
+		o, err = z.Client.MarshalMsg(o)
+		if err != nil {
+			err = msgp.WrapError(err, "Client")
+			return
+		}
+	}
+	o = msgp.AppendString(o, "upstream")
+	o = msgp.AppendArrayHeader(o, uint32(len(z.Upstream)))
+	for _, zb0026 := range z.Upstream {
+		o, err = zb0026.MarshalMsg(o)
+		if err != nil {
+			err = msgp.WrapError(err, "Upstream")
+			return
+		}
+	}
+	o = msgp.AppendString(o, "raw")
+	o, err = z.Raw.MarshalMsg(o)
+	if err != nil {
+		err = msgp.WrapError(err, "Raw")
+		return
+	}
+	return
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (z *AccessLog) UnmarshalMsg(bts []byte) (o []byte, err error) {
+	var field []byte
+	_ = field
+	var zb0027 uint32
+	zb0027, bts, err = msgp.ReadMapHeaderBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	for zb0027 > 0 {
+		zb0027--
+		field, bts, err = msgp.ReadMapKeyZC(bts)
+		if err != nil {
+			err = msgp.WrapError(err)
+			return
+		}
+		switch msgp.UnsafeString(field) {
+		case "level":
+			var zb0028 string
+			zb0028, bts, err = msgp.ReadStringBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Level")
+				return
+			}
+			z.Level = Level(zb0028)
+		case "method":
+			z.Method, bts, err = msgp.ReadStringBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Method")
+				return
+			}
+		case "path":
+			z.Path, bts, err = msgp.ReadStringBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Path")
+				return
+			}
+		case "status":
+			z.Status, bts, err = msgp.ReadIntBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Status")
+				return
+			}
+		case "bytes":
+			z.Bytes, bts, err = msgp.ReadUint64Bytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Bytes")
+				return
+			}
+		case "duration":
+			z.Duration, bts, err = msgp.ReadDurationBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Duration")
+				return
+			}
+		case "start":
+			z.Start, bts, err = msgp.ReadTimeBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Start")
+				return
+			}
+		case "body":
+			z.Body, bts, err = msgp.ReadBytesBytes(bts, z.Body)
+			if err != nil {
+				err = msgp.WrapError(err, "Body")
+				return
+			}
+		case "tags":
+			var zb0029 uint32
+			zb0029, bts, err = msgp.ReadArrayHeaderBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Tags")
+				return
+			}
+			if cap(z.Tags) >= int(zb0029) {
+				z.Tags = (z.Tags)[:zb0029]
+			} else {
+				z.Tags = make([]string, zb0029)
+			}
+			for zb0030 := range z.Tags {
+				z.Tags[zb0030], bts, err = msgp.ReadStringBytes(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "Tags")
+					return
+				}
+			}
+		case "headers":
+			var zb0031 uint32
+			zb0031, bts, err = msgp.ReadMapHeaderBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Headers")
+				return
+			}
+			if z.Headers == nil {
+				z.Headers = make(map[string]string, zb0031)
+			} else if len(z.Headers) > 0 {
+				for zb0034 := range z.Headers {
+					delete(z.Headers, zb0034)
+				}
+			}
+			for zb0031 > 0 {
+				zb0031--
+				var zb0032 string
+				var zb0033 string
+				zb0032, bts, err = msgp.ReadStringBytes(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "Headers")
+					return
+				}
+				zb0033, bts, err = msgp.ReadStringBytes(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "Headers")
+					return
+				}
+				z.Headers[zb0032] = zb0033
+			}
+		case "timings":
+			var zb0035 uint32
+			zb0035, bts, err = msgp.ReadMapHeaderBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Timings")
+				return
+			}
+			if z.Timings == nil {
+				z.Timings = make(map[string]float64, zb0035)
+			} else if len(z.Timings) > 0 {
+				for zb0038 := range z.Timings {
+					delete(z.Timings, zb0038)
+				}
+			}
+			for zb0035 > 0 {
+				zb0035--
+				var zb0036 string
+				var zb0037 float64
+				zb0036, bts, err = msgp.ReadStringBytes(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "Timings")
+					return
+				}
+				zb0037, bts, err = msgp.ReadFloat64Bytes(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "Timings")
+					return
+				}
+				z.Timings[zb0036] = zb0037
+			}
+		case "extra":
+			z.Extra, bts, err = msgp.ReadIntfBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Extra")
+				return
+			}
+		case "client":
+			if msgp.IsNil(bts) {
+				bts, err = msgp.ReadNilBytes(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "Client")
+					return
+				}
+				z.Client = nil
+			} else {
+				if z.Client == nil {
+					z.Client = new(Client)
+				}
+				bts, err = z.Client.UnmarshalMsg(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "Client")
+					return
+				}
+			}
+		case "upstream":
+			var zb0039 uint32
+			zb0039, bts, err = msgp.ReadArrayHeaderBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Upstream")
+				return
+			}
+			if cap(z.Upstream) >= int(zb0039) {
+				z.Upstream = (z.Upstream)[:zb0039]
+			} else {
+				z.Upstream = make([]Client, zb0039)
+			}
+			for zb0040 := range z.Upstream {
+				bts, err = z.Upstream[zb0040].UnmarshalMsg(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "Upstream")
+					return
+				}
+			}
+		case "raw":
+			bts, err = z.Raw.UnmarshalMsg(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Raw")
+				return
+			}
+		default:
+			bts, err = msgp.Skip(bts)
+			if err != nil {
+				err = msgp.WrapError(err)
+				return
+			}
+		}
+	}
+	o = bts
+	return
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
+func (z *AccessLog) Msgsize() (s int) {
+	s = msgp.MapHeaderSize
+	s += msgp.StringPrefixSize + 5
+	s += msgp.StringPrefixSize + len(z.Level)
+	s += msgp.StringPrefixSize + 6
+	s += msgp.StringPrefixSize + len(z.Method)
+	s += msgp.StringPrefixSize + 4
+	s += msgp.StringPrefixSize + len(z.Path)
+	s += msgp.StringPrefixSize + 6
+	s += msgp.IntSize
+	s += msgp.StringPrefixSize + 5
+	s += msgp.Uint64Size
+	s += msgp.StringPrefixSize + 8
+	s += msgp.DurationSize
+	s += msgp.StringPrefixSize + 5
+	s += msgp.TimeSize
+	s += msgp.StringPrefixSize + 4
+	s += msgp.BytesPrefixSize + len(z.Body)
+	s += msgp.StringPrefixSize + 4
+	s += msgp.ArrayHeaderSize
+	for _, zb0041 := range z.Tags {
+		s += msgp.StringPrefixSize + len(zb0041)
+	}
+	s += msgp.StringPrefixSize + 7
+	s += msgp.MapHeaderSize
+	for zb0042, zb0043 := range z.Headers {
+		s += msgp.StringPrefixSize + len(zb0042)
+		s += msgp.StringPrefixSize + len(zb0043)
+	}
+	s += msgp.StringPrefixSize + 7
+	s += msgp.MapHeaderSize
+	for zb0044 := range z.Timings {
+		s += msgp.StringPrefixSize + len(zb0044) + msgp.Float64Size
+	}
+	s += msgp.StringPrefixSize + 5
+	s += msgp.GuessSize(z.Extra)
+	s += msgp.StringPrefixSize + 6
+	if z.Client == nil {
+		s += msgp.NilSize
+	} else {
+		s += z.Client.Msgsize()
+	}
+	s += msgp.StringPrefixSize + 8
+	s += msgp.ArrayHeaderSize
+	for _, zb0046 := range z.Upstream {
+		s += zb0046.Msgsize()
+	}
+	s += msgp.StringPrefixSize + 3
+	s += z.Raw.Msgsize()
+	return
+}
+
+// AccessLogSender sends AccessLog records through a client.MessageClient.
+type AccessLogSender struct {
+	client.MessageClient
+}
+
+// SendAccessLog sends rec as a Message.
+func (s AccessLogSender) SendAccessLog(tag string, rec *AccessLog) error {
+	return s.SendMessage(tag, rec)
+}
+
+// SendAccessLogBatch sends recs as a single ForwardMessage.
+func (s AccessLogSender) SendAccessLogBatch(tag string, recs []*AccessLog) error {
+	entries := make(protocol.EntryList, len(recs))
+	for i, rec := range recs {
+		entries[i] = protocol.EntryExt{
+			Timestamp: protocol.EventTimeNow(),
+			Record:    rec,
+		}
+	}
+
+	return s.SendForward(tag, entries)
+}
+
+// DecodeAccessLog decodes the record of an event received by a
+// server.EventHandler.
+func DecodeAccessLog(entry protocol.EntryExt) (*AccessLog, error) {
+	bts, err := msgp.AppendIntf(nil, entry.Record)
+	if err != nil {
+		return nil, err
+	}
+
+	rec := &AccessLog{}
+	if _, err = rec.UnmarshalMsg(bts); err != nil {
+		return nil, err
+	}
+
+	return rec, nil
+}
+
+// AccessLogHandler returns a server.EventHandler that decodes every event into a
+// AccessLog and passes it to fn.
+func AccessLogHandler(fn func(ctx context.Context, tag string, t time.Time, rec *AccessLog) error) server.EventHandler {
+	return server.EventHandlerFunc(func(ctx context.Context, tag string, entries protocol.EntryList) error {
+		for _, entry := range entries {
+			rec, err := DecodeAccessLog(entry)
+			if err != nil {
+				return err
+			}
+
+			if err = fn(ctx, tag, entry.Timestamp.Time, rec); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// DecodeMsg implements msgp.Decodable
+func (z *Client) DecodeMsg(dc *msgp.Reader) (err error) {
+	var field []byte
+	_ = field
+	var zb0047 uint32
+	zb0047, err = dc.ReadMapHeader()
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	for zb0047 > 0 {
+		zb0047--
+		field, err = dc.ReadMapKeyPtr()
+		if err != nil {
+			err = msgp.WrapError(err)
+			return
+		}
+		switch msgp.UnsafeString(field) {
+		case "Addr":
+			z.Addr, err = dc.ReadString()
+			if err != nil {
+				err = msgp.WrapError(err, "Addr")
+				return
+			}
+		case "user_agent":
+			z.UserAgent, err = dc.ReadString()
+			if err != nil {
+				err = msgp.WrapError(err, "UserAgent")
+				return
+			}
+		default:
+			err = dc.Skip()
+			if err != nil {
+				err = msgp.WrapError(err)
+				return
+			}
+		}
+	}
+	return
+}
+
+// EncodeMsg implements msgp.Encodable
+func (z *Client) EncodeMsg(en *msgp.Writer) (err error) {
+	err = en.WriteMapHeader(2)
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	err = en.WriteString("Addr")
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	err = en.WriteString(z.Addr)
+	if err != nil {
+		err = msgp.WrapError(err, "Addr")
+		return
+	}
+	err = en.WriteString("user_agent")
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	err = en.WriteString(z.UserAgent)
+	if err != nil {
+		err = msgp.WrapError(err, "UserAgent")
+		return
+	}
+	return
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (z *Client) MarshalMsg(b []byte) (o []byte, err error) {
+	o = msgp.Require(b, z.Msgsize())
+	o = msgp.AppendMapHeader(o, 2)
+	o = msgp.AppendString(o, "Addr")
+	o = msgp.AppendString(o, z.Addr)
+	o = msgp.AppendString(o, "user_agent")
+	o = msgp.AppendString(o, z.UserAgent)
+	return
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (z *Client) UnmarshalMsg(bts []byte) (o []byte, err error) {
+	var field []byte
+	_ = field
+	var zb0048 uint32
+	zb0048, bts, err = msgp.ReadMapHeaderBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	for zb0048 > 0 {
+		zb0048--
+		field, bts, err = msgp.ReadMapKeyZC(bts)
+		if err != nil {
+			err = msgp.WrapError(err)
+			return
+		}
+		switch msgp.UnsafeString(field) {
+		case "Addr":
+			z.Addr, bts, err = msgp.ReadStringBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Addr")
+				return
+			}
+		case "user_agent":
+			z.UserAgent, bts, err = msgp.ReadStringBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "UserAgent")
+				return
+			}
+		default:
+			bts, err = msgp.Skip(bts)
+			if err != nil {
+				err = msgp.WrapError(err)
+				return
+			}
+		}
+	}
+	o = bts
+	return
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
+func (z *Client) Msgsize() (s int) {
+	s = msgp.MapHeaderSize
+	s += msgp.StringPrefixSize + 4
+	s += msgp.StringPrefixSize + len(z.Addr)
+	s += msgp.StringPrefixSize + 10
+	s += msgp.StringPrefixSize + len(z.UserAgent)
+	return
+}
+
+// ClientSender sends Client records through a client.MessageClient.
+type ClientSender struct {
+	client.MessageClient
+}
+
+// SendClient sends rec as a Message.
+func (s ClientSender) SendClient(tag string, rec *Client) error {
+	return s.SendMessage(tag, rec)
+}
+
+// SendClientBatch sends recs as a single ForwardMessage.
+func (s ClientSender) SendClientBatch(tag string, recs []*Client) error {
+	entries := make(protocol.EntryList, len(recs))
+	for i, rec := range recs {
+		entries[i] = protocol.EntryExt{
+			Timestamp: protocol.EventTimeNow(),
+			Record:    rec,
+		}
+	}
+
+	return s.SendForward(tag, entries)
+}
+
+// DecodeClient decodes the record of an event received by a
+// server.EventHandler.
+func DecodeClient(entry protocol.EntryExt) (*Client, error) {
+	bts, err := msgp.AppendIntf(nil, entry.Record)
+	if err != nil {
+		return nil, err
+	}
+
+	rec := &Client{}
+	if _, err = rec.UnmarshalMsg(bts); err != nil {
+		return nil, err
+	}
+
+	return rec, nil
+}
+
+// ClientHandler returns a server.EventHandler that decodes every event into a
+// Client and passes it to fn.
+func ClientHandler(fn func(ctx context.Context, tag string, t time.Time, rec *Client) error) server.EventHandler {
+	return server.EventHandlerFunc(func(ctx context.Context, tag string, entries protocol.EntryList) error {
+		for _, entry := range entries {
+			rec, err := DecodeClient(entry)
+			if err != nil {
+				return err
+			}
+
+			if err = fn(ctx, tag, entry.Timestamp.Time, rec); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
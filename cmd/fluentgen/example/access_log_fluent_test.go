@@ -0,0 +1,216 @@
+// Code generated by fluentgen. DO NOT EDIT.
+
+package example
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/IBM/fluent-forward-go/fluent/client/clientfakes"
+	"github.com/IBM/fluent-forward-go/fluent/protocol"
+	"github.com/tinylib/msgp/msgp"
+)
+
+func TestMarshalUnmarshalAccessLog(t *testing.T) {
+	v := AccessLog{}
+	bts, err := v.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	left, err := v.UnmarshalMsg(bts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(left) > 0 {
+		t.Errorf("%d bytes left over after UnmarshalMsg(): %q", len(left), left)
+	}
+
+	left, err = msgp.Skip(bts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(left) > 0 {
+		t.Errorf("%d bytes left over after Skip(): %q", len(left), left)
+	}
+}
+
+func TestEncodeDecodeAccessLog(t *testing.T) {
+	v := AccessLog{}
+	var buf bytes.Buffer
+	if err := msgp.Encode(&buf, &v); err != nil {
+		t.Fatal(err)
+	}
+
+	if m := v.Msgsize(); buf.Len() > m {
+		t.Log("WARNING: TestEncodeDecodeAccessLog Msgsize() is inaccurate")
+	}
+
+	vn := AccessLog{}
+	if err := msgp.Decode(&buf, &vn); err != nil {
+		t.Error(err)
+	}
+
+	buf.Reset()
+	if err := msgp.Encode(&buf, &v); err != nil {
+		t.Fatal(err)
+	}
+	if err := msgp.NewReader(&buf).Skip(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAccessLogSender(t *testing.T) {
+	fake := &clientfakes.FakeMessageClient{}
+	s := AccessLogSender{fake}
+	v := &AccessLog{}
+
+	if err := s.SendAccessLog("foo", v); err != nil {
+		t.Fatal(err)
+	}
+	if tag, rec := fake.SendMessageArgsForCall(0); tag != "foo" || rec != interface{}(v) {
+		t.Errorf("SendMessage(%q, %v) was called", tag, rec)
+	}
+
+	if err := s.SendAccessLogBatch("foo", []*AccessLog{v}); err != nil {
+		t.Fatal(err)
+	}
+	if tag, entries := fake.SendForwardArgsForCall(0); tag != "foo" || len(entries) != 1 || entries[0].Record != interface{}(v) {
+		t.Errorf("SendForward(%q, %v) was called", tag, entries)
+	}
+}
+
+func TestAccessLogHandler(t *testing.T) {
+	v := AccessLog{}
+	bts, err := v.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// decode the record as a server does
+	record, _, err := msgp.ReadIntfBytes(bts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries := protocol.EntryList{{Timestamp: protocol.EventTimeNow(), Record: record}}
+
+	var got *AccessLog
+	h := AccessLogHandler(func(ctx context.Context, tag string, tm time.Time, rec *AccessLog) error {
+		got = rec
+		return nil
+	})
+	if err = h.HandleEvents(context.Background(), "foo", entries); err != nil {
+		t.Fatal(err)
+	}
+
+	rt, err := got.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(bts, rt) {
+		t.Errorf("%q was decoded as %q", bts, rt)
+	}
+}
+
+func TestMarshalUnmarshalClient(t *testing.T) {
+	v := Client{}
+	bts, err := v.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	left, err := v.UnmarshalMsg(bts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(left) > 0 {
+		t.Errorf("%d bytes left over after UnmarshalMsg(): %q", len(left), left)
+	}
+
+	left, err = msgp.Skip(bts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(left) > 0 {
+		t.Errorf("%d bytes left over after Skip(): %q", len(left), left)
+	}
+}
+
+func TestEncodeDecodeClient(t *testing.T) {
+	v := Client{}
+	var buf bytes.Buffer
+	if err := msgp.Encode(&buf, &v); err != nil {
+		t.Fatal(err)
+	}
+
+	if m := v.Msgsize(); buf.Len() > m {
+		t.Log("WARNING: TestEncodeDecodeClient Msgsize() is inaccurate")
+	}
+
+	vn := Client{}
+	if err := msgp.Decode(&buf, &vn); err != nil {
+		t.Error(err)
+	}
+
+	buf.Reset()
+	if err := msgp.Encode(&buf, &v); err != nil {
+		t.Fatal(err)
+	}
+	if err := msgp.NewReader(&buf).Skip(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestClientSender(t *testing.T) {
+	fake := &clientfakes.FakeMessageClient{}
+	s := ClientSender{fake}
+	v := &Client{}
+
+	if err := s.SendClient("foo", v); err != nil {
+		t.Fatal(err)
+	}
+	if tag, rec := fake.SendMessageArgsForCall(0); tag != "foo" || rec != interface{}(v) {
+		t.Errorf("SendMessage(%q, %v) was called", tag, rec)
+	}
+
+	if err := s.SendClientBatch("foo", []*Client{v}); err != nil {
+		t.Fatal(err)
+	}
+	if tag, entries := fake.SendForwardArgsForCall(0); tag != "foo" || len(entries) != 1 || entries[0].Record != interface{}(v) {
+		t.Errorf("SendForward(%q, %v) was called", tag, entries)
+	}
+}
+
+func TestClientHandler(t *testing.T) {
+	v := Client{}
+	bts, err := v.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// decode the record as a server does
+	record, _, err := msgp.ReadIntfBytes(bts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries := protocol.EntryList{{Timestamp: protocol.EventTimeNow(), Record: record}}
+
+	var got *Client
+	h := ClientHandler(func(ctx context.Context, tag string, tm time.Time, rec *Client) error {
+		got = rec
+		return nil
+	})
+	if err = h.HandleEvents(context.Background(), "foo", entries); err != nil {
+		t.Fatal(err)
+	}
+
+	rt, err := got.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(bts, rt) {
+		t.Errorf("%q was decoded as %q", bts, rt)
+	}
+}
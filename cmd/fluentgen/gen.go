@@ -0,0 +1,747 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// generator writes the msgp methods of records. The code follows that of
+// github.com/tinylib/msgp, without its optimizations.
+type generator struct {
+	buf   bytes.Buffer
+	temps int
+}
+
+func (g *generator) p(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+	g.buf.WriteByte('\n')
+}
+
+// temp returns a unique variable name.
+func (g *generator) temp() string {
+	g.temps++
+	return fmt.Sprintf("zb%04d", g.temps)
+}
+
+func (g *generator) check(ctx string) {
+	g.p("if err != nil {")
+	if ctx == "" {
+		g.p("err = msgp.WrapError(err)")
+	} else {
+		g.p("err = msgp.WrapError(err, %q)", ctx)
+	}
+	g.p("return")
+	g.p("}")
+}
+
+// toBasic converts v to the basic type of t.
+func toBasic(t *fieldType, v string) string {
+	if t.converted() {
+		return fmt.Sprintf("%s(%s)", t.basic, v)
+	}
+
+	return v
+}
+
+func (g *generator) encode(t *fieldType, v, ctx string) {
+	switch t.kind {
+	case kindBasic:
+		g.p("err = en.Write%s(%s)", t.msgp(), toBasic(t, v))
+	case kindBytes:
+		g.p("err = en.WriteBytes(%s)", v)
+	case kindTime:
+		g.p("err = en.WriteTime(%s)", v)
+	case kindDuration:
+		g.p("err = en.WriteDuration(%s)", v)
+	case kindIntf:
+		g.p("err = en.WriteIntf(%s)", v)
+	case kindSlice:
+		g.p("err = en.WriteArrayHeader(uint32(len(%s)))", v)
+		g.check(ctx)
+
+		e := g.temp()
+		g.p("for _, %s := range %s {", e, v)
+		g.encode(t.elem, e, ctx)
+		g.p("}")
+
+		return
+	case kindMap:
+		g.p("err = en.WriteMapHeader(uint32(len(%s)))", v)
+		g.check(ctx)
+
+		k, e := g.temp(), g.temp()
+		g.p("for %s, %s := range %s {", k, e, v)
+		g.p("err = en.WriteString(%s)", toBasic(t.key, k))
+		g.check(ctx)
+		g.encode(t.elem, e, ctx)
+		g.p("}")
+
+		return
+	case kindNamed:
+		g.p("err = %s.EncodeMsg(en)", v)
+	case kindPtr:
+		g.p("if %s == nil {", v)
+		g.p("err = en.WriteNil()")
+		g.p("} else {")
+		g.p("err = %s.EncodeMsg(en)", v)
+		g.p("}")
+	}
+
+	g.check(ctx)
+}
+
+func (g *generator) marshal(t *fieldType, v, ctx string) {
+	switch t.kind {
+	case kindBasic:
+		g.p("o = msgp.Append%s(o, %s)", t.msgp(), toBasic(t, v))
+	case kindBytes:
+		g.p("o = msgp.AppendBytes(o, %s)", v)
+	case kindTime:
+		g.p("o = msgp.AppendTime(o, %s)", v)
+	case kindDuration:
+		g.p("o = msgp.AppendDuration(o, %s)", v)
+	case kindIntf:
+		g.p("o, err = msgp.AppendIntf(o, %s)", v)
+		g.check(ctx)
+	case kindSlice:
+		e := g.temp()
+		g.p("o = msgp.AppendArrayHeader(o, uint32(len(%s)))", v)
+		g.p("for _, %s := range %s {", e, v)
+		g.marshal(t.elem, e, ctx)
+		g.p("}")
+	case kindMap:
+		k, e := g.temp(), g.temp()
+		g.p("o = msgp.AppendMapHeader(o, uint32(len(%s)))", v)
+		g.p("for %s, %s := range %s {", k, e, v)
+		g.p("o = msgp.AppendString(o, %s)", toBasic(t.key, k))
+		g.marshal(t.elem, e, ctx)
+		g.p("}")
+	case kindNamed:
+		g.p("o, err = %s.MarshalMsg(o)", v)
+		g.check(ctx)
+	case kindPtr:
+		g.p("if %s == nil {", v)
+		g.p("o = msgp.AppendNil(o)")
+		g.p("} else {")
+		g.p("o, err = %s.MarshalMsg(o)", v)
+		g.check(ctx)
+		g.p("}")
+	}
+}
+
+// constSize returns the encoded size of t if it does not depend on the
+// value.
+func constSize(t *fieldType) string {
+	switch {
+	case t.kind == kindBasic && t.basic != "string":
+		return "msgp." + t.msgp() + "Size"
+	case t.kind == kindTime:
+		return "msgp.TimeSize"
+	case t.kind == kindDuration:
+		return "msgp.DurationSize"
+	}
+
+	return ""
+}
+
+func (g *generator) size(t *fieldType, v string) {
+	if size := constSize(t); size != "" {
+		g.p("s += %s", size)
+		return
+	}
+
+	switch t.kind {
+	case kindBasic:
+		g.p("s += msgp.StringPrefixSize + len(%s)", v)
+	case kindBytes:
+		g.p("s += msgp.BytesPrefixSize + len(%s)", v)
+	case kindIntf:
+		g.p("s += msgp.GuessSize(%s)", v)
+	case kindSlice:
+		g.p("s += msgp.ArrayHeaderSize")
+
+		if size := constSize(t.elem); size != "" {
+			g.p("s += len(%s) * %s", v, size)
+			return
+		}
+
+		e := g.temp()
+		g.p("for _, %s := range %s {", e, v)
+		g.size(t.elem, e)
+		g.p("}")
+	case kindMap:
+		g.p("s += msgp.MapHeaderSize")
+
+		k, e := g.temp(), g.temp()
+
+		if size := constSize(t.elem); size != "" {
+			g.p("for %s := range %s {", k, v)
+			g.p("s += msgp.StringPrefixSize + len(%s) + %s", k, size)
+			g.p("}")
+			return
+		}
+
+		g.p("for %s, %s := range %s {", k, e, v)
+		g.p("s += msgp.StringPrefixSize + len(%s)", k)
+		g.size(t.elem, e)
+		g.p("}")
+	case kindNamed:
+		g.p("s += %s.Msgsize()", v)
+	case kindPtr:
+		g.p("if %s == nil {", v)
+		g.p("s += msgp.NilSize")
+		g.p("} else {")
+		g.p("s += %s.Msgsize()", v)
+		g.p("}")
+	}
+}
+
+func (g *generator) decode(t *fieldType, v, ctx string) {
+	switch t.kind {
+	case kindBasic:
+		if !t.converted() {
+			g.p("%s, err = dc.Read%s()", v, t.msgp())
+			break
+		}
+
+		b := g.temp()
+		g.p("var %s %s", b, t.basic)
+		g.p("%s, err = dc.Read%s()", b, t.msgp())
+		g.check(ctx)
+		g.p("%s = %s(%s)", v, t.expr, b)
+
+		return
+	case kindBytes:
+		g.p("%s, err = dc.ReadBytes(%s)", v, v)
+	case kindTime:
+		g.p("%s, err = dc.ReadTime()", v)
+	case kindDuration:
+		g.p("%s, err = dc.ReadDuration()", v)
+	case kindIntf:
+		g.p("%s, err = dc.ReadIntf()", v)
+	case kindSlice:
+		n, i := g.temp(), g.temp()
+		g.p("var %s uint32", n)
+		g.p("%s, err = dc.ReadArrayHeader()", n)
+		g.check(ctx)
+		g.resize(t, v, n)
+		g.p("for %s := range %s {", i, v)
+		g.decode(t.elem, fmt.Sprintf("%s[%s]", v, i), ctx)
+		g.p("}")
+
+		return
+	case kindMap:
+		n, k, e := g.temp(), g.temp(), g.temp()
+		g.p("var %s uint32", n)
+		g.p("%s, err = dc.ReadMapHeader()", n)
+		g.check(ctx)
+		g.clear(t, v, n)
+		g.p("for %s > 0 {", n)
+		g.p("%s--", n)
+		g.p("var %s string", k)
+		g.p("var %s %s", e, t.elem.expr)
+		g.p("%s, err = dc.ReadString()", k)
+		g.check(ctx)
+		g.decode(t.elem, e, ctx)
+		g.p("%s[%s] = %s", v, fromString(t.key, k), e)
+		g.p("}")
+
+		return
+	case kindNamed:
+		g.p("err = %s.DecodeMsg(dc)", v)
+	case kindPtr:
+		g.p("if dc.IsNil() {")
+		g.p("err = dc.ReadNil()")
+		g.check(ctx)
+		g.p("%s = nil", v)
+		g.p("} else {")
+		g.p("if %s == nil {", v)
+		g.p("%s = new(%s)", v, t.elem.expr)
+		g.p("}")
+		g.p("err = %s.DecodeMsg(dc)", v)
+		g.check(ctx)
+		g.p("}")
+
+		return
+	}
+
+	g.check(ctx)
+}
+
+func (g *generator) unmarshal(t *fieldType, v, ctx string) {
+	switch t.kind {
+	case kindBasic:
+		if !t.converted() {
+			g.p("%s, bts, err = msgp.Read%sBytes(bts)", v, t.msgp())
+			break
+		}
+
+		b := g.temp()
+		g.p("var %s %s", b, t.basic)
+		g.p("%s, bts, err = msgp.Read%sBytes(bts)", b, t.msgp())
+		g.check(ctx)
+		g.p("%s = %s(%s)", v, t.expr, b)
+
+		return
+	case kindBytes:
+		g.p("%s, bts, err = msgp.ReadBytesBytes(bts, %s)", v, v)
+	case kindTime:
+		g.p("%s, bts, err = msgp.ReadTimeBytes(bts)", v)
+	case kindDuration:
+		g.p("%s, bts, err = msgp.ReadDurationBytes(bts)", v)
+	case kindIntf:
+		g.p("%s, bts, err = msgp.ReadIntfBytes(bts)", v)
+	case kindSlice:
+		n, i := g.temp(), g.temp()
+		g.p("var %s uint32", n)
+		g.p("%s, bts, err = msgp.ReadArrayHeaderBytes(bts)", n)
+		g.check(ctx)
+		g.resize(t, v, n)
+		g.p("for %s := range %s {", i, v)
+		g.unmarshal(t.elem, fmt.Sprintf("%s[%s]", v, i), ctx)
+		g.p("}")
+
+		return
+	case kindMap:
+		n, k, e := g.temp(), g.temp(), g.temp()
+		g.p("var %s uint32", n)
+		g.p("%s, bts, err = msgp.ReadMapHeaderBytes(bts)", n)
+		g.check(ctx)
+		g.clear(t, v, n)
+		g.p("for %s > 0 {", n)
+		g.p("%s--", n)
+		g.p("var %s string", k)
+		g.p("var %s %s", e, t.elem.expr)
+		g.p("%s, bts, err = msgp.ReadStringBytes(bts)", k)
+		g.check(ctx)
+		g.unmarshal(t.elem, e, ctx)
+		g.p("%s[%s] = %s", v, fromString(t.key, k), e)
+		g.p("}")
+
+		return
+	case kindNamed:
+		g.p("bts, err = %s.UnmarshalMsg(bts)", v)
+	case kindPtr:
+		g.p("if msgp.IsNil(bts) {")
+		g.p("bts, err = msgp.ReadNilBytes(bts)")
+		g.check(ctx)
+		g.p("%s = nil", v)
+		g.p("} else {")
+		g.p("if %s == nil {", v)
+		g.p("%s = new(%s)", v, t.elem.expr)
+		g.p("}")
+		g.p("bts, err = %s.UnmarshalMsg(bts)", v)
+		g.check(ctx)
+		g.p("}")
+
+		return
+	}
+
+	g.check(ctx)
+}
+
+// resize sets the length of slice v to n, reusing its backing array if
+// possible.
+func (g *generator) resize(t *fieldType, v, n string) {
+	g.p("if cap(%s) >= int(%s) {", v, n)
+	g.p("%s = (%s)[:%s]", v, v, n)
+	g.p("} else {")
+	g.p("%s = make(%s, %s)", v, t.expr, n)
+	g.p("}")
+}
+
+// clear empties map v, or allocates it with room for n entries.
+func (g *generator) clear(t *fieldType, v, n string) {
+	k := g.temp()
+	g.p("if %s == nil {", v)
+	g.p("%s = make(%s, %s)", v, t.expr, n)
+	g.p("} else if len(%s) > 0 {", v)
+	g.p("for %s := range %s {", k, v)
+	g.p("delete(%s, %s)", v, k)
+	g.p("}")
+	g.p("}")
+}
+
+// fromString converts the string k to the key type t.
+func fromString(t *fieldType, k string) string {
+	if t.converted() {
+		return fmt.Sprintf("%s(%s)", t.expr, k)
+	}
+
+	return k
+}
+
+// methods writes the msgp methods of r, which encode it as a map.
+func (g *generator) methods(r *record) {
+	n := len(r.fields)
+
+	g.p("// DecodeMsg implements msgp.Decodable")
+	g.p("func (z *%s) DecodeMsg(dc *msgp.Reader) (err error) {", r.name)
+	g.p("var field []byte")
+	g.p("_ = field")
+	sz := g.temp()
+	g.p("var %s uint32", sz)
+	g.p("%s, err = dc.ReadMapHeader()", sz)
+	g.check("")
+	g.p("for %s > 0 {", sz)
+	g.p("%s--", sz)
+	g.p("field, err = dc.ReadMapKeyPtr()")
+	g.check("")
+	g.p("switch msgp.UnsafeString(field) {")
+	for _, f := range r.fields {
+		g.p("case %q:", f.key)
+		g.decode(f.typ, "z."+f.name, f.name)
+	}
+	g.p("default:")
+	g.p("err = dc.Skip()")
+	g.check("")
+	g.p("}")
+	g.p("}")
+	g.p("return")
+	g.p("}")
+	g.p("")
+
+	g.p("// EncodeMsg implements msgp.Encodable")
+	g.p("func (z *%s) EncodeMsg(en *msgp.Writer) (err error) {", r.name)
+	g.p("err = en.WriteMapHeader(%d)", n)
+	g.check("")
+	for _, f := range r.fields {
+		g.p("err = en.WriteString(%q)", f.key)
+		g.check("")
+		g.encode(f.typ, "z."+f.name, f.name)
+	}
+	g.p("return")
+	g.p("}")
+	g.p("")
+
+	g.p("// MarshalMsg implements msgp.Marshaler")
+	g.p("func (z *%s) MarshalMsg(b []byte) (o []byte, err error) {", r.name)
+	g.p("o = msgp.Require(b, z.Msgsize())")
+	g.p("o = msgp.AppendMapHeader(o, %d)", n)
+	for _, f := range r.fields {
+		g.p("o = msgp.AppendString(o, %q)", f.key)
+		g.marshal(f.typ, "z."+f.name, f.name)
+	}
+	g.p("return")
+	g.p("}")
+	g.p("")
+
+	g.p("// UnmarshalMsg implements msgp.Unmarshaler")
+	g.p("func (z *%s) UnmarshalMsg(bts []byte) (o []byte, err error) {", r.name)
+	g.p("var field []byte")
+	g.p("_ = field")
+	sz = g.temp()
+	g.p("var %s uint32", sz)
+	g.p("%s, bts, err = msgp.ReadMapHeaderBytes(bts)", sz)
+	g.check("")
+	g.p("for %s > 0 {", sz)
+	g.p("%s--", sz)
+	g.p("field, bts, err = msgp.ReadMapKeyZC(bts)")
+	g.check("")
+	g.p("switch msgp.UnsafeString(field) {")
+	for _, f := range r.fields {
+		g.p("case %q:", f.key)
+		g.unmarshal(f.typ, "z."+f.name, f.name)
+	}
+	g.p("default:")
+	g.p("bts, err = msgp.Skip(bts)")
+	g.check("")
+	g.p("}")
+	g.p("}")
+	g.p("o = bts")
+	g.p("return")
+	g.p("}")
+	g.p("")
+
+	g.p("// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message")
+	g.p("func (z *%s) Msgsize() (s int) {", r.name)
+	g.p("s = msgp.MapHeaderSize")
+	for _, f := range r.fields {
+		g.p("s += msgp.StringPrefixSize + %d", len(f.key))
+		g.size(f.typ, "z."+f.name)
+	}
+	g.p("return")
+	g.p("}")
+	g.p("")
+}
+
+const header = `// Code generated by fluentgen. DO NOT EDIT.
+
+package %s
+
+import (
+%s
+)
+
+`
+
+var helpers = template.Must(template.New("helpers").Parse(`
+// {{.}}Sender sends {{.}} records through a client.MessageClient.
+type {{.}}Sender struct {
+	client.MessageClient
+}
+
+// Send{{.}} sends rec as a Message.
+func (s {{.}}Sender) Send{{.}}(tag string, rec *{{.}}) error {
+	return s.SendMessage(tag, rec)
+}
+
+// Send{{.}}Batch sends recs as a single ForwardMessage.
+func (s {{.}}Sender) Send{{.}}Batch(tag string, recs []*{{.}}) error {
+	entries := make(protocol.EntryList, len(recs))
+	for i, rec := range recs {
+		entries[i] = protocol.EntryExt{
+			Timestamp: protocol.EventTimeNow(),
+			Record:    rec,
+		}
+	}
+
+	return s.SendForward(tag, entries)
+}
+
+// Decode{{.}} decodes the record of an event received by a
+// server.EventHandler.
+func Decode{{.}}(entry protocol.EntryExt) (*{{.}}, error) {
+	bts, err := msgp.AppendIntf(nil, entry.Record)
+	if err != nil {
+		return nil, err
+	}
+
+	rec := &{{.}}{}
+	if _, err = rec.UnmarshalMsg(bts); err != nil {
+		return nil, err
+	}
+
+	return rec, nil
+}
+
+// {{.}}Handler returns a server.EventHandler that decodes every event into a
+// {{.}} and passes it to fn.
+func {{.}}Handler(fn func(ctx context.Context, tag string, t time.Time, rec *{{.}}) error) server.EventHandler {
+	return server.EventHandlerFunc(func(ctx context.Context, tag string, entries protocol.EntryList) error {
+		for _, entry := range entries {
+			rec, err := Decode{{.}}(entry)
+			if err != nil {
+				return err
+			}
+
+			if err = fn(ctx, tag, entry.Timestamp.Time, rec); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+`))
+
+var tests = template.Must(template.New("tests").Parse(`
+func TestMarshalUnmarshal{{.}}(t *testing.T) {
+	v := {{.}}{}
+	bts, err := v.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	left, err := v.UnmarshalMsg(bts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(left) > 0 {
+		t.Errorf("%d bytes left over after UnmarshalMsg(): %q", len(left), left)
+	}
+
+	left, err = msgp.Skip(bts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(left) > 0 {
+		t.Errorf("%d bytes left over after Skip(): %q", len(left), left)
+	}
+}
+
+func TestEncodeDecode{{.}}(t *testing.T) {
+	v := {{.}}{}
+	var buf bytes.Buffer
+	if err := msgp.Encode(&buf, &v); err != nil {
+		t.Fatal(err)
+	}
+
+	if m := v.Msgsize(); buf.Len() > m {
+		t.Log("WARNING: TestEncodeDecode{{.}} Msgsize() is inaccurate")
+	}
+
+	vn := {{.}}{}
+	if err := msgp.Decode(&buf, &vn); err != nil {
+		t.Error(err)
+	}
+
+	buf.Reset()
+	if err := msgp.Encode(&buf, &v); err != nil {
+		t.Fatal(err)
+	}
+	if err := msgp.NewReader(&buf).Skip(); err != nil {
+		t.Error(err)
+	}
+}
+
+func Test{{.}}Sender(t *testing.T) {
+	fake := &clientfakes.FakeMessageClient{}
+	s := {{.}}Sender{fake}
+	v := &{{.}}{}
+
+	if err := s.Send{{.}}("foo", v); err != nil {
+		t.Fatal(err)
+	}
+	if tag, rec := fake.SendMessageArgsForCall(0); tag != "foo" || rec != interface{}(v) {
+		t.Errorf("SendMessage(%q, %v) was called", tag, rec)
+	}
+
+	if err := s.Send{{.}}Batch("foo", []*{{.}}{v}); err != nil {
+		t.Fatal(err)
+	}
+	if tag, entries := fake.SendForwardArgsForCall(0); tag != "foo" || len(entries) != 1 || entries[0].Record != interface{}(v) {
+		t.Errorf("SendForward(%q, %v) was called", tag, entries)
+	}
+}
+
+func Test{{.}}Handler(t *testing.T) {
+	v := {{.}}{}
+	bts, err := v.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// decode the record as a server does
+	record, _, err := msgp.ReadIntfBytes(bts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries := protocol.EntryList{{"{{"}}Timestamp: protocol.EventTimeNow(), Record: record{{"}}"}}
+
+	var got *{{.}}
+	h := {{.}}Handler(func(ctx context.Context, tag string, tm time.Time, rec *{{.}}) error {
+		got = rec
+		return nil
+	})
+	if err = h.HandleEvents(context.Background(), "foo", entries); err != nil {
+		t.Fatal(err)
+	}
+
+	rt, err := got.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(bts, rt) {
+		t.Errorf("%q was decoded as %q", bts, rt)
+	}
+}
+`))
+
+const modulePath = "github.com/IBM/fluent-forward-go"
+
+// generate returns the formatted source of the methods and helpers of
+// records, and of their tests.
+func generate(p *pkg, records []*record) (src, test []byte, err error) {
+	imports := map[string]string{
+		"context":                       "context",
+		"time":                          "time",
+		"github.com/tinylib/msgp/msgp":  "msgp",
+		modulePath + "/fluent/client":   "client",
+		modulePath + "/fluent/protocol": "protocol",
+		modulePath + "/fluent/server":   "server",
+	}
+	for path, name := range p.imports {
+		imports[path] = name
+	}
+
+	g := &generator{}
+	fmt.Fprintf(&g.buf, header, p.name, importList(imports))
+
+	for _, r := range records {
+		g.methods(r)
+
+		if err = helpers.Execute(&g.buf, r.name); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if src, err = format.Source(g.buf.Bytes()); err != nil {
+		return g.buf.Bytes(), nil, fmt.Errorf("formatting generated code: %w", err)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, header, p.name, importList(map[string]string{
+		"bytes":                        "bytes",
+		"context":                      "context",
+		"testing":                      "testing",
+		"time":                         "time",
+		"github.com/tinylib/msgp/msgp": "msgp",
+		modulePath + "/fluent/client/clientfakes": "clientfakes",
+		modulePath + "/fluent/protocol":           "protocol",
+	}))
+
+	for _, r := range records {
+		if err = tests.Execute(&buf, r.name); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if test, err = format.Source(buf.Bytes()); err != nil {
+		return nil, buf.Bytes(), fmt.Errorf("formatting generated tests: %w", err)
+	}
+
+	return src, test, nil
+}
+
+// importList returns the import specs of imports, which maps paths to
+// package names, with the standard library first.
+func importList(imports map[string]string) string {
+	var std, other []string
+
+	for path, name := range imports {
+		spec := fmt.Sprintf("\t%q", path)
+		if path[strings.LastIndex(path, "/")+1:] != name {
+			spec = fmt.Sprintf("\t%s %q", name, path)
+		}
+
+		if strings.Contains(strings.Split(path, "/")[0], ".") {
+			other = append(other, spec)
+		} else {
+			std = append(std, spec)
+		}
+	}
+
+	sort.Strings(std)
+	sort.Strings(other)
+
+	return strings.Join(std, "\n") + "\n\n" + strings.Join(other, "\n")
+}
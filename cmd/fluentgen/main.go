@@ -0,0 +1,110 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+// Command fluentgen generates the msgp methods of record structs, so that
+// they can be sent without reflection, along with a typed sender, typed
+// decoders for server.EventHandler, and round-trip tests. It is meant to be
+// run by go generate:
+//
+//	//go:generate go run github.com/IBM/fluent-forward-go/cmd/fluentgen -type MyRecord
+//
+// Records are encoded as maps keyed by the "msg" tag of each exported field,
+// or by its name. Fields can be basic types, []byte, time.Time,
+// time.Duration, interface{}, slices, maps with string keys, and structs
+// that implement the msgp interfaces, such as other generated records.
+package main
+
+import (
+	"errors"
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	typeNames string
+	input     string
+	output    string
+	withTests bool
+)
+
+func init() {
+	flag.StringVar(&typeNames, "type", "", "-type <name>[,<name>...] of the record structs")
+	flag.StringVar(&input, "file", os.Getenv("GOFILE"), "-file <path> of a Go file in the package of the records")
+	flag.StringVar(&output, "o", "", "-o <path> of the generated file; the default is <file>_fluent.go")
+	flag.BoolVar(&withTests, "tests", true, "-tests generates round-trip tests in <output>_test.go")
+}
+
+func main() {
+	flag.Parse()
+
+	if err := run(); err != nil {
+		log.Fatalf("fluentgen: %v", err)
+	}
+}
+
+func run() error {
+	if typeNames == "" || input == "" {
+		return errors.New("-type and -file are required")
+	}
+
+	if output == "" {
+		output = strings.TrimSuffix(input, ".go") + "_fluent.go"
+	}
+
+	testOutput := strings.TrimSuffix(output, ".go") + "_test.go"
+
+	p, err := parsePackage(filepath.Dir(input), output)
+	if err != nil {
+		return err
+	}
+
+	var records []*record
+
+	for _, name := range strings.Split(typeNames, ",") {
+		r, err := p.record(strings.TrimSpace(name))
+		if err != nil {
+			return err
+		}
+
+		records = append(records, r)
+	}
+
+	src, test, err := generate(p, records)
+	if err != nil {
+		return err
+	}
+
+	if err = os.WriteFile(output, src, 0o644); err != nil {
+		return err
+	}
+
+	if !withTests {
+		return nil
+	}
+
+	return os.WriteFile(testOutput, test, 0o644)
+}
@@ -0,0 +1,362 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+type kind int
+
+const (
+	kindBasic kind = iota
+	kindBytes
+	kindTime
+	kindDuration
+	kindIntf
+	kindSlice
+	kindMap
+	kindNamed
+	kindPtr
+)
+
+// basics maps the supported basic types to the suffix of their msgp
+// functions, such as msgp.AppendInt64.
+var basics = map[string]string{
+	"string":  "String",
+	"bool":    "Bool",
+	"int":     "Int",
+	"int8":    "Int8",
+	"int16":   "Int16",
+	"int32":   "Int32",
+	"int64":   "Int64",
+	"uint":    "Uint",
+	"uint8":   "Uint8",
+	"byte":    "Uint8",
+	"uint16":  "Uint16",
+	"uint32":  "Uint32",
+	"uint64":  "Uint64",
+	"float32": "Float32",
+	"float64": "Float64",
+}
+
+// fieldType describes how a field is encoded.
+type fieldType struct {
+	kind kind
+	// expr is the Go type expression, such as "[]string".
+	expr string
+	// basic is the basic type of a kindBasic, such as "int64".
+	basic string
+	// elem is the element type of a slice, map, or pointer.
+	elem *fieldType
+	// key is the key type of a map.
+	key *fieldType
+}
+
+// msgp returns the suffix of the msgp functions for a kindBasic.
+func (t *fieldType) msgp() string {
+	return basics[t.basic]
+}
+
+// converted reports whether values must be converted to and from the basic
+// type, because the field has a named type such as "type Level string".
+func (t *fieldType) converted() bool {
+	return t.kind == kindBasic && t.expr != t.basic
+}
+
+type field struct {
+	name string
+	key  string
+	typ  *fieldType
+}
+
+type record struct {
+	name   string
+	fields []field
+}
+
+// pkg is the parsed package that declares the records.
+type pkg struct {
+	name string
+	// specs are the type declarations of the package, by name.
+	specs map[string]*ast.TypeSpec
+	// files are the files that declare each type, by type name.
+	files map[string]*ast.File
+	// imports are the imports, by path, that the records need.
+	imports map[string]string
+}
+
+// parsePackage parses the non-test Go files in dir, skipping the files named
+// in skip.
+func parsePackage(dir string, skip ...string) (*pkg, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	p := &pkg{
+		specs:   map[string]*ast.TypeSpec{},
+		files:   map[string]*ast.File{},
+		imports: map[string]string{},
+	}
+
+	fset := token.NewFileSet()
+
+next:
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+
+		for _, s := range skip {
+			if filepath.Base(path) == filepath.Base(s) {
+				continue next
+			}
+		}
+
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		f, err := parser.ParseFile(fset, path, src, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+
+		p.name = f.Name.Name
+
+		ast.Inspect(f, func(n ast.Node) bool {
+			if spec, ok := n.(*ast.TypeSpec); ok {
+				p.specs[spec.Name.Name] = spec
+				p.files[spec.Name.Name] = f
+			}
+
+			return true
+		})
+	}
+
+	if p.name == "" {
+		return nil, fmt.Errorf("no Go files in %s", dir)
+	}
+
+	return p, nil
+}
+
+// record returns the fields of the named struct.
+func (p *pkg) record(name string) (*record, error) {
+	spec, ok := p.specs[name]
+	if !ok {
+		return nil, fmt.Errorf("type %s not found", name)
+	}
+
+	st, ok := spec.Type.(*ast.StructType)
+	if !ok {
+		return nil, fmt.Errorf("type %s is not a struct", name)
+	}
+
+	r := &record{name: name}
+
+	for _, f := range st.Fields.List {
+		typ, err := p.resolve(p.files[name], f.Type)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		names := f.Names
+		if len(names) == 0 {
+			// an embedded field is named after its type
+			embedded := strings.TrimPrefix(typ.expr, "*")
+			embedded = embedded[strings.LastIndex(embedded, ".")+1:]
+			names = []*ast.Ident{ast.NewIdent(embedded)}
+		}
+
+		for _, n := range names {
+			if !n.IsExported() {
+				continue
+			}
+
+			key := n.Name
+			if f.Tag != nil {
+				tag, err := strconv.Unquote(f.Tag.Value)
+				if err != nil {
+					return nil, err
+				}
+
+				if k := strings.Split(reflect.StructTag(tag).Get("msg"), ",")[0]; k == "-" {
+					continue
+				} else if k != "" {
+					key = k
+				}
+			}
+
+			r.fields = append(r.fields, field{name: n.Name, key: key, typ: typ})
+		}
+	}
+
+	return r, nil
+}
+
+// resolve returns the fieldType of a field type expression in file f.
+func (p *pkg) resolve(f *ast.File, expr ast.Expr) (*fieldType, error) {
+	t := &fieldType{expr: types.ExprString(expr)}
+
+	switch e := expr.(type) {
+	case *ast.Ident:
+		if _, ok := basics[e.Name]; ok {
+			t.kind, t.basic = kindBasic, e.Name
+			return t, nil
+		}
+
+		spec, ok := p.specs[e.Name]
+		if !ok {
+			return nil, fmt.Errorf("unsupported type %s", e.Name)
+		}
+
+		if _, ok := spec.Type.(*ast.StructType); ok {
+			t.kind = kindNamed
+			return t, nil
+		}
+
+		underlying, ok := spec.Type.(*ast.Ident)
+		if !ok || basics[underlying.Name] == "" {
+			return nil, fmt.Errorf("unsupported type %s: only structs and basic types can be named", e.Name)
+		}
+
+		t.kind, t.basic = kindBasic, underlying.Name
+	case *ast.SelectorExpr:
+		x, ok := e.X.(*ast.Ident)
+		if !ok {
+			return nil, fmt.Errorf("unsupported type %s", t.expr)
+		}
+
+		path, err := importPath(f, x.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case path == "time" && e.Sel.Name == "Time":
+			t.kind = kindTime
+		case path == "time" && e.Sel.Name == "Duration":
+			t.kind = kindDuration
+		default:
+			// types from other packages must implement the msgp
+			// interfaces
+			t.kind = kindNamed
+		}
+
+		p.imports[path] = x.Name
+	case *ast.ArrayType:
+		if e.Len != nil {
+			return nil, fmt.Errorf("unsupported array type %s", t.expr)
+		}
+
+		elem, err := p.resolve(f, e.Elt)
+		if err != nil {
+			return nil, err
+		}
+
+		if elem.expr == "byte" || elem.expr == "uint8" {
+			t.kind = kindBytes
+			return t, nil
+		}
+
+		t.kind, t.elem = kindSlice, elem
+	case *ast.MapType:
+		key, err := p.resolve(f, e.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		if key.kind != kindBasic || key.basic != "string" {
+			return nil, fmt.Errorf("unsupported map type %s: keys must be strings", t.expr)
+		}
+
+		elem, err := p.resolve(f, e.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		t.kind, t.key, t.elem = kindMap, key, elem
+	case *ast.InterfaceType:
+		if len(e.Methods.List) > 0 {
+			return nil, fmt.Errorf("unsupported type %s: only empty interfaces are supported", t.expr)
+		}
+
+		t.kind = kindIntf
+	case *ast.StarExpr:
+		elem, err := p.resolve(f, e.X)
+		if err != nil {
+			return nil, err
+		}
+
+		if elem.kind != kindNamed {
+			return nil, fmt.Errorf("unsupported type %s: only pointers to structs are supported", t.expr)
+		}
+
+		t.kind, t.elem = kindPtr, elem
+	default:
+		return nil, fmt.Errorf("unsupported type %s", t.expr)
+	}
+
+	return t, nil
+}
+
+// importPath returns the path of the package imported as name in f.
+func importPath(f *ast.File, name string) (string, error) {
+	for _, spec := range f.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			return "", err
+		}
+
+		if spec.Name != nil {
+			if spec.Name.Name == name {
+				return path, nil
+			}
+
+			continue
+		}
+
+		// this assumes that the package name is the last element of its
+		// path, which is true of the packages that define msgp types
+		if path[strings.LastIndex(path, "/")+1:] == name {
+			return path, nil
+		}
+	}
+
+	return "", errors.New("no import found for package " + name)
+}
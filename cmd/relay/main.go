@@ -0,0 +1,160 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/IBM/fluent-forward-go/fluent/client"
+	"github.com/IBM/fluent-forward-go/fluent/server"
+)
+
+var (
+	listenAddr  string
+	fluentAddr  string
+	fluentNet   string
+	sharedKey   string
+	token       string
+	certFile    string
+	keyFile     string
+	requireAck  bool
+	dialTimeout time.Duration
+)
+
+func init() {
+	flag.StringVar(&listenAddr, "listen", ":8083", "-listen <address> for websocket connections")
+	flag.StringVar(&fluentAddr, "fluent", "localhost:24224", "-fluent <address> of the Fluent endpoint")
+	flag.StringVar(&fluentNet, "network", "tcp", "-network <tcp|unix> of the Fluent endpoint")
+	flag.StringVar(&sharedKey, "shared-key", "", "-shared-key <key> for the Fluent endpoint handshake")
+	flag.StringVar(&token, "token", "", "-token <token> that websocket clients must send in the Authorization header")
+	flag.StringVar(&certFile, "cert", "", "-cert <file> to serve wss (requires -key)")
+	flag.StringVar(&keyFile, "key", "", "-key <file> to serve wss (requires -cert)")
+	flag.BoolVar(&requireAck, "ack", true, "-ack=<bool> to wait for the Fluent endpoint to ack before acking the websocket peer")
+	flag.DurationVar(&dialTimeout, "timeout", client.DefaultConnectionTimeout, "-timeout <duration> for connecting and acks")
+}
+
+func main() {
+	flag.Parse()
+
+	c := client.New(client.ConnectionOptions{
+		Factory: &client.ConnFactory{
+			Network: fluentNet,
+			Address: fluentAddr,
+			Timeout: dialTimeout,
+		},
+		RequireAck:        requireAck,
+		ConnectionTimeout: dialTimeout,
+		AuthInfo: client.AuthInfo{
+			SharedKey: sharedKeyBytes(),
+		},
+	})
+
+	if err := c.Connect(); err != nil {
+		fmt.Fprintln(os.Stderr, "Unable to connect, exiting", err)
+		os.Exit(1)
+	}
+
+	if !c.TransportPhase() {
+		if err := c.Handshake(); err != nil {
+			fmt.Fprintln(os.Stderr, "Handshake failed, exiting", err)
+			os.Exit(1)
+		}
+	}
+
+	opts := server.WSRelayOptions{Client: c}
+
+	if token != "" {
+		opts.TokenValidator = server.TokenValidatorFunc(func(t string) error {
+			if subtle.ConstantTimeCompare([]byte(t), []byte(token)) != 1 {
+				return errors.New("invalid token")
+			}
+
+			return nil
+		})
+	}
+
+	relay := server.NewWSRelay(opts)
+
+	svr := &http.Server{
+		Addr:              listenAddr,
+		Handler:           relay,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	svr.RegisterOnShutdown(func() {
+		if err := relay.Close(); err != nil {
+			log.Println("close error:", err)
+		}
+	})
+
+	go func() {
+		log.Println("Relaying", listenAddr, "to", fluentAddr)
+
+		var err error
+		if certFile != "" {
+			err = svr.ListenAndServeTLS(certFile, keyFile)
+		} else {
+			err = svr.ListenAndServe()
+		}
+
+		if err != nil && err != http.ErrServerClosed {
+			log.Fatal("ListenAndServe error: " + err.Error())
+		}
+	}()
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	<-interrupt
+
+	log.Println("shutting down")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := svr.Shutdown(ctx); err != nil {
+		log.Println("shutdown error:", err)
+	}
+
+	if err := c.Disconnect(); err != nil {
+		log.Println("disconnect error:", err)
+	}
+}
+
+func sharedKeyBytes() []byte {
+	if sharedKey == "" {
+		return nil
+	}
+
+	return []byte(sharedKey)
+}
@@ -0,0 +1,112 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/IBM/fluent-forward-go/fluent/client"
+	"github.com/IBM/fluent-forward-go/fluent/server"
+)
+
+var (
+	listenAddr string
+	listenNet  string
+	wsURL      string
+	token      string
+	authScheme string
+	sharedKey  string
+	hostname   string
+	ackTimeout time.Duration
+)
+
+// staticToken is a client.TokenProvider that always returns the same token.
+type staticToken string
+
+func (t staticToken) Token() (string, error) {
+	return string(t), nil
+}
+
+func init() {
+	flag.StringVar(&listenAddr, "listen", "localhost:24224", "-listen <address> for Fluent connections")
+	flag.StringVar(&listenNet, "network", "tcp", "-network <tcp|unix> to listen on")
+	flag.StringVar(&wsURL, "url", "ws://127.0.0.1:8083", "-url <url> of the websocket endpoint")
+	flag.StringVar(&token, "token", "", "-token <token> to send in the Authorization header")
+	flag.StringVar(&authScheme, "scheme", "", "-scheme <scheme> to prepend to the token, e.g. Bearer")
+	flag.StringVar(&sharedKey, "shared-key", "", "-shared-key <key> that Fluent clients must use in the handshake")
+	flag.StringVar(&hostname, "hostname", "", "-hostname <name> to send to Fluent clients in the handshake")
+	flag.DurationVar(&ackTimeout, "ack-timeout", server.DefaultAckTimeout, "-ack-timeout <duration> to wait for websocket acks")
+}
+
+func main() {
+	flag.Parse()
+
+	factory := &client.DefaultWSConnectionFactory{
+		URL:        wsURL,
+		AuthScheme: authScheme,
+	}
+
+	if token != "" {
+		factory.TokenProvider = staticToken(token)
+	}
+
+	opts := server.TunnelOptions{
+		WSOptions: client.WSConnectionOptions{
+			Factory: factory,
+		},
+		Hostname:   hostname,
+		AckTimeout: ackTimeout,
+	}
+
+	if sharedKey != "" {
+		opts.SharedKey = []byte(sharedKey)
+	}
+
+	tunnel := server.NewTunnel(opts)
+
+	go func() {
+		log.Println("Tunneling", listenAddr, "to", wsURL)
+
+		if err := tunnel.ListenAndServe(listenNet, listenAddr); err != nil && err != server.ErrServerClosed {
+			fmt.Fprintln(os.Stderr, "Unable to start tunnel, exiting", err)
+			os.Exit(1)
+		}
+	}()
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	<-interrupt
+
+	log.Println("shutting down")
+
+	if err := tunnel.Close(); err != nil {
+		log.Println("close error:", err)
+	}
+}
@@ -32,55 +32,39 @@ import (
 	"sync"
 	"time"
 
-	"github.com/IBM/fluent-forward-go/fluent/client/ws"
+	"github.com/IBM/fluent-forward-go/fluent/server"
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
 )
 
 type Listener struct {
-	upgrader       *websocket.Upgrader
-	server         *http.Server
-	shutdown       chan struct{}
-	exited         chan struct{}
-	connectionLock sync.Mutex
-	wsopts         ws.ConnectionOptions
+	handler  *server.WSHandler
+	server   *http.Server
+	shutdown chan struct{}
+	exited   chan struct{}
 }
 
-func NewListener(server *http.Server, wsopts ws.ConnectionOptions) *Listener {
-	return &Listener{
-		&websocket.Upgrader{},
-		server,
+func NewListener(svr *http.Server, opts server.WSHandlerOptions) *Listener {
+	l := &Listener{
+		server.NewWSHandler(opts),
+		svr,
 		make(chan struct{}, 1),
 		make(chan struct{}, 1),
-		sync.Mutex{},
-		wsopts,
 	}
-}
 
-func (s *Listener) Connect(w http.ResponseWriter, r *http.Request) {
-	var (
-		c   *websocket.Conn
-		err error
-	)
-
-	if c, err = s.upgrader.Upgrade(w, r, nil); err == nil {
-		connection, _ := ws.NewConnection(c, s.wsopts)
-
-		s.server.RegisterOnShutdown(func() {
-			if !connection.Closed() {
-				if err := connection.Close(); err != nil && err != websocket.ErrCloseSent {
-					log.Println("server conn close error:", err)
-				}
-			}
+	svr.RegisterOnShutdown(func() {
+		if err := l.handler.Close(); err != nil && err != websocket.ErrCloseSent {
+			log.Println("server conn close error:", err)
+		}
+
+		log.Println("server conns closed")
+	})
 
-			log.Println("server conn closed")
-		})
+	return l
+}
 
-		if err = connection.Listen(); err != nil &&
-			!websocket.IsCloseError(err, websocket.CloseNormalClosure) {
-			log.Println("server listen error:", err)
-		}
-	}
+func (s *Listener) Connect(w http.ResponseWriter, r *http.Request) {
+	s.handler.ServeHTTP(w, r)
 }
 
 var (
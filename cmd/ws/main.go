@@ -25,6 +25,7 @@ SOFTWARE.
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"flag"
 	"fmt"
@@ -35,8 +36,8 @@ import (
 	"time"
 
 	"github.com/IBM/fluent-forward-go/fluent/client"
-	"github.com/IBM/fluent-forward-go/fluent/client/ws"
 	"github.com/IBM/fluent-forward-go/fluent/protocol"
+	"github.com/IBM/fluent-forward-go/fluent/server"
 )
 
 var (
@@ -56,18 +57,17 @@ func listen() *Listener {
 	log.Println("Starting server on port 8083")
 
 	s := &http.Server{Addr: ":8083"}
-	wo := ws.ConnectionOptions{
-		ReadHandler: func(conn ws.Connection, _ int, p []byte, err error) error {
-			msg := protocol.Message{}
-			msg.UnmarshalMsg(p)
-
-			log.Println("server got a message", msg, err)
-
-			return err
-		},
+	opts := server.WSHandlerOptions{
+		Handler: server.EventHandlerFunc(func(_ context.Context, tag string, entries protocol.EntryList) error {
+			for _, entry := range entries {
+				log.Println("server got an event", tag, entry.Timestamp, entry.Record)
+			}
+
+			return nil
+		}),
 	}
 
-	wsSvr := NewListener(s, wo)
+	wsSvr := NewListener(s, opts)
 
 	go func() {
 		if err := wsSvr.ListenAndServe(); err != nil {
@@ -25,15 +25,18 @@ SOFTWARE.
 package client
 
 import (
+	"context"
 	"errors"
-	"fmt"
+	"io"
 	"sync"
 
 	"crypto/rand"
 	"net"
 	"time"
 
+	"github.com/IBM/fluent-forward-go/fluent/metrics"
 	"github.com/IBM/fluent-forward-go/fluent/protocol"
+	"github.com/IBM/fluent-forward-go/fluent/trace"
 
 	"github.com/tinylib/msgp/msgp"
 )
@@ -71,13 +74,18 @@ type ConnectionFactory interface {
 
 type Client struct {
 	ConnectionFactory
-	RequireAck  bool
-	Timeout     time.Duration
-	AuthInfo    AuthInfo
-	Hostname    string
-	session     *Session
-	ackLock     sync.Mutex
-	sessionLock sync.RWMutex
+	RequireAck    bool
+	Timeout       time.Duration
+	AuthInfo      AuthInfo
+	Hostname      string
+	Logger        Logger
+	Hooks         Hooks
+	Metrics       metrics.Recorder
+	Propagator    trace.Propagator
+	TraceMetadata bool
+	session       *Session
+	ackLock       sync.Mutex
+	sessionLock   sync.RWMutex
 }
 
 type ConnectionOptions struct {
@@ -88,6 +96,21 @@ type ConnectionOptions struct {
 	// ReadTimeout       time.Duration
 	// WriteTimeout      time.Duration
 	AuthInfo AuthInfo
+	// Logger is an optional leveled logger for connection events and
+	// errors.
+	Logger Logger
+	// Hooks are optional callbacks for connection and send events.
+	Hooks Hooks
+	// Metrics, if set, records counters and latencies for every send,
+	// ack, handshake and reconnect.
+	Metrics metrics.Recorder
+	// Propagator, if set, is used by the Context send helpers, such as
+	// SendMessageContext, to add the trace context from their
+	// context.Context to each event.
+	Propagator trace.Propagator
+	// TraceMetadata adds the trace context to the Fluent Bit v2 entry
+	// metadata instead of the record.
+	TraceMetadata bool
 }
 
 type AuthInfo struct {
@@ -119,9 +142,31 @@ func New(opts ConnectionOptions) *Client {
 		AuthInfo:          opts.AuthInfo,
 		RequireAck:        opts.RequireAck,
 		Timeout:           opts.ConnectionTimeout,
+		Logger:            opts.Logger,
+		Hooks:             opts.Hooks,
+		Metrics:           opts.Metrics,
+		Propagator:        opts.Propagator,
+		TraceMetadata:     opts.TraceMetadata,
 	}
 }
 
+// endpoint identifies the server in metrics.
+func (c *Client) endpoint() string {
+	if f, ok := c.ConnectionFactory.(*ConnFactory); ok {
+		return f.Address
+	}
+
+	return ""
+}
+
+func (c *Client) log() Logger {
+	if c.Logger == nil {
+		return noopLogger{}
+	}
+
+	return c.Logger
+}
+
 // TransportPhase indicates if the client has completed the
 // initial connection handshake.
 func (c *Client) TransportPhase() bool {
@@ -134,7 +179,7 @@ func (c *Client) TransportPhase() bool {
 func (c *Client) connect() error {
 	conn, err := c.New()
 	if err != nil {
-		return err
+		return wrapConnErr("connect", err)
 	}
 
 	c.session = &Session{
@@ -155,11 +200,32 @@ func (c *Client) connect() error {
 // handshake puts the connection into message (or forward) mode, at which time
 // the client is free to send event messages.
 func (c *Client) Handshake() error {
+	start := time.Now()
+	err := c.handshake()
+
+	if c.Metrics != nil {
+		c.Metrics.Handshake(c.endpoint(), time.Since(start), err)
+	}
+
+	if err != nil {
+		c.log().Error(err, "handshake failed")
+	} else {
+		c.log().Debug("handshake completed")
+	}
+
+	if c.Hooks.OnHandshake != nil {
+		c.Hooks.OnHandshake(err)
+	}
+
+	return err
+}
+
+func (c *Client) handshake() error {
 	c.sessionLock.RLock()
 	defer c.sessionLock.RUnlock()
 
 	if c.session == nil {
-		return errors.New("not connected")
+		return ErrNoSession
 	}
 
 	var helo protocol.Helo
@@ -206,25 +272,49 @@ func (c *Client) Handshake() error {
 }
 
 // Connect initializes the Session and Connection objects by opening
-// a client connect to the target configured in the ConnectionFactory
+// a client connect to the target configured in the ConnectionFactory.
+// If a session is already active, it returns ErrSessionActive without
+// logging a failure or calling the OnConnect hook.
 func (c *Client) Connect() error {
 	c.sessionLock.Lock()
 	defer c.sessionLock.Unlock()
 
 	if c.session != nil {
-		return errors.New("a session is already active")
+		return ErrSessionActive
+	}
+
+	err := c.connect()
+	if err != nil {
+		c.log().Error(err, "connect failed")
+	} else {
+		c.log().Debug("connected")
+	}
+
+	if c.Hooks.OnConnect != nil {
+		c.Hooks.OnConnect(err)
 	}
 
-	return c.connect()
+	return err
 }
 
 func (c *Client) disconnect() (err error) {
-	if c.session != nil {
-		err = c.session.Connection.Close()
+	if c.session == nil {
+		return nil
 	}
 
+	err = c.session.Connection.Close()
 	c.session = nil
 
+	if err != nil {
+		c.log().Error(err, "disconnect failed")
+	} else {
+		c.log().Debug("disconnected")
+	}
+
+	if c.Hooks.OnDisconnect != nil {
+		c.Hooks.OnDisconnect(err)
+	}
+
 	return
 }
 
@@ -242,10 +332,28 @@ func (c *Client) Reconnect() error {
 
 	_ = c.disconnect()
 
-	return c.connect()
+	err := c.connect()
+
+	if err != nil {
+		c.log().Error(err, "reconnect failed")
+	} else {
+		c.log().Debug("reconnected")
+	}
+
+	if c.Hooks.OnReconnect != nil {
+		c.Hooks.OnReconnect(err)
+	}
+
+	if c.Metrics != nil {
+		c.Metrics.Reconnected(c.endpoint(), err)
+	}
+
+	return err
 }
 
 func (c *Client) checkAck(chunk string) error {
+	start := time.Now()
+
 	if c.Timeout != 0 {
 		if err := c.session.Connection.SetReadDeadline(time.Now().Add(c.Timeout)); err != nil {
 			return err
@@ -254,28 +362,95 @@ func (c *Client) checkAck(chunk string) error {
 
 	var ack protocol.AckMessage
 	if err := msgp.Decode(c.session.Connection, &ack); err != nil {
-		return err
+		var ne net.Error
+		if errors.As(err, &ne) && ne.Timeout() {
+			if c.Hooks.OnAckTimeout != nil {
+				c.Hooks.OnAckTimeout(chunk)
+			}
+
+			if c.Metrics != nil {
+				c.Metrics.AckTimedOut(c.endpoint())
+			}
+
+			return &AckTimeoutError{Chunk: chunk, Err: err}
+		}
+
+		return wrapConnErr("read ack", err)
 	}
 
 	if ack.Ack != chunk {
-		return fmt.Errorf("Expected chunk %s, but got %s", chunk, ack.Ack)
+		return &AckMismatchError{Expected: chunk, Received: ack.Ack}
+	}
+
+	if c.Hooks.OnAck != nil || c.Metrics != nil {
+		rtt := time.Since(start)
+
+		if c.Hooks.OnAck != nil {
+			c.Hooks.OnAck(chunk, rtt)
+		}
+
+		if c.Metrics != nil {
+			c.Metrics.AckReceived(c.endpoint(), rtt)
+		}
 	}
 
 	return nil
 }
 
+// sent logs failed sends, calls the OnSend hook, and records metrics. n is
+// the number of bytes written.
+func (c *Client) sent(info SendInfo, n int, start time.Time) {
+	if info.Err != nil {
+		c.log().Error(info.Err, "send failed", "chunk", info.Chunk)
+	}
+
+	if c.Hooks.OnSend == nil && c.Metrics == nil {
+		return
+	}
+
+	info.Duration = time.Since(start)
+
+	if c.Hooks.OnSend != nil {
+		c.Hooks.OnSend(info)
+	}
+
+	if c.Metrics != nil {
+		stats := metrics.SendStats{
+			Endpoint: c.endpoint(),
+			Bytes:    n,
+			Chunked:  info.Chunk != "",
+			Latency:  info.Duration,
+			Err:      info.Err,
+		}
+
+		if info.Message != nil {
+			stats.Tag, stats.Events = describe(info.Message)
+		}
+
+		c.Metrics.Sent(stats)
+	}
+}
+
 // Send sends a single protocol.ChunkEncoder across the wire.  If the session
 // is not yet in transport phase, an error is returned, and no message is sent.
 func (c *Client) Send(e protocol.ChunkEncoder) error {
+	start := time.Now()
+	chunk, n, err := c.send(e)
+	c.sent(SendInfo{Message: e, Chunk: chunk, Err: err}, n, start)
+
+	return err
+}
+
+func (c *Client) send(e protocol.ChunkEncoder) (string, int, error) {
 	c.sessionLock.RLock()
 	defer c.sessionLock.RUnlock()
 
 	if c.session == nil {
-		return errors.New("no active session")
+		return "", 0, ErrNoSession
 	}
 
 	if !c.session.TransportPhase {
-		return errors.New("session handshake not completed")
+		return "", 0, ErrHandshakeRequired
 	}
 
 	var (
@@ -285,39 +460,63 @@ func (c *Client) Send(e protocol.ChunkEncoder) error {
 
 	if c.RequireAck {
 		if chunk, err = e.Chunk(); err != nil {
-			return err
+			return "", 0, err
 		}
 
 		c.ackLock.Lock()
 		defer c.ackLock.Unlock()
 	}
 
-	err = msgp.Encode(c.session.Connection, e)
+	var (
+		w  io.Writer = c.session.Connection
+		cw *countingWriter
+	)
+
+	if c.Metrics != nil {
+		cw = &countingWriter{w: w}
+		w = cw
+	}
+
+	err = msgp.Encode(w, e)
+
+	n := 0
+	if cw != nil {
+		n = cw.n
+	}
+
 	if err != nil || !c.RequireAck {
-		return err
+		return chunk, n, wrapConnErr("write", err)
 	}
 
-	return c.checkAck(chunk)
+	return chunk, n, c.checkAck(chunk)
 }
 
 // SendRaw sends bytes across the wire. If the session
 // is not yet in transport phase, an error is returned,
 // and no message is sent.
 func (c *Client) SendRaw(m []byte) error {
+	start := time.Now()
+	n, err := c.sendRaw(m)
+	c.sent(SendInfo{Raw: m, Err: err}, n, start)
+
+	return err
+}
+
+func (c *Client) sendRaw(m []byte) (int, error) {
 	c.sessionLock.RLock()
 	defer c.sessionLock.RUnlock()
 
 	if c.session == nil {
-		return errors.New("no active session")
+		return 0, ErrNoSession
 	}
 
 	if !c.session.TransportPhase {
-		return errors.New("session handshake not completed")
+		return 0, ErrHandshakeRequired
 	}
 
-	_, err := c.session.Connection.Write(m)
+	n, err := c.session.Connection.Write(m)
 
-	return err
+	return n, wrapConnErr("write", err)
 }
 
 func (c *Client) SendPacked(tag string, entries protocol.EntryList) error {
@@ -354,8 +553,15 @@ func (c *Client) SendForward(tag string, entries protocol.EntryList) error {
 }
 
 func (c *Client) SendCompressed(tag string, entries protocol.EntryList) error {
-	msg, err := protocol.NewCompressedPackedForwardMessage(tag, entries)
+	bits, err := entries.MarshalPacked()
+	if err != nil {
+		return err
+	}
+
+	msg, err := c.compress(tag, bits)
 	if err == nil {
+		lenEntries := len(entries)
+		msg.Options.Size = &lenEntries
 		err = c.Send(msg)
 	}
 
@@ -363,10 +569,69 @@ func (c *Client) SendCompressed(tag string, entries protocol.EntryList) error {
 }
 
 func (c *Client) SendCompressedFromBytes(tag string, entries []byte) error {
-	msg, err := protocol.NewCompressedPackedForwardMessageFromBytes(tag, entries)
+	msg, err := c.compress(tag, entries)
 	if err == nil {
 		err = c.Send(msg)
 	}
 
 	return err
 }
+
+// compress creates a compressed PackedForwardMessage and records its
+// compression ratio.
+func (c *Client) compress(tag string, entries []byte) (*protocol.PackedForwardMessage, error) {
+	msg, err := protocol.NewCompressedPackedForwardMessageFromBytes(tag, entries)
+	if err == nil && c.Metrics != nil {
+		c.Metrics.Compressed(c.endpoint(), tag, len(entries), len(msg.EventStream))
+	}
+
+	return msg, err
+}
+
+// SendMessageContext is SendMessage with the trace context from ctx added
+// to the record. Because Message mode has no entry metadata, the event is
+// sent as a single-entry ForwardMessage when TraceMetadata is set.
+func (c *Client) SendMessageContext(ctx context.Context, tag string, record interface{}) error {
+	if c.TraceMetadata && c.Propagator != nil {
+		return c.SendForwardContext(ctx, tag, protocol.EntryList{
+			{Timestamp: protocol.EventTimeNow(), Record: record},
+		})
+	}
+
+	return c.SendMessage(tag, trace.InjectRecord(ctx, c.Propagator, record))
+}
+
+// SendMessageExtContext is SendMessageExt with the trace context from ctx
+// added to the record. Because Message mode has no entry metadata, the event
+// is sent as a single-entry ForwardMessage when TraceMetadata is set.
+func (c *Client) SendMessageExtContext(ctx context.Context, tag string, record interface{}) error {
+	if c.TraceMetadata && c.Propagator != nil {
+		return c.SendForwardContext(ctx, tag, protocol.EntryList{
+			{Timestamp: protocol.EventTimeNow(), Record: record},
+		})
+	}
+
+	return c.SendMessageExt(tag, trace.InjectRecord(ctx, c.Propagator, record))
+}
+
+// SendForwardContext is SendForward with the trace context from ctx added to
+// every entry.
+func (c *Client) SendForwardContext(ctx context.Context, tag string, entries protocol.EntryList) error {
+	return c.SendForward(tag, c.traceEntries(ctx, entries))
+}
+
+// SendPackedContext is SendPacked with the trace context from ctx added to
+// every entry.
+func (c *Client) SendPackedContext(ctx context.Context, tag string, entries protocol.EntryList) error {
+	return c.SendPacked(tag, c.traceEntries(ctx, entries))
+}
+
+// SendCompressedContext is SendCompressed with the trace context from ctx
+// added to every entry.
+func (c *Client) SendCompressedContext(ctx context.Context, tag string, entries protocol.EntryList) error {
+	return c.SendCompressed(tag, c.traceEntries(ctx, entries))
+}
+
+func (c *Client) traceEntries(ctx context.Context, entries protocol.EntryList) protocol.EntryList {
+	return trace.InjectEntries(ctx, c.Propagator, entries, c.TraceMetadata)
+}
@@ -87,7 +87,7 @@ var _ = Describe("Client", func() {
 			err := client.Connect()
 			Expect(err).NotTo(HaveOccurred())
 			err = client.Connect()
-			Expect(err.Error()).To(Equal("a session is already active"))
+			Expect(err).To(MatchError(ErrSessionActive))
 		})
 
 		Context("When the factory returns an error", func() {
@@ -178,12 +178,38 @@ var _ = Describe("Client", func() {
 			})
 
 			It("Returns an error", func() {
-				Expect(client.Send(&msg)).To(HaveOccurred())
+				Expect(client.Send(&msg)).To(MatchError(ErrNoSession))
 			})
 
 			// TODO: We need a test that no message is sent
 		})
 
+		Context("When the server requires a handshake", func() {
+			BeforeEach(func() {
+				client.AuthInfo = AuthInfo{SharedKey: []byte("secret")}
+			})
+
+			It("Returns an error until the handshake is performed", func() {
+				Expect(client.Send(&msg)).To(MatchError(ErrHandshakeRequired))
+				Expect(client.SendRaw([]byte("oi"))).To(MatchError(ErrHandshakeRequired))
+			})
+		})
+
+		Context("When the connection fails", func() {
+			JustBeforeEach(func() {
+				serverSide.Close()
+			})
+
+			It("Returns a retryable ConnError", func() {
+				err := client.Send(&msg)
+
+				var connErr *ConnError
+				Expect(errors.As(err, &connErr)).To(BeTrue())
+				Expect(connErr.Op).To(Equal("write"))
+				Expect(IsRetryable(err)).To(BeTrue())
+			})
+		})
+
 		Context("RequireAck is true", func() {
 			var (
 				serverSide   net.Conn
@@ -239,6 +265,12 @@ var _ = Describe("Client", func() {
 					defer func() { done <- true }()
 					err := client.Send(&msg)
 					Expect(err.Error()).To(ContainSubstring("Expected chunk"))
+
+					var mismatch *AckMismatchError
+					Expect(errors.As(err, &mismatch)).To(BeTrue())
+					Expect(mismatch.Expected).To(Equal(msg.Options.Chunk))
+					Expect(mismatch.Received).To(BeEmpty())
+					Expect(IsRetryable(err)).To(BeTrue())
 				}()
 
 				rcvd := &protocol.MessageExt{}
@@ -257,6 +289,28 @@ var _ = Describe("Client", func() {
 
 				<-done
 			})
+
+			It("returns an AckTimeoutError when the ack does not arrive", func() {
+				client.Timeout = 50 * time.Millisecond
+
+				done := make(chan bool)
+				go func() {
+					defer GinkgoRecover()
+					defer func() { done <- true }()
+					err := client.Send(&msg)
+
+					var timeout *AckTimeoutError
+					Expect(errors.As(err, &timeout)).To(BeTrue())
+					Expect(timeout.Chunk).To(Equal(msg.Options.Chunk))
+					Expect(IsRetryable(err)).To(BeTrue())
+				}()
+
+				rcvd := &protocol.MessageExt{}
+				err := rcvd.DecodeMsg(serverReader)
+				Expect(err).ToNot(HaveOccurred())
+
+				<-done
+			})
 		})
 	})
 
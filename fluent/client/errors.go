@@ -1,10 +1,143 @@
 package client
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
+
+	"github.com/gorilla/websocket"
+)
+
+var (
+	// ErrNoSession is returned when a message is sent, or a handshake is
+	// attempted, without an active session.
+	ErrNoSession = errors.New("no active session")
+	// ErrSessionActive is returned by Connect when a session already exists.
+	ErrSessionActive = errors.New("a session is already active")
+	// ErrHandshakeRequired is returned when a message is sent before the
+	// shared key handshake has completed.
+	ErrHandshakeRequired = errors.New("session handshake not completed")
+	// ErrBatcherClosed is returned by a WSBatcher after Close is called.
+	ErrBatcherClosed = errors.New("batcher is closed")
+	// ErrNoRoute is returned by a Router when no route matches a message's
+	// tag and there is no default client.
+	ErrNoRoute = errors.New("no route matches tag")
 )
 
+// Retryable is implemented by errors that know whether the failed operation
+// can succeed if it is retried, usually after reconnecting.
+type Retryable interface {
+	error
+	IsRetryable() bool
+}
+
+// IsRetryable reports whether an error in err's chain is Retryable and
+// says that it is.
+func IsRetryable(err error) bool {
+	var r Retryable
+
+	return errors.As(err, &r) && r.IsRetryable()
+}
+
+// ConnError is returned when the connection to the server fails while
+// connecting, writing a message or reading an ack. The connection should be
+// reestablished before the message is retried.
+type ConnError struct {
+	Op  string
+	Err error
+}
+
+func (e *ConnError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Op, e.Err)
+}
+
+func (e *ConnError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ConnError) IsRetryable() bool {
+	return true
+}
+
+// AckMismatchError is returned when the server acks a chunk other than the
+// one that was sent. The connection is out of sync and should be
+// reestablished.
+type AckMismatchError struct {
+	Expected string
+	Received string
+}
+
+func (e *AckMismatchError) Error() string {
+	return fmt.Sprintf("Expected chunk %s, but got %s", e.Expected, e.Received)
+}
+
+func (e *AckMismatchError) IsRetryable() bool {
+	return true
+}
+
+// AckTimeoutError is returned when the server does not ack a chunk within
+// the client's timeout. The message may or may not have been processed.
+type AckTimeoutError struct {
+	Chunk string
+	Err   error
+}
+
+func (e *AckTimeoutError) Error() string {
+	return fmt.Sprintf("timed out waiting for ack of chunk %s: %s", e.Chunk, e.Err)
+}
+
+func (e *AckTimeoutError) Unwrap() error {
+	return e.Err
+}
+
+func (e *AckTimeoutError) Timeout() bool {
+	return true
+}
+
+func (e *AckTimeoutError) IsRetryable() bool {
+	return true
+}
+
+// AsyncReadError is returned by WSClient's send methods when the
+// connection's read loop failed since the last send. The client should
+// reconnect before sending again.
+type AsyncReadError struct {
+	Err error
+}
+
+func (e *AsyncReadError) Error() string {
+	return fmt.Sprintf("async read: %s", e.Err)
+}
+
+func (e *AsyncReadError) Unwrap() error {
+	return e.Err
+}
+
+func (e *AsyncReadError) IsRetryable() bool {
+	return true
+}
+
+// isConnErr reports whether err came from the connection rather than from
+// encoding a message.
+func isConnErr(err error) bool {
+	var ne net.Error
+
+	return errors.As(err, &ne) || errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.ErrClosedPipe) ||
+		errors.Is(err, net.ErrClosed) || errors.Is(err, websocket.ErrCloseSent)
+}
+
+// wrapConnErr wraps err in a ConnError if it came from the connection.
+func wrapConnErr(op string, err error) error {
+	if err == nil || !isConnErr(err) {
+		return err
+	}
+
+	return &ConnError{Op: op, Err: err}
+}
+
 type WSConnError struct {
 	StatusCode   int
 	ResponseBody string
@@ -30,6 +163,10 @@ func (e *WSConnError) Error() string {
 	return fmt.Sprintf("Connection Error. Status Code: %d. Response: %s", e.StatusCode, e.ResponseBody)
 }
 
+func (e *WSConnError) Unwrap() error {
+	return e.ConnErr
+}
+
 func (e *WSConnError) IsRetryable() bool {
 	return e.retryable
 }
@@ -0,0 +1,82 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package client
+
+import (
+	"time"
+
+	"github.com/IBM/fluent-forward-go/fluent/protocol"
+)
+
+// Logger is a leveled, structured logger. The keysAndValues are alternating
+// keys and values, as used by log/slog and logr. See NewLogrLogger and
+// NewSlogLogger for adapters.
+type Logger interface {
+	Debug(msg string, keysAndValues ...interface{})
+	Error(err error, msg string, keysAndValues ...interface{})
+}
+
+type noopLogger struct{}
+
+func (noopLogger) Debug(_ string, _ ...interface{}) {}
+
+func (noopLogger) Error(_ error, _ string, _ ...interface{}) {}
+
+// SendInfo describes a completed call to Send or SendRaw.
+type SendInfo struct {
+	// Message is the message passed to Send. It is nil for SendRaw.
+	Message protocol.ChunkEncoder
+	// Raw is the message passed to SendRaw. It is nil for Send.
+	Raw []byte
+	// Chunk is the chunk ID, if an ack was requested.
+	Chunk string
+	// Duration includes the time spent waiting for the ack.
+	Duration time.Duration
+	// Err is the error returned to the caller.
+	Err error
+}
+
+// Hooks are optional callbacks for client events. Any of them may be nil.
+// They are called synchronously, some while the client holds its locks, so
+// they must return quickly and must not call back into the client.
+type Hooks struct {
+	// OnConnect is called after every call to Connect, with its error.
+	OnConnect func(err error)
+	// OnDisconnect is called when an active session is closed by
+	// Disconnect or Reconnect, with the error from closing it.
+	OnDisconnect func(err error)
+	// OnReconnect is called after every call to Reconnect, with its error.
+	OnReconnect func(err error)
+	// OnHandshake is called after every call to Handshake, with its error.
+	OnHandshake func(err error)
+	// OnSend is called after every call to Send and SendRaw.
+	OnSend func(info SendInfo)
+	// OnAck is called when an expected ack is received. The rtt is
+	// measured from the end of the write.
+	OnAck func(chunk string, rtt time.Duration)
+	// OnAckTimeout is called when an ack is not received within the
+	// client's Timeout.
+	OnAckTimeout func(chunk string)
+}
@@ -0,0 +1,233 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package client_test
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	. "github.com/IBM/fluent-forward-go/fluent/client"
+	"github.com/IBM/fluent-forward-go/fluent/client/clientfakes"
+	"github.com/IBM/fluent-forward-go/fluent/protocol"
+	"github.com/go-logr/logr/funcr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// recordingLogger records every log call as a single line.
+type recordingLogger struct {
+	lines []string
+}
+
+func (l *recordingLogger) Debug(msg string, keysAndValues ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprintf("DEBUG %s %v", msg, keysAndValues))
+}
+
+func (l *recordingLogger) Error(err error, msg string, keysAndValues ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprintf("ERROR %s: %v %v", msg, err, keysAndValues))
+}
+
+var _ = Describe("Client logging and hooks", func() {
+	var (
+		factory    *clientfakes.FakeConnectionFactory
+		client     *Client
+		logger     *recordingLogger
+		clientSide net.Conn
+		serverSide net.Conn
+		events     []string
+	)
+
+	record := func(name string) func(error) {
+		return func(err error) {
+			events = append(events, fmt.Sprint(name, " ", err))
+		}
+	}
+
+	BeforeEach(func() {
+		factory = &clientfakes.FakeConnectionFactory{}
+		logger = &recordingLogger{}
+		events = nil
+
+		clientSide, serverSide = net.Pipe()
+		factory.NewReturns(clientSide, nil)
+
+		client = New(ConnectionOptions{
+			Factory:           factory,
+			ConnectionTimeout: 100 * time.Millisecond,
+			Logger:            logger,
+			Hooks: Hooks{
+				OnConnect:    record("connect"),
+				OnDisconnect: record("disconnect"),
+				OnReconnect:  record("reconnect"),
+				OnHandshake:  record("handshake"),
+				OnSend: func(info SendInfo) {
+					events = append(events, fmt.Sprint("send ", info.Chunk != "", " ", info.Err))
+				},
+				OnAck: func(chunk string, rtt time.Duration) {
+					events = append(events, "ack")
+				},
+				OnAckTimeout: func(chunk string) {
+					events = append(events, "ack timeout")
+				},
+			},
+		})
+	})
+
+	AfterEach(func() {
+		serverSide.Close()
+	})
+
+	It("reports the connection lifecycle", func() {
+		Expect(client.Connect()).ToNot(HaveOccurred())
+		Expect(client.Connect()).To(MatchError(ErrSessionActive))
+		Expect(client.Reconnect()).ToNot(HaveOccurred())
+		Expect(client.Disconnect()).ToNot(HaveOccurred())
+
+		Expect(events).To(Equal([]string{
+			"connect <nil>",
+			"disconnect <nil>",
+			"reconnect <nil>",
+			"disconnect <nil>",
+		}))
+
+		Expect(logger.lines).To(ContainElement("DEBUG connected []"))
+		Expect(logger.lines).ToNot(ContainElement(HavePrefix("ERROR connect failed")))
+	})
+
+	It("reports failed connections", func() {
+		factory.NewReturns(nil, errors.New("nope"))
+		Expect(client.Connect()).To(HaveOccurred())
+		Expect(events).To(Equal([]string{"connect nope"}))
+	})
+
+	It("reports failed handshakes", func() {
+		Expect(client.Connect()).ToNot(HaveOccurred())
+		serverSide.Close()
+
+		Expect(client.Handshake()).To(HaveOccurred())
+		Expect(events).To(HaveLen(2))
+		Expect(events[1]).To(HavePrefix("handshake "))
+		Expect(events[1]).ToNot(Equal("handshake <nil>"))
+	})
+
+	Describe("sending", func() {
+		var msg *protocol.Message
+
+		BeforeEach(func() {
+			msg = protocol.NewMessage("foo", map[string]interface{}{"a": "b"})
+		})
+
+		JustBeforeEach(func() {
+			Expect(client.Connect()).ToNot(HaveOccurred())
+			events = nil
+		})
+
+		It("reports sends", func() {
+			go func() {
+				_, _ = msgp.NewReader(serverSide).CopyNext(&discardWriter{})
+			}()
+
+			Expect(client.Send(msg)).ToNot(HaveOccurred())
+			Expect(events).To(Equal([]string{"send false <nil>"}))
+		})
+
+		It("logs failed sends", func() {
+			serverSide.Close()
+
+			Expect(client.Send(msg)).To(HaveOccurred())
+			Expect(events).To(HaveLen(1))
+			Expect(logger.lines[len(logger.lines)-1]).To(HavePrefix("ERROR send failed"))
+		})
+
+		When("RequireAck is set", func() {
+			BeforeEach(func() {
+				client.RequireAck = true
+			})
+
+			It("reports acks", func() {
+				go func() {
+					defer GinkgoRecover()
+
+					var rcvd protocol.Message
+					Expect(rcvd.DecodeMsg(msgp.NewReader(serverSide))).ToNot(HaveOccurred())
+					Expect(msgp.Encode(serverSide, &protocol.AckMessage{Ack: rcvd.Options.Chunk})).ToNot(HaveOccurred())
+				}()
+
+				Expect(client.Send(msg)).ToNot(HaveOccurred())
+				Expect(events).To(Equal([]string{"ack", "send true <nil>"}))
+			})
+
+			It("reports ack timeouts", func() {
+				go func() {
+					_, _ = msgp.NewReader(serverSide).CopyNext(&discardWriter{})
+				}()
+
+				err := client.Send(msg)
+				Expect(err).To(HaveOccurred())
+				Expect(events).To(HaveLen(2))
+				Expect(events[0]).To(Equal("ack timeout"))
+			})
+		})
+	})
+})
+
+type discardWriter struct{}
+
+func (discardWriter) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+var _ = Describe("NewLogrLogger", func() {
+	It("writes debug messages at V(1) and errors", func() {
+		var lines []string
+
+		sink := funcr.New(func(prefix, args string) {
+			lines = append(lines, args)
+		}, funcr.Options{Verbosity: 1})
+
+		logger := NewLogrLogger(sink)
+		logger.Debug("connected", "chunk", "abc")
+		logger.Error(errors.New("nope"), "send failed")
+
+		Expect(lines).To(HaveLen(2))
+		Expect(lines[0]).To(ContainSubstring(`"msg"="connected"`))
+		Expect(lines[0]).To(ContainSubstring(`"chunk"="abc"`))
+		Expect(lines[1]).To(ContainSubstring(`"error"="nope"`))
+	})
+
+	It("drops debug messages below the sink's verbosity", func() {
+		var lines []string
+
+		sink := funcr.New(func(prefix, args string) {
+			lines = append(lines, args)
+		}, funcr.Options{})
+
+		NewLogrLogger(sink).Debug("connected")
+		Expect(lines).To(BeEmpty())
+	})
+})
@@ -0,0 +1,47 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package client
+
+import (
+	"github.com/go-logr/logr"
+)
+
+type logrLogger struct {
+	logger logr.Logger
+}
+
+// NewLogrLogger returns a Logger that writes to a logr.Logger. Debug
+// messages are logged at verbosity level 1.
+func NewLogrLogger(logger logr.Logger) Logger {
+	return &logrLogger{logger: logger}
+}
+
+func (l *logrLogger) Debug(msg string, keysAndValues ...interface{}) {
+	l.logger.V(1).Info(msg, keysAndValues...)
+}
+
+func (l *logrLogger) Error(err error, msg string, keysAndValues ...interface{}) {
+	l.logger.Error(err, msg, keysAndValues...)
+}
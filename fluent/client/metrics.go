@@ -0,0 +1,67 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package client
+
+import (
+	"io"
+
+	"github.com/IBM/fluent-forward-go/fluent/protocol"
+)
+
+// describe returns the tag and, where it is known without decoding, the
+// number of events carried by the message.
+func describe(e protocol.ChunkEncoder) (tag string, events int) {
+	switch m := e.(type) {
+	case *protocol.Message:
+		return m.Tag, 1
+	case *protocol.MessageExt:
+		return m.Tag, 1
+	case *protocol.ForwardMessage:
+		return m.Tag, len(m.Entries)
+	case *protocol.PackedForwardMessage:
+		if m.Options != nil && m.Options.Size != nil {
+			events = *m.Options.Size
+		}
+
+		return m.Tag, events
+	}
+
+	return "", 0
+}
+
+// countingWriter counts the bytes written to w. It is only used when a
+// metrics.Recorder is configured, so that the default send path stays free
+// of allocations.
+type countingWriter struct {
+	w io.Writer
+	n int
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += n
+
+	return n, err
+}
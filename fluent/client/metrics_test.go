@@ -0,0 +1,190 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package client_test
+
+import (
+	"net"
+	"time"
+
+	. "github.com/IBM/fluent-forward-go/fluent/client"
+	"github.com/IBM/fluent-forward-go/fluent/client/clientfakes"
+	"github.com/IBM/fluent-forward-go/fluent/metrics/metricsfakes"
+	"github.com/IBM/fluent-forward-go/fluent/protocol"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/tinylib/msgp/msgp"
+)
+
+var _ = Describe("Client metrics", func() {
+	var (
+		factory    *ConnFactory
+		client     *Client
+		recorder   *metricsfakes.FakeRecorder
+		serverSide net.Conn
+	)
+
+	BeforeEach(func() {
+		var clientSide net.Conn
+		clientSide, serverSide = net.Pipe()
+
+		fake := &clientfakes.FakeConnectionFactory{}
+		fake.NewReturns(clientSide, nil)
+
+		factory = &ConnFactory{Address: "localhost:24224"}
+		recorder = &metricsfakes.FakeRecorder{}
+
+		client = New(ConnectionOptions{
+			Factory:           fake,
+			ConnectionTimeout: 100 * time.Millisecond,
+			Metrics:           recorder,
+		})
+	})
+
+	JustBeforeEach(func() {
+		Expect(client.Connect()).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		serverSide.Close()
+	})
+
+	It("records the endpoint of a ConnFactory", func() {
+		client.ConnectionFactory = factory
+
+		go func() {
+			_, _ = serverSide.Read(make([]byte, 16))
+		}()
+
+		Expect(client.SendRaw([]byte{0xc0})).ToNot(HaveOccurred())
+
+		Expect(recorder.SentCallCount()).To(Equal(1))
+		Expect(recorder.SentArgsForCall(0).Endpoint).To(Equal("localhost:24224"))
+	})
+
+	It("records sends", func() {
+		msg := protocol.NewForwardMessage("foo", protocol.EntryList{
+			{Timestamp: protocol.EventTimeNow(), Record: map[string]interface{}{"a": "b"}},
+			{Timestamp: protocol.EventTimeNow(), Record: map[string]interface{}{"c": "d"}},
+		})
+		bits, err := msg.MarshalMsg(nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		go func() {
+			_, _ = msgp.NewReader(serverSide).CopyNext(&discardWriter{})
+		}()
+
+		Expect(client.Send(msg)).ToNot(HaveOccurred())
+
+		Expect(recorder.SentCallCount()).To(Equal(1))
+		stats := recorder.SentArgsForCall(0)
+		Expect(stats.Tag).To(Equal("foo"))
+		Expect(stats.Events).To(Equal(2))
+		Expect(stats.Bytes).To(Equal(len(bits)))
+		Expect(stats.Chunked).To(BeFalse())
+		Expect(stats.Latency).To(BeNumerically(">", 0))
+		Expect(stats.Err).ToNot(HaveOccurred())
+	})
+
+	It("records failed sends", func() {
+		serverSide.Close()
+
+		Expect(client.SendMessage("foo", map[string]interface{}{})).To(HaveOccurred())
+
+		Expect(recorder.SentCallCount()).To(Equal(1))
+		Expect(recorder.SentArgsForCall(0).Err).To(HaveOccurred())
+	})
+
+	It("records the compression ratio", func() {
+		go func() {
+			_, _ = msgp.NewReader(serverSide).CopyNext(&discardWriter{})
+		}()
+
+		entries := protocol.EntryList{
+			{Timestamp: protocol.EventTimeNow(), Record: map[string]interface{}{"a": "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"}},
+		}
+		bits, err := entries.MarshalPacked()
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(client.SendCompressed("foo", entries)).ToNot(HaveOccurred())
+
+		Expect(recorder.CompressedCallCount()).To(Equal(1))
+		_, tag, raw, compressed := recorder.CompressedArgsForCall(0)
+		Expect(tag).To(Equal("foo"))
+		Expect(raw).To(Equal(len(bits)))
+		Expect(compressed).To(BeNumerically(">", 0))
+
+		Expect(recorder.SentArgsForCall(0).Events).To(Equal(1))
+	})
+
+	It("records reconnects", func() {
+		Expect(client.Reconnect()).ToNot(HaveOccurred())
+
+		Expect(recorder.ReconnectedCallCount()).To(Equal(1))
+		_, err := recorder.ReconnectedArgsForCall(0)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	When("RequireAck is set", func() {
+		BeforeEach(func() {
+			client.RequireAck = true
+		})
+
+		It("records acks", func() {
+			go func() {
+				defer GinkgoRecover()
+
+				var rcvd protocol.Message
+				Expect(rcvd.DecodeMsg(msgp.NewReader(serverSide))).ToNot(HaveOccurred())
+				Expect(msgp.Encode(serverSide, &protocol.AckMessage{Ack: rcvd.Options.Chunk})).ToNot(HaveOccurred())
+			}()
+
+			Expect(client.SendMessage("foo", map[string]interface{}{})).ToNot(HaveOccurred())
+
+			Expect(recorder.AckReceivedCallCount()).To(Equal(1))
+			Expect(recorder.SentArgsForCall(0).Chunked).To(BeTrue())
+		})
+
+		It("records ack timeouts", func() {
+			go func() {
+				_, _ = msgp.NewReader(serverSide).CopyNext(&discardWriter{})
+			}()
+
+			Expect(client.SendMessage("foo", map[string]interface{}{})).To(HaveOccurred())
+
+			Expect(recorder.AckTimedOutCallCount()).To(Equal(1))
+			Expect(recorder.AckReceivedCallCount()).To(Equal(0))
+		})
+	})
+
+	It("records handshakes", func() {
+		serverSide.Close()
+
+		Expect(client.Handshake()).To(HaveOccurred())
+
+		Expect(recorder.HandshakeCallCount()).To(Equal(1))
+		_, _, err := recorder.HandshakeArgsForCall(0)
+		Expect(err).To(HaveOccurred())
+	})
+})
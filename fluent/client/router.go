@@ -0,0 +1,282 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package client
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/IBM/fluent-forward-go/fluent/protocol"
+	"github.com/IBM/fluent-forward-go/fluent/tag"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// Route sends the messages whose tags match Pattern to Clients.
+type Route struct {
+	// Pattern is one or more Fluentd match patterns, separated by
+	// whitespace, e.g. "app.** {audit,security}.*". See tag.Pattern.
+	Pattern string
+	// Clients receive a copy of every message the route matches, in
+	// order.
+	Clients []MessageClient
+}
+
+// RouterOptions configures a Router.
+type RouterOptions struct {
+	// Routes are tried in order; a message goes to the first route
+	// whose pattern matches its tag, as in a Fluentd configuration.
+	Routes []Route
+	// Default receives the messages no route matches. If nil, sending
+	// them fails with ErrNoRoute.
+	Default MessageClient
+}
+
+// Router is a MessageClient that sends each message to downstream clients
+// chosen by its tag. A route with several clients copies the message to
+// each of them. A Router does not change after it is created, so it is
+// as safe for concurrent use as its clients are.
+type Router struct {
+	routes   []route
+	fallback MessageClient
+	clients  []MessageClient
+}
+
+type route struct {
+	pattern *tag.Pattern
+	clients []MessageClient
+}
+
+// NewRouter returns a Router for opts. It fails if a pattern is invalid.
+func NewRouter(opts RouterOptions) (*Router, error) {
+	r := &Router{fallback: opts.Default}
+
+	for _, rt := range opts.Routes {
+		p, err := tag.Compile(rt.Pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		r.routes = append(r.routes, route{pattern: p, clients: rt.Clients})
+		r.addClients(rt.Clients...)
+	}
+
+	if opts.Default != nil {
+		r.addClients(opts.Default)
+	}
+
+	return r, nil
+}
+
+// addClients remembers each client once, so that clients shared by
+// several routes are connected and disconnected once.
+func (r *Router) addClients(clients ...MessageClient) {
+	for _, c := range clients {
+		known := false
+
+		for _, k := range r.clients {
+			if k == c {
+				known = true
+				break
+			}
+		}
+
+		if !known {
+			r.clients = append(r.clients, c)
+		}
+	}
+}
+
+// Clients returns the clients that messages with tag are sent to, or nil
+// if there are none.
+func (r *Router) Clients(tag string) []MessageClient {
+	for _, rt := range r.routes {
+		if rt.pattern.Match(tag) {
+			return rt.clients
+		}
+	}
+
+	if r.fallback != nil {
+		return []MessageClient{r.fallback}
+	}
+
+	return nil
+}
+
+// forEach calls send with every client for tag. Every client is tried,
+// even if one fails, and the first error is returned.
+func (r *Router) forEach(tag string, send func(c MessageClient) error) error {
+	clients := r.Clients(tag)
+	if len(clients) == 0 {
+		return fmt.Errorf("%w: %s", ErrNoRoute, tag)
+	}
+
+	var first error
+
+	for _, c := range clients {
+		if err := send(c); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	return first
+}
+
+// forAll calls fn with every distinct client and returns the first error.
+func (r *Router) forAll(fn func(c MessageClient) error) error {
+	var first error
+
+	for _, c := range r.clients {
+		if err := fn(c); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	return first
+}
+
+// Connect connects every client. It tries them all, even if one fails,
+// and returns the first error.
+func (r *Router) Connect() error {
+	return r.forAll(MessageClient.Connect)
+}
+
+// Disconnect disconnects every client. It tries them all, even if one
+// fails, and returns the first error.
+func (r *Router) Disconnect() error {
+	return r.forAll(MessageClient.Disconnect)
+}
+
+// Reconnect reconnects every client. It tries them all, even if one
+// fails, and returns the first error.
+func (r *Router) Reconnect() error {
+	return r.forAll(MessageClient.Reconnect)
+}
+
+// Send sends e to the clients for its tag. The tag of a message type
+// other than those in the protocol package is found by encoding it.
+func (r *Router) Send(e protocol.ChunkEncoder) error {
+	tag, err := tagOf(e)
+	if err != nil {
+		return err
+	}
+
+	return r.forEach(tag, func(c MessageClient) error {
+		return c.Send(e)
+	})
+}
+
+// SendRaw sends raw to the clients for the tag of the first message in
+// it, so every message in raw should share that tag.
+func (r *Router) SendRaw(raw []byte) error {
+	tag, err := readTag(raw)
+	if err != nil {
+		return err
+	}
+
+	return r.forEach(tag, func(c MessageClient) error {
+		return c.SendRaw(raw)
+	})
+}
+
+func (r *Router) SendCompressed(tag string, entries protocol.EntryList) error {
+	return r.forEach(tag, func(c MessageClient) error {
+		return c.SendCompressed(tag, entries)
+	})
+}
+
+func (r *Router) SendCompressedFromBytes(tag string, entries []byte) error {
+	return r.forEach(tag, func(c MessageClient) error {
+		return c.SendCompressedFromBytes(tag, entries)
+	})
+}
+
+func (r *Router) SendForward(tag string, entries protocol.EntryList) error {
+	return r.forEach(tag, func(c MessageClient) error {
+		return c.SendForward(tag, entries)
+	})
+}
+
+func (r *Router) SendMessage(tag string, record interface{}) error {
+	return r.forEach(tag, func(c MessageClient) error {
+		return c.SendMessage(tag, record)
+	})
+}
+
+func (r *Router) SendMessageExt(tag string, record interface{}) error {
+	return r.forEach(tag, func(c MessageClient) error {
+		return c.SendMessageExt(tag, record)
+	})
+}
+
+func (r *Router) SendPacked(tag string, entries protocol.EntryList) error {
+	return r.forEach(tag, func(c MessageClient) error {
+		return c.SendPacked(tag, entries)
+	})
+}
+
+func (r *Router) SendPackedFromBytes(tag string, entries []byte) error {
+	return r.forEach(tag, func(c MessageClient) error {
+		return c.SendPackedFromBytes(tag, entries)
+	})
+}
+
+// tagOf returns the tag of a message.
+func tagOf(e protocol.ChunkEncoder) (string, error) {
+	switch m := e.(type) {
+	case *protocol.Message:
+		return m.Tag, nil
+	case *protocol.MessageExt:
+		return m.Tag, nil
+	case *protocol.ForwardMessage:
+		return m.Tag, nil
+	case *protocol.PackedForwardMessage:
+		return m.Tag, nil
+	case protocol.RawMessage:
+		return readTag(m)
+	}
+
+	var buf bytes.Buffer
+
+	if err := msgp.Encode(&buf, e); err != nil {
+		return "", err
+	}
+
+	return readTag(buf.Bytes())
+}
+
+// readTag reads the tag of the marshaled message b.
+func readTag(b []byte) (string, error) {
+	_, b, err := msgp.ReadArrayHeaderBytes(b)
+	if err != nil {
+		return "", msgp.WrapError(err, "Array Header")
+	}
+
+	tag, _, err := msgp.ReadStringBytes(b)
+	if err != nil {
+		return "", msgp.WrapError(err, "Tag")
+	}
+
+	return tag, nil
+}
@@ -0,0 +1,144 @@
+package client_test
+
+import (
+	"errors"
+
+	. "github.com/IBM/fluent-forward-go/fluent/client"
+	"github.com/IBM/fluent-forward-go/fluent/client/clientfakes"
+	"github.com/IBM/fluent-forward-go/fluent/protocol"
+	"github.com/IBM/fluent-forward-go/fluent/tag"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Router", func() {
+	var (
+		app, audit, archive, fallback *clientfakes.FakeMessageClient
+		opts                          RouterOptions
+		router                        *Router
+	)
+
+	BeforeEach(func() {
+		app = &clientfakes.FakeMessageClient{}
+		audit = &clientfakes.FakeMessageClient{}
+		archive = &clientfakes.FakeMessageClient{}
+		fallback = &clientfakes.FakeMessageClient{}
+
+		opts = RouterOptions{
+			Routes: []Route{
+				{Pattern: "app.**", Clients: []MessageClient{app}},
+				{Pattern: "{audit,security}.*", Clients: []MessageClient{audit, archive}},
+				{Pattern: "app.debug", Clients: []MessageClient{archive}},
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		router, err = NewRouter(opts)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("sends each message to the first route that matches", func() {
+		Expect(router.SendMessage("app.debug", map[string]interface{}{"a": "b"})).To(Succeed())
+
+		Expect(app.SendMessageCallCount()).To(Equal(1))
+		tag, record := app.SendMessageArgsForCall(0)
+		Expect(tag).To(Equal("app.debug"))
+		Expect(record).To(Equal(map[string]interface{}{"a": "b"}))
+		Expect(archive.SendMessageCallCount()).To(BeZero())
+	})
+
+	It("copies messages to every client of a route", func() {
+		entries := protocol.EntryList{{Timestamp: protocol.EventTimeNow(), Record: map[string]interface{}{"a": "b"}}}
+
+		Expect(router.SendForward("security.login", entries)).To(Succeed())
+		Expect(audit.SendForwardCallCount()).To(Equal(1))
+		Expect(archive.SendForwardCallCount()).To(Equal(1))
+		Expect(app.SendForwardCallCount()).To(BeZero())
+	})
+
+	It("tries every client of a route and returns the first error", func() {
+		audit.SendPackedReturns(errors.New("boom"))
+
+		err := router.SendPacked("audit.login", nil)
+		Expect(err).To(MatchError("boom"))
+		Expect(archive.SendPackedCallCount()).To(Equal(1))
+	})
+
+	It("routes messages by the tag they carry", func() {
+		msg := protocol.NewMessage("audit.login", map[string]interface{}{"a": "b"})
+		Expect(router.Send(msg)).To(Succeed())
+		Expect(audit.SendCallCount()).To(Equal(1))
+		Expect(audit.SendArgsForCall(0)).To(BeIdenticalTo(msg))
+
+		bits, err := protocol.NewMessage("app.web", nil).MarshalMsg(nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(router.Send(protocol.RawMessage(bits))).To(Succeed())
+		Expect(app.SendCallCount()).To(Equal(1))
+
+		Expect(router.SendRaw(bits)).To(Succeed())
+		Expect(app.SendRawCallCount()).To(Equal(1))
+		Expect(app.SendRawArgsForCall(0)).To(Equal(bits))
+	})
+
+	It("fails messages that no route matches", func() {
+		err := router.SendMessage("other", nil)
+		Expect(err).To(MatchError(ErrNoRoute))
+		Expect(err).To(MatchError(ContainSubstring("other")))
+	})
+
+	When("there is a default client", func() {
+		BeforeEach(func() {
+			opts.Default = fallback
+		})
+
+		It("sends it the messages no route matches", func() {
+			Expect(router.SendMessageExt("other", nil)).To(Succeed())
+			Expect(fallback.SendMessageExtCallCount()).To(Equal(1))
+		})
+	})
+
+	It("connects and disconnects each client once", func() {
+		Expect(router.Connect()).To(Succeed())
+		Expect(app.ConnectCallCount()).To(Equal(1))
+		Expect(audit.ConnectCallCount()).To(Equal(1))
+		Expect(archive.ConnectCallCount()).To(Equal(1))
+
+		app.DisconnectReturns(errors.New("boom"))
+		Expect(router.Disconnect()).To(MatchError("boom"))
+		Expect(archive.DisconnectCallCount()).To(Equal(1))
+	})
+
+	It("rejects invalid patterns", func() {
+		_, err := NewRouter(RouterOptions{Routes: []Route{{Pattern: " "}}})
+		Expect(err).To(MatchError(tag.ErrInvalidPattern))
+	})
+
+	DescribeTable("matches tags like Fluentd",
+		func(pattern, tag string, matches bool) {
+			r, err := NewRouter(RouterOptions{Routes: []Route{{Pattern: pattern, Clients: []MessageClient{app}}}})
+			Expect(err).NotTo(HaveOccurred())
+
+			if matches {
+				Expect(r.Clients(tag)).To(HaveLen(1))
+			} else {
+				Expect(r.Clients(tag)).To(BeEmpty())
+			}
+		},
+		Entry(nil, "a.*", "a.b", true),
+		Entry(nil, "a.*", "a", false),
+		Entry(nil, "a.*", "a.b.c", false),
+		Entry(nil, "a.**", "a", true),
+		Entry(nil, "a.**", "a.b.c", true),
+		Entry(nil, "a.**", "ab", false),
+		Entry(nil, "a.**.c", "a.c", true),
+		Entry(nil, "a.**.c", "a.b.d.c", true),
+		Entry(nil, "**.c", "c", true),
+		Entry(nil, "**.c", "bc", false),
+		Entry(nil, "{a,b}.*", "b.x", true),
+		Entry(nil, "{a,b}.*", "c.x", false),
+		Entry(nil, "a b", "b", true),
+	)
+})
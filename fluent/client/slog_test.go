@@ -0,0 +1,60 @@
+//go:build go1.21
+
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package client_test
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+
+	. "github.com/IBM/fluent-forward-go/fluent/client"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NewSlogLogger", func() {
+	var buf *bytes.Buffer
+
+	BeforeEach(func() {
+		buf = &bytes.Buffer{}
+	})
+
+	It("writes debug messages and errors", func() {
+		logger := NewSlogLogger(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		logger.Debug("connected", "chunk", "abc")
+		logger.Error(errors.New("nope"), "send failed")
+
+		Expect(buf.String()).To(ContainSubstring("level=DEBUG msg=connected chunk=abc"))
+		Expect(buf.String()).To(ContainSubstring("level=ERROR msg=\"send failed\" error=nope"))
+	})
+
+	It("respects the handler's level", func() {
+		logger := NewSlogLogger(slog.NewTextHandler(buf, nil))
+		logger.Debug("connected")
+		Expect(buf.String()).To(BeEmpty())
+	})
+})
@@ -0,0 +1,109 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package client
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultTokenRefreshWindow is how long before expiry a CachingTokenProvider
+// refreshes its token when no other window is configured.
+const DefaultTokenRefreshWindow = 30 * time.Second
+
+// TokenProvider supplies the token sent when a websocket connection is
+// opened. DefaultWSConnectionFactory calls Token on every dial, so
+// implementations should cache tokens that are expensive to obtain.
+//
+//counterfeiter:generate . TokenProvider
+type TokenProvider interface {
+	Token() (string, error)
+}
+
+// TokenRefreshFunc fetches a new token and reports when it expires. A zero
+// expiresAt means the token does not expire.
+type TokenRefreshFunc func() (token string, expiresAt time.Time, err error)
+
+// CachingTokenProvider is a TokenProvider that caches the token returned by
+// a TokenRefreshFunc and refreshes it shortly before it expires.
+type CachingTokenProvider struct {
+	refresh       TokenRefreshFunc
+	refreshWindow time.Duration
+	token         string
+	expiresAt     time.Time
+	mutex         sync.Mutex
+}
+
+// NewCachingTokenProvider returns a CachingTokenProvider that calls refresh
+// whenever the cached token is missing or due to expire within
+// refreshWindow. If refreshWindow is zero, DefaultTokenRefreshWindow is used.
+func NewCachingTokenProvider(refresh TokenRefreshFunc, refreshWindow time.Duration) *CachingTokenProvider {
+	if refreshWindow == 0 {
+		refreshWindow = DefaultTokenRefreshWindow
+	}
+
+	return &CachingTokenProvider{
+		refresh:       refresh,
+		refreshWindow: refreshWindow,
+	}
+}
+
+// Token returns the cached token, refreshing it first if it is missing or
+// about to expire. If the refresh fails but the cached token has not yet
+// expired, the cached token is returned. It is thread safe.
+func (p *CachingTokenProvider) Token() (string, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	now := time.Now()
+
+	if p.token != "" && (p.expiresAt.IsZero() || p.expiresAt.Sub(now) > p.refreshWindow) {
+		return p.token, nil
+	}
+
+	token, expiresAt, err := p.refresh()
+	if err != nil {
+		if p.token != "" && (p.expiresAt.IsZero() || now.Before(p.expiresAt)) {
+			return p.token, nil
+		}
+
+		return "", err
+	}
+
+	p.token = token
+	p.expiresAt = expiresAt
+
+	return token, nil
+}
+
+// Invalidate discards the cached token so that the next call to Token
+// refreshes it; e.g., after the server rejects the token. It is thread safe.
+func (p *CachingTokenProvider) Invalidate() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.token = ""
+	p.expiresAt = time.Time{}
+}
@@ -0,0 +1,119 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package client_test
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/IBM/fluent-forward-go/fluent/client"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CachingTokenProvider", func() {
+	var (
+		provider   *CachingTokenProvider
+		calls      int
+		token      string
+		expiresAt  time.Time
+		refreshErr error
+	)
+
+	BeforeEach(func() {
+		calls = 0
+		token = "t1"
+		expiresAt = time.Now().Add(time.Hour)
+		refreshErr = nil
+
+		provider = NewCachingTokenProvider(func() (string, time.Time, error) {
+			calls++
+			return token, expiresAt, refreshErr
+		}, time.Minute)
+	})
+
+	It("fetches a token on first use and caches it", func() {
+		Expect(provider.Token()).To(Equal("t1"))
+		Expect(provider.Token()).To(Equal("t1"))
+		Expect(calls).To(Equal(1))
+	})
+
+	It("caches tokens without an expiry", func() {
+		expiresAt = time.Time{}
+		Expect(provider.Token()).To(Equal("t1"))
+		Expect(provider.Token()).To(Equal("t1"))
+		Expect(calls).To(Equal(1))
+	})
+
+	It("refreshes the token when it is about to expire", func() {
+		expiresAt = time.Now().Add(30 * time.Second)
+		Expect(provider.Token()).To(Equal("t1"))
+
+		token = "t2"
+		Expect(provider.Token()).To(Equal("t2"))
+		Expect(calls).To(Equal(2))
+	})
+
+	It("refreshes the token after Invalidate", func() {
+		Expect(provider.Token()).To(Equal("t1"))
+
+		token = "t2"
+		provider.Invalidate()
+		Expect(provider.Token()).To(Equal("t2"))
+		Expect(calls).To(Equal(2))
+	})
+
+	When("the refresh fails", func() {
+		BeforeEach(func() {
+			refreshErr = errors.New("BOOM")
+		})
+
+		It("returns the error if there is no usable token", func() {
+			_, err := provider.Token()
+			Expect(err).To(MatchError("BOOM"))
+		})
+
+		It("returns the cached token until it expires", func() {
+			refreshErr = nil
+			expiresAt = time.Now().Add(30 * time.Second)
+			Expect(provider.Token()).To(Equal("t1"))
+
+			refreshErr = errors.New("BOOM")
+			Expect(provider.Token()).To(Equal("t1"))
+			Expect(calls).To(Equal(2))
+		})
+	})
+
+	It("uses the default refresh window", func() {
+		p := NewCachingTokenProvider(func() (string, time.Time, error) {
+			calls++
+			return "t", time.Now().Add(DefaultTokenRefreshWindow / 2), nil
+		}, 0)
+
+		Expect(p.Token()).To(Equal("t"))
+		Expect(p.Token()).To(Equal("t"))
+		Expect(calls).To(Equal(2))
+	})
+})
@@ -41,6 +41,21 @@ const (
 	DefaultCloseDeadline = 5 * time.Second
 )
 
+var (
+	// ErrAlreadyClosed is returned by Close and CloseWithMsg if the
+	// connection was already closed.
+	ErrAlreadyClosed = errors.New("multiple close calls")
+	// ErrAlreadyListening is returned by Listen if the connection is
+	// already listening.
+	ErrAlreadyListening = errors.New("already listening on this connection")
+	// ErrCloseDeadlineExpired is returned by Close and CloseWithMsg if the
+	// peer did not answer the closing handshake within the close deadline.
+	ErrCloseDeadlineExpired = errors.New("close deadline expired")
+	// ErrWriterClosed is returned when a writer from NextWriter is closed
+	// more than once.
+	ErrWriterClosed = errors.New("writer already closed")
+)
+
 type Logger interface {
 	Println(v ...interface{})
 	Printf(format string, v ...interface{})
@@ -69,6 +84,11 @@ type ConnectionOptions struct {
 	WriteDeadline time.Time
 	// Logger is an optional debug log writer.
 	Logger Logger
+	// CompressionLevel sets the flate compression level for outgoing messages
+	// when permessage-deflate has been negotiated with the peer. See the
+	// compress/flate package for valid levels. The zero value keeps the
+	// websocket library's default level.
+	CompressionLevel int
 }
 
 type ConnState uint8
@@ -92,6 +112,7 @@ type Connection interface {
 	ReadHandler() ReadHandler
 	SetReadHandler(rh ReadHandler)
 	Write(data []byte) (int, error)
+	NextUncompressedWriter(messageType int) (io.WriteCloser, error)
 }
 
 type connection struct {
@@ -105,6 +126,39 @@ type connection struct {
 	done          chan struct{}
 	connState     ConnState
 	closeDeadline time.Duration
+	// writeCompression mirrors the value last passed to EnableWriteCompression
+	// so that it can be restored after an uncompressed write.
+	writeCompression bool
+	// writers are handed out by NextWriter in turn. Only one can be open at
+	// a time, so a writer closed again after the next call finds itself
+	// already closed instead of releasing the lock held by the newer one.
+	writers    [2]lockedWriter
+	nextWriter int
+}
+
+// lockedWriter wraps the websocket library's message writer and holds the
+// connection's write lock until it is closed.
+type lockedWriter struct {
+	io.WriteCloser
+	wsc                *connection
+	restoreCompression bool
+}
+
+func (lw *lockedWriter) Close() error {
+	if lw.WriteCloser == nil {
+		return ErrWriterClosed
+	}
+
+	err := lw.WriteCloser.Close()
+
+	if lw.restoreCompression {
+		lw.wsc.Conn.EnableWriteCompression(lw.wsc.writeCompression)
+	}
+
+	lw.WriteCloser = nil
+	lw.wsc.writeLock.Unlock()
+
+	return err
 }
 
 func NewConnection(conn ext.Conn, opts ConnectionOptions) (Connection, error) {
@@ -113,6 +167,9 @@ func NewConnection(conn ext.Conn, opts ConnectionOptions) (Connection, error) {
 		done:      make(chan struct{}),
 		connState: ConnStateOpen,
 		logger:    opts.Logger,
+		// write compression is enabled by default and only takes effect
+		// if it was negotiated during the opening handshake
+		writeCompression: true,
 	}
 
 	if wsc.logger == nil {
@@ -159,6 +216,12 @@ func NewConnection(conn ext.Conn, opts ConnectionOptions) (Connection, error) {
 
 	wsc.closeDeadline = opts.CloseDeadline
 
+	if opts.CompressionLevel != 0 {
+		if err := wsc.SetCompressionLevel(opts.CompressionLevel); err != nil {
+			return nil, err
+		}
+	}
+
 	if err := wsc.SetReadDeadline(opts.ReadDeadline); err != nil {
 		return nil, err
 	}
@@ -208,7 +271,7 @@ func (wsc *connection) CloseWithMsg(closeCode int, msg string) error {
 
 	if wsc.Closed() {
 		wsc.closeLock.Unlock()
-		return errors.New("multiple close calls")
+		return ErrAlreadyClosed
 	}
 
 	wsc.unsetConnState(ConnStateOpen)
@@ -238,7 +301,7 @@ func (wsc *connection) CloseWithMsg(closeCode int, msg string) error {
 			select {
 			case <-time.After(wsc.closeDeadline):
 				// sent a close, but never heard back, close anyway
-				err = errors.New("close deadline expired")
+				err = ErrCloseDeadlineExpired
 			case <-wsc.done:
 			}
 		}
@@ -321,7 +384,7 @@ func (wsc *connection) Listen() error {
 
 	if wsc.hasConnState(ConnStateListening) {
 		wsc.listenLock.Unlock()
-		return errors.New("already listening on this connection")
+		return ErrAlreadyListening
 	}
 
 	wsc.logger.Println("listening")
@@ -379,3 +442,57 @@ func (wsc *connection) Write(data []byte) (int, error) {
 
 	return len(data), nil
 }
+
+// NextWriter returns a writer for the next message to send. Unlike the
+// websocket library's NextWriter, it holds the connection's write lock, so
+// concurrent writes wait until the writer is closed. The writer MUST be
+// closed exactly once; it is reused by later calls.
+func (wsc *connection) NextWriter(messageType int) (io.WriteCloser, error) {
+	return wsc.openWriter(messageType, true)
+}
+
+// NextUncompressedWriter is like NextWriter, but the message is written with
+// compression disabled, even if permessage-deflate was negotiated. It is
+// intended for payloads that are already compressed, such as gzip-compressed
+// PackedForwardMessages, where deflating again costs CPU without saving bytes.
+func (wsc *connection) NextUncompressedWriter(messageType int) (io.WriteCloser, error) {
+	return wsc.openWriter(messageType, false)
+}
+
+func (wsc *connection) openWriter(messageType int, compress bool) (io.WriteCloser, error) {
+	wsc.writeLock.Lock()
+
+	if !compress {
+		wsc.Conn.EnableWriteCompression(false)
+	}
+
+	w, err := wsc.Conn.NextWriter(messageType)
+	if err != nil {
+		if !compress {
+			wsc.Conn.EnableWriteCompression(wsc.writeCompression)
+		}
+
+		wsc.writeLock.Unlock()
+
+		return nil, err
+	}
+
+	lw := &wsc.writers[wsc.nextWriter]
+	wsc.nextWriter = 1 - wsc.nextWriter
+
+	*lw = lockedWriter{
+		WriteCloser:        w,
+		wsc:                wsc,
+		restoreCompression: !compress,
+	}
+
+	return lw, nil
+}
+
+func (wsc *connection) EnableWriteCompression(enable bool) {
+	wsc.writeLock.Lock()
+	defer wsc.writeLock.Unlock()
+
+	wsc.writeCompression = enable
+	wsc.Conn.EnableWriteCompression(enable)
+}
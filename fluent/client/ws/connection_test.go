@@ -26,14 +26,18 @@ package ws_test
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"testing"
 	"time"
 
 	"github.com/IBM/fluent-forward-go/fluent/client/ws"
+	"github.com/IBM/fluent-forward-go/fluent/client/ws/ext/extfakes"
 	"github.com/gorilla/websocket"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -230,8 +234,8 @@ var _ = Describe("Connection", func() {
 
 		When("already listening", func() {
 			It("errors", func() {
-				Expect(connection.Listen().Error()).To(MatchRegexp("already listening on this connection"))
-				Expect(connection.Listen().Error()).To(MatchRegexp("already listening on this connection"))
+				Expect(connection.Listen()).To(MatchError(ws.ErrAlreadyListening))
+				Expect(connection.Listen()).To(MatchError(ws.ErrAlreadyListening))
 			})
 		})
 
@@ -297,7 +301,7 @@ var _ = Describe("Connection", func() {
 		When("called multiple times", func() {
 			It("errors", func() {
 				Expect(connection.Close()).ToNot(HaveOccurred())
-				Expect(connection.Close().Error()).To(MatchRegexp("multiple close calls"))
+				Expect(connection.Close()).To(MatchError(ws.ErrAlreadyClosed))
 			})
 		})
 
@@ -330,3 +334,204 @@ var _ = Describe("Connection", func() {
 		})
 	})
 })
+
+var _ = Describe("Connection compression", func() {
+	var (
+		conn *extfakes.FakeConn
+		opts ws.ConnectionOptions
+	)
+
+	BeforeEach(func() {
+		conn = &extfakes.FakeConn{}
+		opts = ws.ConnectionOptions{}
+	})
+
+	Describe("NewConnection", func() {
+		It("leaves the compression level alone by default", func() {
+			_, err := ws.NewConnection(conn, opts)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(conn.SetCompressionLevelCallCount()).To(Equal(0))
+		})
+
+		When("CompressionLevel is set", func() {
+			BeforeEach(func() {
+				opts.CompressionLevel = 9
+			})
+
+			It("sets the compression level", func() {
+				_, err := ws.NewConnection(conn, opts)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(conn.SetCompressionLevelCallCount()).To(Equal(1))
+				Expect(conn.SetCompressionLevelArgsForCall(0)).To(Equal(9))
+			})
+
+			It("returns an error if the level is invalid", func() {
+				conn.SetCompressionLevelReturns(errors.New("invalid compression level"))
+				_, err := ws.NewConnection(conn, opts)
+				Expect(err).To(MatchError("invalid compression level"))
+			})
+		})
+	})
+})
+
+type closeBuffer struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (cb *closeBuffer) Close() error {
+	cb.closed = true
+	return nil
+}
+
+// staticWriterConn returns the same writer from every NextWriter call
+// without recording the call, so that it does not allocate.
+type staticWriterConn struct {
+	*extfakes.FakeConn
+	w io.WriteCloser
+}
+
+func (c *staticWriterConn) NextWriter(int) (io.WriteCloser, error) {
+	return c.w, nil
+}
+
+type discardCloser struct{}
+
+func (discardCloser) Write(p []byte) (int, error) { return len(p), nil }
+
+func (discardCloser) Close() error { return nil }
+
+var _ = Describe("Connection writers", func() {
+	var (
+		conn       *extfakes.FakeConn
+		connection ws.Connection
+		buf        *closeBuffer
+	)
+
+	BeforeEach(func() {
+		conn = &extfakes.FakeConn{}
+		buf = &closeBuffer{}
+		conn.NextWriterReturns(buf, nil)
+
+		var err error
+		connection, err = ws.NewConnection(conn, ws.ConnectionOptions{})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Describe("NextWriter", func() {
+		It("writes a single message", func() {
+			w, err := connection.NextWriter(websocket.BinaryMessage)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(conn.NextWriterArgsForCall(0)).To(Equal(websocket.BinaryMessage))
+
+			_, err = w.Write([]byte("oi"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(w.Close()).ToNot(HaveOccurred())
+
+			Expect(buf.String()).To(Equal("oi"))
+			Expect(buf.closed).To(BeTrue())
+		})
+
+		It("blocks other writes until it is closed", func() {
+			w, err := connection.NextWriter(websocket.BinaryMessage)
+			Expect(err).ToNot(HaveOccurred())
+
+			written := make(chan struct{})
+
+			go func() {
+				defer GinkgoRecover()
+				_, err := connection.Write([]byte("next"))
+				Expect(err).ToNot(HaveOccurred())
+				close(written)
+			}()
+
+			Consistently(written, 50*time.Millisecond).ShouldNot(BeClosed())
+			Expect(w.Close()).ToNot(HaveOccurred())
+			Eventually(written).Should(BeClosed())
+		})
+
+		It("returns an error if it is closed twice", func() {
+			w, err := connection.NextWriter(websocket.BinaryMessage)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(w.Close()).ToNot(HaveOccurred())
+			Expect(w.Close()).To(MatchError(ws.ErrWriterClosed))
+		})
+
+		It("does not close a newer writer when an old one is closed again", func() {
+			stale, err := connection.NextWriter(websocket.BinaryMessage)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stale.Close()).ToNot(HaveOccurred())
+
+			next := &closeBuffer{}
+			conn.NextWriterReturns(next, nil)
+
+			w, err := connection.NextWriter(websocket.BinaryMessage)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stale.Close()).To(MatchError(ws.ErrWriterClosed))
+			Expect(next.closed).To(BeFalse())
+
+			written := make(chan struct{})
+
+			go func() {
+				defer GinkgoRecover()
+				_, err := connection.Write([]byte("next"))
+				Expect(err).ToNot(HaveOccurred())
+				close(written)
+			}()
+
+			Consistently(written, 50*time.Millisecond).ShouldNot(BeClosed())
+			Expect(w.Close()).ToNot(HaveOccurred())
+			Expect(next.closed).To(BeTrue())
+			Eventually(written).Should(BeClosed())
+		})
+
+		It("does not allocate", func() {
+			connection, err := ws.NewConnection(&staticWriterConn{
+				FakeConn: &extfakes.FakeConn{},
+				w:        discardCloser{},
+			}, ws.ConnectionOptions{})
+			Expect(err).ToNot(HaveOccurred())
+
+			msg := []byte("oi")
+
+			Expect(testing.AllocsPerRun(100, func() {
+				w, _ := connection.NextWriter(websocket.BinaryMessage)
+				_, _ = w.Write(msg)
+				_ = w.Close()
+			})).To(BeZero())
+		})
+
+		It("releases the lock if the writer cannot be created", func() {
+			conn.NextWriterReturns(nil, errors.New("BOOM"))
+			_, err := connection.NextWriter(websocket.BinaryMessage)
+			Expect(err).To(MatchError("BOOM"))
+
+			_, err = connection.Write([]byte("oi"))
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+
+	Describe("NextUncompressedWriter", func() {
+		It("disables compression until the writer is closed", func() {
+			w, err := connection.NextUncompressedWriter(websocket.BinaryMessage)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(conn.EnableWriteCompressionCallCount()).To(Equal(1))
+			Expect(conn.EnableWriteCompressionArgsForCall(0)).To(BeFalse())
+
+			Expect(w.Close()).ToNot(HaveOccurred())
+			Expect(conn.EnableWriteCompressionCallCount()).To(Equal(2))
+			Expect(conn.EnableWriteCompressionArgsForCall(1)).To(BeTrue())
+		})
+
+		It("keeps compression disabled if it was disabled before", func() {
+			connection.EnableWriteCompression(false)
+
+			w, err := connection.NextUncompressedWriter(websocket.BinaryMessage)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(w.Close()).ToNot(HaveOccurred())
+
+			Expect(conn.EnableWriteCompressionCallCount()).To(Equal(3))
+			Expect(conn.EnableWriteCompressionArgsForCall(2)).To(BeFalse())
+		})
+	})
+})
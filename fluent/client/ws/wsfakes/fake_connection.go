@@ -103,6 +103,19 @@ type FakeConnection struct {
 		result2 io.Reader
 		result3 error
 	}
+	NextUncompressedWriterStub        func(int) (io.WriteCloser, error)
+	nextUncompressedWriterMutex       sync.RWMutex
+	nextUncompressedWriterArgsForCall []struct {
+		arg1 int
+	}
+	nextUncompressedWriterReturns struct {
+		result1 io.WriteCloser
+		result2 error
+	}
+	nextUncompressedWriterReturnsOnCall map[int]struct {
+		result1 io.WriteCloser
+		result2 error
+	}
 	NextWriterStub        func(int) (io.WriteCloser, error)
 	nextWriterMutex       sync.RWMutex
 	nextWriterArgsForCall []struct {
@@ -772,6 +785,70 @@ func (fake *FakeConnection) NextReaderReturnsOnCall(i int, result1 int, result2
 	}{result1, result2, result3}
 }
 
+func (fake *FakeConnection) NextUncompressedWriter(arg1 int) (io.WriteCloser, error) {
+	fake.nextUncompressedWriterMutex.Lock()
+	ret, specificReturn := fake.nextUncompressedWriterReturnsOnCall[len(fake.nextUncompressedWriterArgsForCall)]
+	fake.nextUncompressedWriterArgsForCall = append(fake.nextUncompressedWriterArgsForCall, struct {
+		arg1 int
+	}{arg1})
+	stub := fake.NextUncompressedWriterStub
+	fakeReturns := fake.nextUncompressedWriterReturns
+	fake.recordInvocation("NextUncompressedWriter", []interface{}{arg1})
+	fake.nextUncompressedWriterMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeConnection) NextUncompressedWriterCallCount() int {
+	fake.nextUncompressedWriterMutex.RLock()
+	defer fake.nextUncompressedWriterMutex.RUnlock()
+	return len(fake.nextUncompressedWriterArgsForCall)
+}
+
+func (fake *FakeConnection) NextUncompressedWriterCalls(stub func(int) (io.WriteCloser, error)) {
+	fake.nextUncompressedWriterMutex.Lock()
+	defer fake.nextUncompressedWriterMutex.Unlock()
+	fake.NextUncompressedWriterStub = stub
+}
+
+func (fake *FakeConnection) NextUncompressedWriterArgsForCall(i int) int {
+	fake.nextUncompressedWriterMutex.RLock()
+	defer fake.nextUncompressedWriterMutex.RUnlock()
+	argsForCall := fake.nextUncompressedWriterArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeConnection) NextUncompressedWriterReturns(result1 io.WriteCloser, result2 error) {
+	fake.nextUncompressedWriterMutex.Lock()
+	defer fake.nextUncompressedWriterMutex.Unlock()
+	fake.NextUncompressedWriterStub = nil
+	fake.nextUncompressedWriterReturns = struct {
+		result1 io.WriteCloser
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeConnection) NextUncompressedWriterReturnsOnCall(i int, result1 io.WriteCloser, result2 error) {
+	fake.nextUncompressedWriterMutex.Lock()
+	defer fake.nextUncompressedWriterMutex.Unlock()
+	fake.NextUncompressedWriterStub = nil
+	if fake.nextUncompressedWriterReturnsOnCall == nil {
+		fake.nextUncompressedWriterReturnsOnCall = make(map[int]struct {
+			result1 io.WriteCloser
+			result2 error
+		})
+	}
+	fake.nextUncompressedWriterReturnsOnCall[i] = struct {
+		result1 io.WriteCloser
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeConnection) NextWriter(arg1 int) (io.WriteCloser, error) {
 	fake.nextWriterMutex.Lock()
 	ret, specificReturn := fake.nextWriterReturnsOnCall[len(fake.nextWriterArgsForCall)]
@@ -1842,6 +1919,8 @@ func (fake *FakeConnection) Invocations() map[string][][]interface{} {
 	defer fake.localAddrMutex.RUnlock()
 	fake.nextReaderMutex.RLock()
 	defer fake.nextReaderMutex.RUnlock()
+	fake.nextUncompressedWriterMutex.RLock()
+	defer fake.nextUncompressedWriterMutex.RUnlock()
 	fake.nextWriterMutex.RLock()
 	defer fake.nextWriterMutex.RUnlock()
 	fake.pingHandlerMutex.RLock()
@@ -0,0 +1,429 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package client
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	"github.com/IBM/fluent-forward-go/fluent/protocol"
+	"github.com/tinylib/msgp/msgp"
+)
+
+const (
+	DefaultBatchMaxBytes                    = 1024 * 1024
+	DefaultBatchFlushInterval time.Duration = 100 * time.Millisecond
+)
+
+var batchBufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// WSBatchOptions configures a WSBatcher.
+type WSBatchOptions struct {
+	// MaxBytes is the largest frame, in bytes, that the batcher writes. A
+	// message that would take a frame past it is sent in the next frame,
+	// and a single message larger than MaxBytes is sent in a frame of its
+	// own. The default is DefaultBatchMaxBytes.
+	MaxBytes int
+	// MaxMessages, if set, triggers a flush after that many messages have
+	// been batched.
+	MaxMessages int
+	// FlushInterval is the longest a message waits in the batch before it
+	// is flushed. The default is DefaultBatchFlushInterval. A negative value
+	// disables timed flushes.
+	FlushInterval time.Duration
+	// PackMessages converts consecutive Message and MessageExt values that
+	// share a tag and do not request an ack into a single
+	// PackedForwardMessage.
+	PackMessages bool
+}
+
+// WSBatcher coalesces messages into a single websocket frame, which is
+// written through the WSClient when the batch reaches its size limits or
+// its flush interval elapses. The receiving end must read every message in
+// a frame; server.WSHandler and server.WSRelay both do.
+//
+// Messages that request an ack are batched unmodified, so their chunks are
+// preserved. It is safe to use a WSBatcher from multiple goroutines.
+type WSBatcher struct {
+	client      *WSClient
+	opts        WSBatchOptions
+	frame       *bytes.Buffer
+	frameWriter *msgp.Writer
+	pack        *bytes.Buffer
+	packWriter  *msgp.Writer
+	msg         *bytes.Buffer
+	msgWriter   *msgp.Writer
+	packTag     string
+	packCount   int
+	count       int
+	timer       *time.Timer
+	err         error
+	closed      bool
+	lock        sync.Mutex
+}
+
+// NewWSBatcher returns a WSBatcher that writes its batches through the
+// client. The client must be connected before the first flush.
+func NewWSBatcher(client *WSClient, opts WSBatchOptions) *WSBatcher {
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = DefaultBatchMaxBytes
+	}
+
+	if opts.FlushInterval == 0 {
+		opts.FlushInterval = DefaultBatchFlushInterval
+	}
+
+	frame := batchBufferPool.Get().(*bytes.Buffer)
+	pack := batchBufferPool.Get().(*bytes.Buffer)
+	msg := batchBufferPool.Get().(*bytes.Buffer)
+
+	return &WSBatcher{
+		client:      client,
+		opts:        opts,
+		frame:       frame,
+		frameWriter: msgp.NewWriter(frame),
+		pack:        pack,
+		packWriter:  msgp.NewWriter(pack),
+		msg:         msg,
+		msgWriter:   msgp.NewWriter(msg),
+	}
+}
+
+// Send adds a message to the batch. It returns any error from a previous
+// timed flush, or from a flush that the message itself triggered.
+func (b *WSBatcher) Send(e protocol.ChunkEncoder) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if err := b.checkState(); err != nil {
+		return err
+	}
+
+	if b.opts.PackMessages {
+		packed, err := b.packMessage(e)
+		if err != nil {
+			return err
+		}
+
+		if packed {
+			return b.added()
+		}
+	}
+
+	b.msg.Reset()
+
+	err := e.EncodeMsg(b.msgWriter)
+	if ferr := b.msgWriter.Flush(); err == nil {
+		err = ferr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return b.add(b.msg.Bytes())
+}
+
+// SendRaw adds an encoded message to the batch.
+func (b *WSBatcher) SendRaw(m []byte) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if err := b.checkState(); err != nil {
+		return err
+	}
+
+	return b.add(m)
+}
+
+// Flush writes the current batch, if any, as a single frame. If the write
+// fails, the batch is discarded and the error is returned.
+func (b *WSBatcher) Flush() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if err := b.checkState(); err != nil {
+		return err
+	}
+
+	return b.flush()
+}
+
+// Close flushes the current batch and releases the batcher's buffers. It
+// does not disconnect the client.
+func (b *WSBatcher) Close() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.closed {
+		return nil
+	}
+
+	err := b.err
+	if ferr := b.flush(); err == nil {
+		err = ferr
+	}
+
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+
+	b.closed = true
+
+	b.frame.Reset()
+	b.pack.Reset()
+	b.msg.Reset()
+	batchBufferPool.Put(b.frame)
+	batchBufferPool.Put(b.pack)
+	batchBufferPool.Put(b.msg)
+
+	return err
+}
+
+// checkState returns an error if the batcher can no longer be used, or if
+// a timed flush failed since the last call.
+func (b *WSBatcher) checkState() error {
+	if b.closed {
+		return ErrBatcherClosed
+	}
+
+	err := b.err
+	b.err = nil
+
+	return err
+}
+
+// add appends an encoded message to the frame, after the pending
+// PackedForwardMessage. The batch is flushed first if the message would
+// take it past MaxBytes.
+func (b *WSBatcher) add(m []byte) error {
+	if err := b.flushPack(); err != nil {
+		return err
+	}
+
+	if b.count > 0 && b.frame.Len()+len(m) > b.opts.MaxBytes {
+		if err := b.flush(); err != nil {
+			return err
+		}
+	}
+
+	b.frame.Write(m)
+
+	return b.added()
+}
+
+// added updates the batch state after a message was added to it, and
+// flushes the batch if it is full.
+func (b *WSBatcher) added() error {
+	b.count++
+
+	if b.count == 1 && b.opts.FlushInterval > 0 {
+		if b.timer == nil {
+			b.timer = time.AfterFunc(b.opts.FlushInterval, b.timedFlush)
+		} else {
+			b.timer.Reset(b.opts.FlushInterval)
+		}
+	}
+
+	if b.batchLen() >= b.opts.MaxBytes ||
+		(b.opts.MaxMessages > 0 && b.count >= b.opts.MaxMessages) {
+		return b.flush()
+	}
+
+	return nil
+}
+
+func (b *WSBatcher) timedFlush() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.closed || b.count == 0 {
+		return
+	}
+
+	if err := b.flush(); err != nil {
+		b.err = err
+	}
+}
+
+func (b *WSBatcher) flush() error {
+	if err := b.flushPack(); err != nil {
+		return err
+	}
+
+	if b.count == 0 {
+		return nil
+	}
+
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+
+	err := b.client.SendRaw(b.frame.Bytes())
+
+	b.frame.Reset()
+	b.count = 0
+
+	return err
+}
+
+// packMessage appends e to the pending PackedForwardMessage if it is a
+// Message or MessageExt that does not request an ack. It reports whether e
+// was packed; if it returns an error, e was not, and the caller still owns
+// it.
+func (b *WSBatcher) packMessage(e protocol.ChunkEncoder) (bool, error) {
+	var tag string
+
+	switch m := e.(type) {
+	case *protocol.Message:
+		if !packable(m.Options) {
+			return false, nil
+		}
+
+		tag = m.Tag
+	case *protocol.MessageExt:
+		if !packable(m.Options) {
+			return false, nil
+		}
+
+		tag = m.Tag
+	default:
+		return false, nil
+	}
+
+	if b.packCount > 0 && tag != b.packTag {
+		if err := b.flushPack(); err != nil {
+			return false, err
+		}
+	}
+
+	b.msg.Reset()
+	w := b.msgWriter
+
+	err := w.WriteArrayHeader(2)
+
+	if err == nil {
+		switch m := e.(type) {
+		case *protocol.Message:
+			// packed streams carry EventTime timestamps, as EntryList does
+			ts := protocol.EventTime{Time: time.Unix(m.Timestamp, 0)}
+			if err = w.WriteExtension(&ts); err == nil {
+				err = w.WriteIntf(m.Record)
+			}
+		case *protocol.MessageExt:
+			if err = w.WriteExtension(&m.Timestamp); err == nil {
+				err = w.WriteIntf(m.Record)
+			}
+		}
+	}
+
+	if ferr := w.Flush(); err == nil {
+		err = ferr
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	if b.count > 0 && b.frame.Len()+packLen(tag, b.pack.Len()+b.msg.Len()) > b.opts.MaxBytes {
+		if err := b.flush(); err != nil {
+			return false, err
+		}
+	}
+
+	b.pack.Write(b.msg.Bytes())
+	b.packTag = tag
+	b.packCount++
+
+	return true, nil
+}
+
+// flushPack writes the pending PackedForwardMessage, if any, to the frame.
+func (b *WSBatcher) flushPack() error {
+	if b.packCount == 0 {
+		return nil
+	}
+
+	w := b.frameWriter
+
+	err := w.WriteArrayHeader(3)
+	if err == nil {
+		err = w.WriteString(b.packTag)
+	}
+
+	if err == nil {
+		err = w.WriteBytes(b.pack.Bytes())
+	}
+
+	// the options carry the entry count, as SendPacked sets it
+	if err == nil {
+		err = w.WriteMapHeader(1)
+	}
+
+	if err == nil {
+		err = w.WriteString("size")
+	}
+
+	if err == nil {
+		err = w.WriteInt(b.packCount)
+	}
+
+	if ferr := w.Flush(); err == nil {
+		err = ferr
+	}
+
+	b.pack.Reset()
+	b.packCount = 0
+
+	return err
+}
+
+// batchLen returns the size of the frame once the pending
+// PackedForwardMessage has been written to it.
+func (b *WSBatcher) batchLen() int {
+	if b.packCount == 0 {
+		return b.frame.Len()
+	}
+
+	return b.frame.Len() + packLen(b.packTag, b.pack.Len())
+}
+
+// packLen returns the most a PackedForwardMessage with the tag and n bytes
+// of entries can take once encoded by flushPack.
+func packLen(tag string, n int) int {
+	return msgp.ArrayHeaderSize +
+		msgp.StringPrefixSize + len(tag) +
+		msgp.BytesPrefixSize + n +
+		msgp.MapHeaderSize + msgp.StringPrefixSize + len("size") + msgp.IntSize
+}
+
+func packable(opts *protocol.MessageOptions) bool {
+	return opts == nil || (opts.Chunk == "" && opts.Compressed == "")
+}
@@ -0,0 +1,343 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package client_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/IBM/fluent-forward-go/fluent/client"
+	"github.com/IBM/fluent-forward-go/fluent/client/clientfakes"
+	"github.com/IBM/fluent-forward-go/fluent/client/ws/ext/extfakes"
+	"github.com/IBM/fluent-forward-go/fluent/client/ws/wsfakes"
+	"github.com/IBM/fluent-forward-go/fluent/protocol"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/tinylib/msgp/msgp"
+)
+
+var _ = Describe("WSBatcher", func() {
+	var (
+		factory *clientfakes.FakeWSConnectionFactory
+		client  *WSClient
+		conn    *wsfakes.FakeConnection
+		opts    WSBatchOptions
+		batcher *WSBatcher
+		msg     *protocol.MessageExt
+	)
+
+	// frameMessages splits a written frame into its messages
+	frameMessages := func(frame []byte) [][]byte {
+		var msgs [][]byte
+
+		for len(frame) > 0 {
+			rest, err := msgp.Skip(frame)
+			Expect(err).ToNot(HaveOccurred())
+
+			msgs = append(msgs, frame[:len(frame)-len(rest)])
+			frame = rest
+		}
+
+		return msgs
+	}
+
+	BeforeEach(func() {
+		factory = &clientfakes.FakeWSConnectionFactory{}
+		conn = &wsfakes.FakeConnection{}
+		factory.NewReturns(&extfakes.FakeConn{}, nil)
+		factory.NewSessionReturns(&WSSession{Connection: conn})
+
+		client = NewWS(WSConnectionOptions{Factory: factory})
+		Expect(client.Connect()).ToNot(HaveOccurred())
+
+		opts = WSBatchOptions{FlushInterval: -1}
+
+		msg = &protocol.MessageExt{
+			Tag:       "foo",
+			Timestamp: protocol.EventTime{Time: time.Unix(1000, 0)},
+			Record:    map[string]interface{}{"a": "b"},
+		}
+	})
+
+	JustBeforeEach(func() {
+		batcher = NewWSBatcher(client, opts)
+	})
+
+	AfterEach(func() {
+		Expect(batcher.Close()).ToNot(HaveOccurred())
+	})
+
+	It("writes nothing until the batch is flushed", func() {
+		Expect(batcher.Send(msg)).ToNot(HaveOccurred())
+		Expect(batcher.Send(msg)).ToNot(HaveOccurred())
+		Expect(conn.WriteCallCount()).To(Equal(0))
+
+		Expect(batcher.Flush()).ToNot(HaveOccurred())
+		Expect(conn.WriteCallCount()).To(Equal(1))
+
+		expected, err := msg.MarshalMsg(nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		msgs := frameMessages(conn.WriteArgsForCall(0))
+		Expect(msgs).To(HaveLen(2))
+		Expect(msgs[0]).To(Equal(expected))
+		Expect(msgs[1]).To(Equal(expected))
+	})
+
+	It("does not write empty batches", func() {
+		Expect(batcher.Flush()).ToNot(HaveOccurred())
+		Expect(conn.WriteCallCount()).To(Equal(0))
+	})
+
+	It("batches raw messages", func() {
+		raw, err := msg.MarshalMsg(nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(batcher.SendRaw(raw)).ToNot(HaveOccurred())
+		Expect(batcher.Send(msg)).ToNot(HaveOccurred())
+		Expect(batcher.Flush()).ToNot(HaveOccurred())
+
+		Expect(frameMessages(conn.WriteArgsForCall(0))).To(Equal([][]byte{raw, raw}))
+	})
+
+	It("flushes on Close", func() {
+		Expect(batcher.Send(msg)).ToNot(HaveOccurred())
+		Expect(batcher.Close()).ToNot(HaveOccurred())
+		Expect(conn.WriteCallCount()).To(Equal(1))
+		Expect(batcher.Send(msg)).To(HaveOccurred())
+	})
+
+	When("MaxMessages is set", func() {
+		BeforeEach(func() {
+			opts.MaxMessages = 2
+		})
+
+		It("flushes when the batch is full", func() {
+			Expect(batcher.Send(msg)).ToNot(HaveOccurred())
+			Expect(conn.WriteCallCount()).To(Equal(0))
+			Expect(batcher.Send(msg)).ToNot(HaveOccurred())
+			Expect(conn.WriteCallCount()).To(Equal(1))
+			Expect(frameMessages(conn.WriteArgsForCall(0))).To(HaveLen(2))
+		})
+	})
+
+	When("MaxBytes is set", func() {
+		BeforeEach(func() {
+			opts.MaxBytes = 1
+		})
+
+		It("flushes when the batch is too large", func() {
+			Expect(batcher.Send(msg)).ToNot(HaveOccurred())
+			Expect(conn.WriteCallCount()).To(Equal(1))
+			Expect(frameMessages(conn.WriteArgsForCall(0))).To(HaveLen(1))
+		})
+	})
+
+	When("MaxBytes is larger than a message", func() {
+		var size int
+
+		BeforeEach(func() {
+			bits, err := msg.MarshalMsg(nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			size = len(bits)
+			opts.MaxBytes = size*3 + size/2
+		})
+
+		It("never writes a frame larger than MaxBytes", func() {
+			for i := 0; i < 10; i++ {
+				Expect(batcher.Send(msg)).ToNot(HaveOccurred())
+			}
+
+			Expect(batcher.Flush()).ToNot(HaveOccurred())
+
+			var n int
+			for i := 0; i < conn.WriteCallCount(); i++ {
+				frame := conn.WriteArgsForCall(i)
+				Expect(len(frame)).To(BeNumerically("<=", opts.MaxBytes))
+				n += len(frameMessages(frame))
+			}
+
+			Expect(n).To(Equal(10))
+		})
+
+		It("sends a larger message in a frame of its own", func() {
+			large := &protocol.MessageExt{
+				Tag:       "foo",
+				Timestamp: msg.Timestamp,
+				Record:    map[string]interface{}{"a": strings.Repeat("b", opts.MaxBytes)},
+			}
+
+			Expect(batcher.Send(msg)).ToNot(HaveOccurred())
+			Expect(batcher.Send(large)).ToNot(HaveOccurred())
+			Expect(batcher.Send(msg)).ToNot(HaveOccurred())
+			Expect(batcher.Flush()).ToNot(HaveOccurred())
+
+			Expect(conn.WriteCallCount()).To(Equal(3))
+			Expect(frameMessages(conn.WriteArgsForCall(0))).To(HaveLen(1))
+			Expect(frameMessages(conn.WriteArgsForCall(1))).To(HaveLen(1))
+			Expect(len(conn.WriteArgsForCall(1))).To(BeNumerically(">", opts.MaxBytes))
+			Expect(frameMessages(conn.WriteArgsForCall(2))).To(HaveLen(1))
+		})
+
+		When("PackMessages is set", func() {
+			BeforeEach(func() {
+				opts.PackMessages = true
+			})
+
+			It("never writes a frame larger than MaxBytes", func() {
+				for i := 0; i < 10; i++ {
+					Expect(batcher.Send(msg)).ToNot(HaveOccurred())
+				}
+
+				Expect(batcher.Flush()).ToNot(HaveOccurred())
+
+				var n int
+				for i := 0; i < conn.WriteCallCount(); i++ {
+					frame := conn.WriteArgsForCall(i)
+					Expect(len(frame)).To(BeNumerically("<=", opts.MaxBytes))
+
+					for _, m := range frameMessages(frame) {
+						var pfm protocol.PackedForwardMessage
+						_, err := pfm.UnmarshalMsg(m)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(pfm.Options.Size).ToNot(BeNil())
+						n += *pfm.Options.Size
+					}
+				}
+
+				Expect(n).To(Equal(10))
+			})
+
+			It("does not pack a message if the flush before it fails", func() {
+				large := &protocol.MessageExt{
+					Tag:       "foo",
+					Timestamp: msg.Timestamp,
+					Record:    map[string]interface{}{"a": strings.Repeat("b", opts.MaxBytes)},
+				}
+
+				Expect(batcher.Send(msg)).ToNot(HaveOccurred())
+
+				conn.WriteReturns(0, errors.New("nope"))
+				Expect(batcher.Send(large)).To(MatchError("nope"))
+
+				// the failed batch was discarded and the message left out of
+				// the next one
+				conn.WriteReturns(0, nil)
+				Expect(batcher.Flush()).ToNot(HaveOccurred())
+				Expect(conn.WriteCallCount()).To(Equal(1))
+			})
+		})
+	})
+
+	When("FlushInterval is set", func() {
+		BeforeEach(func() {
+			opts.FlushInterval = 10 * time.Millisecond
+		})
+
+		It("flushes when the interval elapses", func() {
+			Expect(batcher.Send(msg)).ToNot(HaveOccurred())
+			Eventually(conn.WriteCallCount).Should(Equal(1))
+		})
+
+		When("the timed flush fails", func() {
+			BeforeEach(func() {
+				conn.WriteReturns(0, errors.New("nope"))
+			})
+
+			It("returns the error from the next call", func() {
+				Expect(batcher.Send(msg)).ToNot(HaveOccurred())
+				Eventually(conn.WriteCallCount).Should(Equal(1))
+				Expect(batcher.Flush()).To(MatchError("nope"))
+				Expect(batcher.Flush()).ToNot(HaveOccurred())
+			})
+		})
+	})
+
+	When("PackMessages is set", func() {
+		BeforeEach(func() {
+			opts.PackMessages = true
+		})
+
+		It("packs messages with the same tag", func() {
+			other := &protocol.Message{Tag: "foo", Timestamp: 2000, Record: map[string]interface{}{"c": "d"}}
+			bar := &protocol.Message{Tag: "bar", Timestamp: 3000, Record: map[string]interface{}{"e": "f"}}
+
+			Expect(batcher.Send(msg)).ToNot(HaveOccurred())
+			Expect(batcher.Send(other)).ToNot(HaveOccurred())
+			Expect(batcher.Send(bar)).ToNot(HaveOccurred())
+			Expect(batcher.Flush()).ToNot(HaveOccurred())
+
+			msgs := frameMessages(conn.WriteArgsForCall(0))
+			Expect(msgs).To(HaveLen(2))
+
+			var pfm protocol.PackedForwardMessage
+			_, err := pfm.UnmarshalMsg(msgs[0])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pfm.Tag).To(Equal("foo"))
+
+			var entries protocol.EntryList
+			_, err = entries.UnmarshalPacked(pfm.EventStream)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(entries).To(HaveLen(2))
+			Expect(entries[0].Timestamp.Time.Unix()).To(BeEquivalentTo(1000))
+			Expect(entries[0].Record).To(HaveKeyWithValue("a", "b"))
+			Expect(entries[1].Timestamp.Time.Unix()).To(BeEquivalentTo(2000))
+			Expect(entries[1].Record).To(HaveKeyWithValue("c", "d"))
+
+			_, err = pfm.UnmarshalMsg(msgs[1])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pfm.Tag).To(Equal("bar"))
+		})
+
+		It("does not pack messages that request an ack", func() {
+			msg.Options = &protocol.MessageOptions{Chunk: "abc"}
+
+			Expect(batcher.Send(msg)).ToNot(HaveOccurred())
+			Expect(batcher.Send(msg)).ToNot(HaveOccurred())
+			Expect(batcher.Flush()).ToNot(HaveOccurred())
+
+			expected, err := msg.MarshalMsg(nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frameMessages(conn.WriteArgsForCall(0))).To(Equal([][]byte{expected, expected}))
+		})
+	})
+
+	It("does not allocate per message", func() {
+		send := func() {
+			_ = batcher.Send(msg)
+		}
+
+		// grow the buffers to their working size
+		for i := 0; i < 200; i++ {
+			send()
+		}
+		Expect(batcher.Flush()).ToNot(HaveOccurred())
+
+		Expect(testing.AllocsPerRun(100, send)).To(BeZero())
+	})
+})
@@ -25,15 +25,16 @@ SOFTWARE.
 package client
 
 import (
-	"bytes"
 	"crypto/tls"
-	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/IBM/fluent-forward-go/fluent/client/ws"
 	"github.com/IBM/fluent-forward-go/fluent/client/ws/ext"
+	"github.com/IBM/fluent-forward-go/fluent/metrics"
 	"github.com/IBM/fluent-forward-go/fluent/protocol"
 	"github.com/gorilla/websocket"
 
@@ -79,6 +80,11 @@ func (ai *IAMAuthInfo) SetIAMToken(token string) {
 	ai.token = token
 }
 
+// Token implements TokenProvider by returning the current token value.
+func (ai *IAMAuthInfo) Token() (string, error) {
+	return ai.IAMToken(), nil
+}
+
 func NewIAMAuthInfo(token string) *IAMAuthInfo {
 	return &IAMAuthInfo{token: token}
 }
@@ -96,9 +102,36 @@ type DefaultWSConnectionFactory struct {
 	AuthInfo  *IAMAuthInfo
 	TLSConfig *tls.Config
 	Header    http.Header
+	// TokenProvider is consulted on every dial for the token sent to the
+	// server. It takes precedence over AuthInfo.
+	TokenProvider TokenProvider
+	// AuthHeader is the name of the header that carries the token. The
+	// default is AuthorizationHeader.
+	AuthHeader string
+	// AuthScheme, if set, is prepended to the token; e.g., "Bearer" sends
+	// "Bearer <token>".
+	AuthScheme string
+}
+
+func (wcf *DefaultWSConnectionFactory) tokenProvider() TokenProvider {
+	if wcf.TokenProvider != nil {
+		return wcf.TokenProvider
+	}
+
+	if wcf.AuthInfo != nil {
+		return wcf.AuthInfo
+	}
+
+	return nil
 }
 
 func (wcf *DefaultWSConnectionFactory) New() (ext.Conn, error) {
+	return wcf.dial(false)
+}
+
+// dial opens the connection, requesting permessage-deflate compression
+// (RFC 7692) during the opening handshake if compress is set.
+func (wcf *DefaultWSConnectionFactory) dial(compress bool) (ext.Conn, error) {
 	var (
 		dialer websocket.Dialer
 		header = http.Header{}
@@ -108,17 +141,35 @@ func (wcf *DefaultWSConnectionFactory) New() (ext.Conn, error) {
 	// header names and values. Caller should make sure the
 	// headers provided are not conflict with protocols
 	if wcf.Header != nil {
-		header = wcf.Header
+		header = wcf.Header.Clone()
 	}
 
-	if wcf.AuthInfo != nil && len(wcf.AuthInfo.IAMToken()) > 0 {
-		header.Set(AuthorizationHeader, wcf.AuthInfo.IAMToken())
+	if tp := wcf.tokenProvider(); tp != nil {
+		token, err := tp.Token()
+		if err != nil {
+			return nil, fmt.Errorf("get auth token: %w", err)
+		}
+
+		if len(token) > 0 {
+			authHeader := wcf.AuthHeader
+			if authHeader == "" {
+				authHeader = AuthorizationHeader
+			}
+
+			if wcf.AuthScheme != "" {
+				token = wcf.AuthScheme + " " + token
+			}
+
+			header.Set(authHeader, token)
+		}
 	}
 
 	if wcf.TLSConfig != nil {
 		dialer.TLSClientConfig = wcf.TLSConfig
 	}
 
+	dialer.EnableCompression = compress
+
 	conn, resp, err := dialer.Dial(wcf.URL, header)
 	if resp != nil && resp.Body != nil {
 		defer resp.Body.Close()
@@ -146,16 +197,35 @@ func (wcf *DefaultWSConnectionFactory) NewSession(connection ws.Connection) *WSS
 type WSConnectionOptions struct {
 	ws.ConnectionOptions
 	Factory WSConnectionFactory
+	// EnableCompression turns on permessage-deflate compression (RFC 7692).
+	// DefaultWSConnectionFactory requests it during the opening handshake;
+	// connections from other factories have write compression enabled or
+	// disabled to match. Messages are only compressed if the server agrees
+	// to it.
+	EnableCompression bool
+	// SkipCompressedPayloads disables websocket compression for messages
+	// whose payload is already gzip-compressed, such as those created by
+	// NewCompressedPackedForwardMessage. It has no effect unless
+	// permessage-deflate was negotiated with the server.
+	SkipCompressedPayloads bool
+	// Metrics, if set, records counters and latencies for every send and
+	// reconnect. Unlike Client, WSClient records no compression ratio:
+	// permessage-deflate compresses frames inside the websocket library,
+	// where their compressed size cannot be observed.
+	Metrics metrics.Recorder
 }
 
 // WSClient manages the lifetime of a single websocket connection.
 type WSClient struct {
-	ConnectionFactory WSConnectionFactory
-	ConnectionOptions ws.ConnectionOptions
-	session           *WSSession
-	errLock           sync.RWMutex
-	sessionLock       sync.RWMutex
-	err               error
+	ConnectionFactory      WSConnectionFactory
+	ConnectionOptions      ws.ConnectionOptions
+	EnableCompression      bool
+	SkipCompressedPayloads bool
+	Metrics                metrics.Recorder
+	session                *WSSession
+	errLock                sync.RWMutex
+	sessionLock            sync.RWMutex
+	err                    error
 }
 
 func NewWS(opts WSConnectionOptions) *WSClient {
@@ -166,9 +236,21 @@ func NewWS(opts WSConnectionOptions) *WSClient {
 	}
 
 	return &WSClient{
-		ConnectionOptions: opts.ConnectionOptions,
-		ConnectionFactory: opts.Factory,
+		ConnectionOptions:      opts.ConnectionOptions,
+		ConnectionFactory:      opts.Factory,
+		EnableCompression:      opts.EnableCompression,
+		SkipCompressedPayloads: opts.SkipCompressedPayloads,
+		Metrics:                opts.Metrics,
+	}
+}
+
+// endpoint identifies the server in metrics.
+func (c *WSClient) endpoint() string {
+	if f, ok := c.ConnectionFactory.(*DefaultWSConnectionFactory); ok {
+		return f.URL
 	}
+
+	return ""
 }
 
 func (c *WSClient) setErr(err error) {
@@ -193,12 +275,21 @@ func (c *WSClient) Session() *WSSession {
 	return c.session
 }
 
+// dial opens a connection with the factory.
+func (c *WSClient) dial() (ext.Conn, error) {
+	if f, ok := c.ConnectionFactory.(*DefaultWSConnectionFactory); ok {
+		return f.dial(c.EnableCompression)
+	}
+
+	return c.ConnectionFactory.New()
+}
+
 // connect is for internal use and should be called within
 // the scope of an acquired 'c.sessionLock.Lock()'
 //
 // extracted for internal re-use.
 func (c *WSClient) connect() error {
-	conn, err := c.ConnectionFactory.New()
+	conn, err := c.dial()
 	if err != nil {
 		return err
 	}
@@ -208,6 +299,8 @@ func (c *WSClient) connect() error {
 		return err
 	}
 
+	connection.EnableWriteCompression(c.EnableCompression)
+
 	c.session = c.ConnectionFactory.NewSession(connection)
 
 	go func() {
@@ -235,15 +328,15 @@ func (c *WSClient) connect() error {
 }
 
 // Connect initializes the Session and Connection objects by opening
-// a websocket connection. If AuthInfo is not nil, the token it returns
-// will be passed via the "Authentication" header during the initial
-// HTTP call.
+// a websocket connection. If the factory is configured with a TokenProvider
+// or AuthInfo, the token it returns will be passed via the "Authorization"
+// header (or the factory's AuthHeader) during the initial HTTP call.
 func (c *WSClient) Connect() error {
 	c.sessionLock.Lock()
 	defer c.sessionLock.Unlock()
 
 	if c.session != nil {
-		return errors.New("a session is already active")
+		return ErrSessionActive
 	}
 
 	return c.connect()
@@ -278,57 +371,128 @@ func (c *WSClient) Reconnect() (err error) {
 
 	c.setErr(err)
 
+	if c.Metrics != nil {
+		c.Metrics.Reconnected(c.endpoint(), err)
+	}
+
 	return
 }
 
-// Send sends a single msgp.Encodable across the wire.
+// Send sends a single msgp.Encodable across the wire. The message is
+// encoded directly into the websocket frame, without an intermediate buffer.
 func (c *WSClient) Send(e protocol.ChunkEncoder) error {
-	var (
-		err            error
-		rawMessageData bytes.Buffer
-	)
+	if c.Metrics == nil {
+		_, err := c.send(e)
+		return err
+	}
+
+	start := time.Now()
+	n, err := c.send(e)
+
+	tag, events := describe(e)
+	c.Metrics.Sent(metrics.SendStats{
+		Endpoint: c.endpoint(),
+		Tag:      tag,
+		Events:   events,
+		Bytes:    n,
+		Latency:  time.Since(start),
+		Err:      err,
+	})
+
+	return err
+}
+
+func (c *WSClient) send(e protocol.ChunkEncoder) (int, error) {
 	// Check for an async connection error and return it here.
 	// In most cases, the client will not care about reading from
 	// the connection, so checking for the error here is sufficient.
-	if err = c.getErr(); err != nil {
-		return err // TODO: wrap this
+	if err := c.getErr(); err != nil {
+		return 0, &AsyncReadError{Err: err}
 	}
 
 	// prevent this from raise conditions by copy the session pointer
 	session := c.Session()
 	if session == nil || session.Connection.Closed() {
-		return errors.New("no active session")
+		return 0, ErrNoSession
+	}
+
+	var (
+		w   io.WriteCloser
+		err error
+	)
+
+	if c.SkipCompressedPayloads && isGzipped(e) {
+		w, err = session.Connection.NextUncompressedWriter(BinaryMessage)
+	} else {
+		w, err = session.Connection.NextWriter(BinaryMessage)
 	}
 
-	err = msgp.Encode(&rawMessageData, e)
 	if err != nil {
-		return err
+		return 0, wrapConnErr("write", err)
 	}
 
-	bytesData := rawMessageData.Bytes()
-	// Write function does not accurately return the number of bytes written
-	// so it would be ineffective to compare
-	_, err = c.session.Connection.Write(bytesData)
+	// msgp.Encode uses a pooled msgp.Writer. The writer must be closed even
+	// if encoding fails, because it holds the connection's write lock.
+	n := 0
 
-	return err
+	if c.Metrics != nil {
+		cw := &countingWriter{w: w}
+		err = msgp.Encode(cw, e)
+		n = cw.n
+	} else {
+		err = msgp.Encode(w, e)
+	}
+
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+
+	return n, wrapConnErr("write", err)
+}
+
+// isGzipped reports whether the message carries a gzip-compressed
+// event stream.
+func isGzipped(e protocol.ChunkEncoder) bool {
+	pfm, ok := e.(*protocol.PackedForwardMessage)
+
+	return ok && pfm.Options != nil && pfm.Options.Compressed == protocol.OptValGZIP
 }
 
 // SendRaw sends an array of bytes across the wire.
 func (c *WSClient) SendRaw(m []byte) error {
+	if c.Metrics == nil {
+		_, err := c.sendRaw(m)
+		return err
+	}
+
+	start := time.Now()
+	n, err := c.sendRaw(m)
+
+	c.Metrics.Sent(metrics.SendStats{
+		Endpoint: c.endpoint(),
+		Bytes:    n,
+		Latency:  time.Since(start),
+		Err:      err,
+	})
+
+	return err
+}
+
+func (c *WSClient) sendRaw(m []byte) (int, error) {
 	// Check for an async connection error and return it here.
 	// In most cases, the client will not care about reading from
 	// the connection, so checking for the error here is sufficient.
 	if err := c.getErr(); err != nil {
-		return err // TODO: wrap this
+		return 0, &AsyncReadError{Err: err}
 	}
 
 	// prevent this from raise conditions by copy the session pointer
 	session := c.Session()
 	if session == nil || session.Connection.Closed() {
-		return errors.New("no active session")
+		return 0, ErrNoSession
 	}
 
-	_, err := session.Connection.Write(m)
+	n, err := session.Connection.Write(m)
 
-	return err
+	return n, wrapConnErr("write", err)
 }
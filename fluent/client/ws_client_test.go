@@ -43,6 +43,7 @@ import (
 	"github.com/IBM/fluent-forward-go/fluent/client/ws/ext"
 	"github.com/IBM/fluent-forward-go/fluent/client/ws/ext/extfakes"
 	"github.com/IBM/fluent-forward-go/fluent/client/ws/wsfakes"
+	"github.com/IBM/fluent-forward-go/fluent/metrics/metricsfakes"
 	"github.com/IBM/fluent-forward-go/fluent/protocol"
 	"github.com/gorilla/websocket"
 	. "github.com/onsi/ginkgo/v2"
@@ -50,6 +51,17 @@ import (
 	"github.com/tinylib/msgp/msgp"
 )
 
+// frameWriter records a websocket message written through NextWriter.
+type frameWriter struct {
+	bytes.Buffer
+	closed int
+}
+
+func (fw *frameWriter) Close() error {
+	fw.closed++
+	return nil
+}
+
 var _ = Describe("IAMAuthInfo", func() {
 	It("gets and sets an IAM token", func() {
 		iai := NewIAMAuthInfo("a")
@@ -66,8 +78,16 @@ var _ = Describe("DefaultWSConnectionFactory", func() {
 		useTLS, testError bool
 		testHeaders       http.Header
 		customErr         *client.WSConnError
+		rcvdExtensions    string
+		authHeader        string
+		authValue         string
 	)
 
+	BeforeEach(func() {
+		authHeader = fclient.AuthorizationHeader
+		authValue = "oi"
+	})
+
 	happyHandler := func(ch chan struct{}) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			defer GinkgoRecover()
@@ -76,8 +96,10 @@ var _ = Describe("DefaultWSConnectionFactory", func() {
 			var upgrader websocket.Upgrader
 			wc, _ := upgrader.Upgrade(w, r, nil)
 
-			header := r.Header.Get(fclient.AuthorizationHeader)
-			Expect(header).To(Equal("oi"))
+			header := r.Header.Get(authHeader)
+			Expect(header).To(Equal(authValue))
+
+			rcvdExtensions = r.Header.Get("Sec-Websocket-Extensions")
 
 			for k := range testHeaders {
 				v := r.Header.Get(k)
@@ -163,6 +185,151 @@ var _ = Describe("DefaultWSConnectionFactory", func() {
 		Expect(cli.Disconnect()).ToNot(HaveOccurred())
 	})
 
+	It("does not modify the caller's headers", func() {
+		u := "ws" + strings.TrimPrefix(svr.URL, "http")
+
+		testHeaders = http.Header{
+			"X-b": []string{"value"},
+		}
+
+		cli := fclient.NewWS(client.WSConnectionOptions{
+			Factory: &client.DefaultWSConnectionFactory{
+				URL:      u,
+				AuthInfo: NewIAMAuthInfo("oi"),
+				Header:   testHeaders,
+			},
+		})
+
+		Expect(cli.Connect()).ToNot(HaveOccurred())
+		Eventually(ch).Should(Receive())
+		Expect(testHeaders).ToNot(HaveKey(fclient.AuthorizationHeader))
+		Expect(cli.Disconnect()).ToNot(HaveOccurred())
+	})
+
+	When("a TokenProvider is set", func() {
+		var tp *clientfakes.FakeTokenProvider
+
+		BeforeEach(func() {
+			tp = &clientfakes.FakeTokenProvider{}
+			tp.TokenReturns("tp-token", nil)
+			authValue = "tp-token"
+		})
+
+		It("sends the provider's token instead of AuthInfo", func() {
+			u := "ws" + strings.TrimPrefix(svr.URL, "http")
+
+			cli := fclient.NewWS(client.WSConnectionOptions{
+				Factory: &client.DefaultWSConnectionFactory{
+					URL:           u,
+					AuthInfo:      NewIAMAuthInfo("oi"),
+					TokenProvider: tp,
+				},
+			})
+
+			Expect(cli.Connect()).ToNot(HaveOccurred())
+			Eventually(ch).Should(Receive())
+			Expect(tp.TokenCallCount()).To(Equal(1))
+			Expect(cli.Disconnect()).ToNot(HaveOccurred())
+		})
+
+		It("consults the provider on every dial", func() {
+			u := "ws" + strings.TrimPrefix(svr.URL, "http")
+
+			cli := fclient.NewWS(client.WSConnectionOptions{
+				Factory: &client.DefaultWSConnectionFactory{
+					URL:           u,
+					TokenProvider: tp,
+				},
+			})
+
+			Expect(cli.Connect()).ToNot(HaveOccurred())
+			Eventually(ch).Should(Receive())
+			Expect(cli.Reconnect()).ToNot(HaveOccurred())
+			Eventually(ch).Should(Receive())
+			Expect(tp.TokenCallCount()).To(Equal(2))
+			Expect(cli.Disconnect()).ToNot(HaveOccurred())
+		})
+
+		When("a header name and scheme are configured", func() {
+			BeforeEach(func() {
+				authHeader = "X-Auth-Token"
+				authValue = "Bearer tp-token"
+			})
+
+			It("uses them", func() {
+				u := "ws" + strings.TrimPrefix(svr.URL, "http")
+
+				cli := fclient.NewWS(client.WSConnectionOptions{
+					Factory: &client.DefaultWSConnectionFactory{
+						URL:           u,
+						TokenProvider: tp,
+						AuthHeader:    "X-Auth-Token",
+						AuthScheme:    "Bearer",
+					},
+				})
+
+				Expect(cli.Connect()).ToNot(HaveOccurred())
+				Eventually(ch).Should(Receive())
+				Expect(cli.Disconnect()).ToNot(HaveOccurred())
+			})
+		})
+
+		When("the provider returns an error", func() {
+			BeforeEach(func() {
+				tp.TokenReturns("", errors.New("BOOM"))
+			})
+
+			It("does not dial", func() {
+				u := "ws" + strings.TrimPrefix(svr.URL, "http")
+
+				cli := fclient.NewWS(client.WSConnectionOptions{
+					Factory: &client.DefaultWSConnectionFactory{
+						URL:           u,
+						TokenProvider: tp,
+					},
+				})
+
+				err := cli.Connect()
+				Expect(err).To(HaveOccurred())
+				Expect(errors.Unwrap(err)).To(MatchError("BOOM"))
+				Consistently(ch).ShouldNot(Receive())
+			})
+		})
+	})
+
+	It("negotiates compression when enabled", func() {
+		u := "ws" + strings.TrimPrefix(svr.URL, "http")
+
+		cli := fclient.NewWS(client.WSConnectionOptions{
+			Factory: &client.DefaultWSConnectionFactory{
+				URL:      u,
+				AuthInfo: NewIAMAuthInfo("oi"),
+			},
+			EnableCompression: true,
+		})
+
+		Expect(cli.Connect()).ToNot(HaveOccurred())
+		Eventually(ch).Should(Receive())
+		Expect(rcvdExtensions).To(ContainSubstring("permessage-deflate"))
+		Expect(cli.Disconnect()).ToNot(HaveOccurred())
+	})
+
+	It("does not negotiate compression by default", func() {
+		u := "ws" + strings.TrimPrefix(svr.URL, "http")
+
+		cli := fclient.NewWS(client.WSConnectionOptions{
+			Factory: &client.DefaultWSConnectionFactory{
+				URL:      u,
+				AuthInfo: NewIAMAuthInfo("oi"),
+			},
+		})
+
+		Expect(cli.Connect()).ToNot(HaveOccurred())
+		Eventually(ch).Should(Receive())
+		Expect(rcvdExtensions).To(BeEmpty())
+		Expect(cli.Disconnect()).ToNot(HaveOccurred())
+	})
+
 	When("sends wrong url, expects error", func() {
 
 		BeforeEach(func() {
@@ -250,6 +417,43 @@ var _ = Describe("WSClient", func() {
 			Expect(client.Connect()).ToNot(HaveOccurred())
 		})
 
+		It("disables write compression by default", func() {
+			Expect(client.Connect()).ToNot(HaveOccurred())
+			fake := clientSide.(*extfakes.FakeConn)
+			Expect(fake.EnableWriteCompressionCallCount()).To(Equal(1))
+			Expect(fake.EnableWriteCompressionArgsForCall(0)).To(BeFalse())
+		})
+
+		It("keeps write compression disabled after an uncompressed write", func() {
+			fake := clientSide.(*extfakes.FakeConn)
+			fake.NextWriterReturns(&frameWriter{}, nil)
+
+			Expect(client.Connect()).ToNot(HaveOccurred())
+
+			w, err := factory.NewSessionArgsForCall(0).NextUncompressedWriter(BinaryMessage)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(w.Close()).ToNot(HaveOccurred())
+
+			n := fake.EnableWriteCompressionCallCount()
+			Expect(fake.EnableWriteCompressionArgsForCall(n - 1)).To(BeFalse())
+		})
+
+		When("compression is enabled", func() {
+			BeforeEach(func() {
+				client = fclient.NewWS(fclient.WSConnectionOptions{
+					Factory:           factory,
+					EnableCompression: true,
+				})
+			})
+
+			It("enables write compression on connections from any factory", func() {
+				Expect(client.Connect()).ToNot(HaveOccurred())
+				fake := clientSide.(*extfakes.FakeConn)
+				Expect(fake.EnableWriteCompressionCallCount()).To(Equal(1))
+				Expect(fake.EnableWriteCompressionArgsForCall(0)).To(BeTrue())
+			})
+		})
+
 		It("Gets the connection from the ConnectionFactory", func() {
 			err := client.Connect()
 			Expect(err).NotTo(HaveOccurred())
@@ -335,16 +539,21 @@ var _ = Describe("WSClient", func() {
 
 	Describe("Send", func() {
 		var (
-			msg protocol.MessageExt
+			msg    protocol.MessageExt
+			writer *frameWriter
 		)
 
 		BeforeEach(func() {
 			msg = protocol.MessageExt{
 				Tag:       "foo.bar",
-				Timestamp: protocol.EventTime{time.Now()}, //nolint
+				Timestamp: protocol.EventTime{Time: time.Now()},
 				Record:    map[string]interface{}{},
 				Options:   &protocol.MessageOptions{},
 			}
+
+			writer = &frameWriter{}
+			conn.NextWriterReturns(writer, nil)
+			conn.NextUncompressedWriterReturns(writer, nil)
 		})
 
 		JustBeforeEach(func() {
@@ -357,16 +566,52 @@ var _ = Describe("WSClient", func() {
 			bits, _ := msg.MarshalMsg(nil)
 			Expect(client.Send(&msg)).ToNot(HaveOccurred())
 
-			writtenbits := conn.WriteArgsForCall(0)
-			Expect(bytes.Equal(bits, writtenbits)).To(BeTrue())
+			Expect(conn.NextWriterCallCount()).To(Equal(1))
+			Expect(conn.NextWriterArgsForCall(0)).To(Equal(BinaryMessage))
+			Expect(bytes.Equal(bits, writer.Bytes())).To(BeTrue())
+			Expect(writer.closed).To(Equal(1))
 		})
 
-		It("Sends the message", func() {
-			msgBytes, _ := msg.MarshalMsg(nil)
-			Expect(client.Send(&msg)).ToNot(HaveOccurred())
+		When("Metrics is set", func() {
+			var recorder *metricsfakes.FakeRecorder
+
+			BeforeEach(func() {
+				recorder = &metricsfakes.FakeRecorder{}
+				client.Metrics = recorder
+			})
+
+			It("records the send", func() {
+				bits, _ := msg.MarshalMsg(nil)
+				Expect(client.Send(&msg)).ToNot(HaveOccurred())
+
+				Expect(recorder.SentCallCount()).To(Equal(1))
+				stats := recorder.SentArgsForCall(0)
+				Expect(stats.Tag).To(Equal("foo.bar"))
+				Expect(stats.Events).To(Equal(1))
+				Expect(stats.Bytes).To(Equal(len(bits)))
+				Expect(stats.Err).ToNot(HaveOccurred())
+			})
+		})
+
+		When("the message cannot be encoded", func() {
+			BeforeEach(func() {
+				msg.Record = make(chan int)
+			})
+
+			It("returns the error and closes the writer", func() {
+				Expect(client.Send(&msg)).To(HaveOccurred())
+				Expect(writer.closed).To(Equal(1))
+			})
+		})
+
+		When("the writer cannot be created", func() {
+			BeforeEach(func() {
+				conn.NextWriterReturns(nil, errors.New("nope"))
+			})
 
-			writtenBytes := conn.WriteArgsForCall(0)
-			Expect(bytes.Equal(msgBytes, writtenBytes)).To(BeTrue())
+			It("returns the error", func() {
+				Expect(client.Send(&msg)).To(MatchError("nope"))
+			})
 		})
 
 		When("The message is large", func() {
@@ -393,9 +638,35 @@ var _ = Describe("WSClient", func() {
 
 			It("Sends the correct number of bits", func() {
 				Expect(client.Send(&msg)).ToNot(HaveOccurred())
-				Expect(conn.WriteCallCount()).To(Equal(1))
-				writtenBytes := len(conn.WriteArgsForCall(0))
-				Expect(writtenBytes).To(Equal(expectedBytes))
+				Expect(conn.NextWriterCallCount()).To(Equal(1))
+				Expect(writer.Len()).To(Equal(expectedBytes))
+			})
+		})
+
+		When("SkipCompressedPayloads is set", func() {
+			BeforeEach(func() {
+				client.SkipCompressedPayloads = true
+			})
+
+			It("writes gzip-compressed messages without websocket compression", func() {
+				cmsg, err := protocol.NewCompressedPackedForwardMessage("foo.bar", protocol.EntryList{
+					{Timestamp: protocol.EventTimeNow(), Record: map[string]interface{}{"a": "b"}},
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				bits, _ := cmsg.MarshalMsg(nil)
+				Expect(client.Send(cmsg)).ToNot(HaveOccurred())
+
+				Expect(conn.NextWriterCallCount()).To(Equal(0))
+				Expect(conn.NextUncompressedWriterCallCount()).To(Equal(1))
+				Expect(bytes.Equal(bits, writer.Bytes())).To(BeTrue())
+			})
+
+			It("writes other messages normally", func() {
+				Expect(client.Send(&msg)).ToNot(HaveOccurred())
+
+				Expect(conn.NextWriterCallCount()).To(Equal(1))
+				Expect(conn.NextUncompressedWriterCallCount()).To(Equal(0))
 			})
 		})
 
@@ -406,7 +677,7 @@ var _ = Describe("WSClient", func() {
 			})
 
 			It("returns an error", func() {
-				Expect(client.Send(&msg)).To(MatchError("no active session"))
+				Expect(client.Send(&msg)).To(MatchError(ErrNoSession))
 			})
 		})
 
@@ -416,7 +687,12 @@ var _ = Describe("WSClient", func() {
 			})
 
 			It("returns the error", func() {
-				Expect(client.Send(&msg)).To(MatchError("BOOM"))
+				err := client.Send(&msg)
+
+				var readErr *AsyncReadError
+				Expect(errors.As(err, &readErr)).To(BeTrue())
+				Expect(readErr.Err).To(MatchError("BOOM"))
+				Expect(IsRetryable(err)).To(BeTrue())
 			})
 		})
 	})
@@ -450,7 +726,7 @@ var _ = Describe("WSClient", func() {
 			})
 
 			It("returns an error", func() {
-				Expect(client.SendRaw(bits)).To(MatchError("no active session"))
+				Expect(client.SendRaw(bits)).To(MatchError(ErrNoSession))
 			})
 		})
 
@@ -460,7 +736,11 @@ var _ = Describe("WSClient", func() {
 			})
 
 			It("returns the error", func() {
-				Expect(client.SendRaw(bits)).To(MatchError("BOOM"))
+				err := client.SendRaw(bits)
+
+				var readErr *AsyncReadError
+				Expect(errors.As(err, &readErr)).To(BeTrue())
+				Expect(readErr.Err).To(MatchError("BOOM"))
 			})
 		})
 	})
@@ -0,0 +1,558 @@
+/*
+This file is derived from fluent/fluent.go of fluent-logger-golang v1.8.0
+(https://github.com/fluent/fluent-logger-golang), which is licensed under the
+Apache License, Version 2.0. It was modified by contributors to the
+fluent-forward-go project to send through client.Client.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License in the LICENSE file of this directory or
+at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fluent mirrors the API of github.com/fluent/fluent-logger-golang
+// on top of client.Client, so that code written against that package can be
+// migrated by changing its import path.
+package fluent
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/IBM/fluent-forward-go/fluent/client"
+	"github.com/IBM/fluent-forward-go/fluent/protocol"
+	"github.com/tinylib/msgp/msgp"
+)
+
+const (
+	defaultHost                   = "127.0.0.1"
+	defaultNetwork                = "tcp"
+	defaultPort                   = 24224
+	defaultTimeout                = 3 * time.Second
+	defaultBufferLimit            = 8 * 1024
+	defaultRetryWait              = 500
+	defaultMaxRetryWait           = 60000
+	defaultMaxRetry               = 13
+	defaultReconnectWaitIncreRate = 1.5
+)
+
+var (
+	// ErrJSONUnsupported is returned by New when MarshalAsJSON is set. Only
+	// MessagePack encoding is supported.
+	ErrJSONUnsupported = errors.New("fluent#New: MarshalAsJSON is not supported")
+
+	errIsClosing = errors.New("fluent logger is closing")
+)
+
+// Config has the same fields, defaults, and JSON names as the Config of
+// fluent-logger-golang.
+type Config struct {
+	FluentPort          int           `json:"fluent_port"`
+	FluentHost          string        `json:"fluent_host"`
+	FluentNetwork       string        `json:"fluent_network"`
+	FluentSocketPath    string        `json:"fluent_socket_path"`
+	Timeout             time.Duration `json:"timeout"`
+	WriteTimeout        time.Duration `json:"write_timeout"`
+	BufferLimit         int           `json:"buffer_limit"`
+	RetryWait           int           `json:"retry_wait"`
+	MaxRetry            int           `json:"max_retry"`
+	MaxRetryWait        int           `json:"max_retry_wait"`
+	TagPrefix           string        `json:"tag_prefix"`
+	Async               bool          `json:"async"`
+	ForceStopAsyncSend  bool          `json:"force_stop_async_send"`
+	AsyncResultCallback func(data []byte, err error)
+	// Deprecated: Use Async instead
+	AsyncConnect bool `json:"async_connect"`
+	// MarshalAsJSON is not supported. New returns ErrJSONUnsupported if it
+	// is set.
+	MarshalAsJSON bool `json:"marshal_as_json"`
+	// SubSecondPrecision sends a MessageExt, whose timestamp is an
+	// EventTime, instead of a Message.
+	SubSecondPrecision bool `json:"sub_second_precision"`
+	// RequestAck sets the "chunk" option on every message and waits for the
+	// server to acknowledge it.
+	RequestAck bool `json:"request_ack"`
+	// TlsInsecureSkipVerify skips verifying the server certificate when
+	// FluentNetwork is "tls".
+	TlsInsecureSkipVerify bool `json:"tls_insecure_skip_verify"` //nolint
+}
+
+type ErrUnknownNetwork struct {
+	network string
+}
+
+func (e *ErrUnknownNetwork) Error() string {
+	return "unknown network " + e.network
+}
+
+func NewErrUnknownNetwork(network string) error {
+	return &ErrUnknownNetwork{network}
+}
+
+// msgToSend is a message encoded by EncodeData.
+type msgToSend struct {
+	data protocol.RawMessage
+}
+
+// Fluent sends messages through a client.Client. In async mode, messages are
+// encoded by the caller and sent in order from a background goroutine.
+type Fluent struct {
+	Config
+
+	client *client.Client
+	// connected reports whether client has a session. Both are guarded by
+	// muconn.
+	connected bool
+	muconn    sync.Mutex
+
+	// stopRunning is used in async mode to signal to run() it should abort.
+	stopRunning chan struct{}
+	// cancelDialings is used by Close() to stop any in-progress dialing.
+	cancelDialings context.CancelFunc
+	pending        chan *msgToSend
+	pendingMutex   sync.RWMutex
+	closed         bool
+	wg             sync.WaitGroup
+}
+
+// New creates a new Fluent with the defaults of fluent-logger-golang. Unless
+// Async is set, it connects before returning.
+func New(config Config) (f *Fluent, err error) {
+	if config.MarshalAsJSON {
+		return nil, ErrJSONUnsupported
+	}
+
+	if config.Timeout == 0 {
+		config.Timeout = defaultTimeout
+	}
+
+	if config.FluentNetwork == "" {
+		config.FluentNetwork = defaultNetwork
+	}
+
+	if config.FluentHost == "" {
+		config.FluentHost = defaultHost
+	}
+
+	if config.FluentPort == 0 {
+		config.FluentPort = defaultPort
+	}
+
+	if config.BufferLimit == 0 {
+		config.BufferLimit = defaultBufferLimit
+	}
+
+	if config.RetryWait == 0 {
+		config.RetryWait = defaultRetryWait
+	}
+
+	if config.MaxRetry == 0 {
+		config.MaxRetry = defaultMaxRetry
+	}
+
+	if config.MaxRetryWait == 0 {
+		config.MaxRetryWait = defaultMaxRetryWait
+	}
+
+	config.Async = config.Async || config.AsyncConnect
+
+	f = &Fluent{Config: config}
+	f.client = client.New(client.ConnectionOptions{
+		Factory:           &dialer{config: &f.Config},
+		RequireAck:        config.RequestAck,
+		ConnectionTimeout: config.Timeout,
+	})
+
+	if !config.Async {
+		f.muconn.Lock()
+		defer f.muconn.Unlock()
+
+		err = f.connect()
+
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	f.stopRunning = make(chan struct{})
+	f.cancelDialings = cancel
+	f.pending = make(chan *msgToSend, config.BufferLimit)
+
+	f.wg.Add(1)
+	go f.run(ctx)
+
+	return
+}
+
+// Post writes the output for a logging event.
+//
+// Examples:
+//
+//	// send map[string]
+//	mapStringData := map[string]string{
+//		"foo":  "bar",
+//	}
+//	f.Post("tag_name", mapStringData)
+//
+//	// send message with specified time
+//	mapStringData := map[string]string{
+//		"foo":  "bar",
+//	}
+//	tm := time.Now()
+//	f.PostWithTime("tag_name", tm, mapStringData)
+//
+//	// send struct
+//	structData := struct {
+//			Name string `msg:"name"`
+//	} {
+//			"john smith",
+//	}
+//	f.Post("tag_name", structData)
+func (f *Fluent) Post(tag string, message interface{}) error {
+	return f.PostWithTime(tag, time.Now(), message)
+}
+
+// PostWithTime is Post with the event time set to tm. The tag is prefixed
+// with TagPrefix. The message must be a msgp.Marshaler, a struct, whose
+// exported fields are named by their "msg" or "codec" tags, or a map with
+// string keys.
+func (f *Fluent) PostWithTime(tag string, tm time.Time, message interface{}) error {
+	if len(f.TagPrefix) > 0 {
+		tag = f.TagPrefix + "." + tag
+	}
+
+	if m, ok := message.(msgp.Marshaler); ok {
+		return f.EncodeAndPostData(tag, tm, m)
+	}
+
+	msg := reflect.ValueOf(message)
+	msgtype := msg.Type()
+
+	if msgtype.Kind() == reflect.Struct {
+		kv := make(map[string]interface{})
+
+		for i := 0; i < msgtype.NumField(); i++ {
+			field := msgtype.Field(i)
+			value := msg.FieldByIndex(field.Index)
+
+			if !value.CanInterface() {
+				continue
+			}
+
+			name := field.Name
+			if n := field.Tag.Get("msg"); n != "" {
+				name = n
+			} else if n := field.Tag.Get("codec"); n != "" {
+				name = n
+			}
+
+			kv[name] = value.Interface()
+		}
+
+		return f.EncodeAndPostData(tag, tm, kv)
+	}
+
+	if msgtype.Kind() != reflect.Map {
+		return errors.New("fluent#PostWithTime: message must be a map")
+	} else if msgtype.Key().Kind() != reflect.String {
+		return errors.New("fluent#PostWithTime: map keys must be strings")
+	}
+
+	kv := make(map[string]interface{})
+	for _, k := range msg.MapKeys() {
+		kv[k.String()] = msg.MapIndex(k).Interface()
+	}
+
+	return f.EncodeAndPostData(tag, tm, kv)
+}
+
+// EncodeAndPostData encodes the message as is, without adding TagPrefix, and
+// sends it.
+func (f *Fluent) EncodeAndPostData(tag string, tm time.Time, message interface{}) error {
+	msg, err := f.EncodeData(tag, tm, message)
+	if err != nil {
+		return fmt.Errorf("fluent#EncodeAndPostData: can't convert '%#v' to msgpack:%v", message, err)
+	}
+
+	return f.postRawData(msg)
+}
+
+// Deprecated: Use EncodeAndPostData instead
+func (f *Fluent) PostRawData(msg *msgToSend) {
+	_ = f.postRawData(msg)
+}
+
+func (f *Fluent) postRawData(msg *msgToSend) error {
+	if f.Async {
+		return f.appendBuffer(msg)
+	}
+
+	f.pendingMutex.RLock()
+	closed := f.closed
+	f.pendingMutex.RUnlock()
+
+	if closed {
+		return fmt.Errorf("fluent#postRawData: Logger already closed")
+	}
+
+	return f.writeWithRetry(context.Background(), msg)
+}
+
+// EncodeData encodes the message as a Message, or a MessageExt if
+// SubSecondPrecision is set. When RequestAck is set, the "chunk" option is
+// set to a unique ID.
+func (f *Fluent) EncodeData(tag string, tm time.Time, message interface{}) (msg *msgToSend, err error) {
+	var m interface {
+		protocol.ChunkEncoder
+		msgp.Marshaler
+	}
+
+	if f.SubSecondPrecision {
+		m = &protocol.MessageExt{
+			Tag:       tag,
+			Timestamp: protocol.EventTime{Time: tm},
+			Record:    message,
+		}
+	} else {
+		m = &protocol.Message{
+			Tag:       tag,
+			Timestamp: tm.Unix(),
+			Record:    message,
+		}
+	}
+
+	msg = &msgToSend{}
+
+	if f.RequestAck {
+		if _, err = m.Chunk(); err != nil {
+			return nil, err
+		}
+	}
+
+	msg.data, err = m.MarshalMsg(nil)
+
+	return
+}
+
+// Close closes the connection, waiting for pending messages to be sent unless
+// ForceStopAsyncSend is set. In async mode, the background goroutine exits
+// before Close returns.
+func (f *Fluent) Close() (err error) {
+	if f.Async {
+		f.pendingMutex.Lock()
+		if f.closed {
+			f.pendingMutex.Unlock()
+			return nil
+		}
+
+		f.closed = true
+		f.pendingMutex.Unlock()
+
+		if f.ForceStopAsyncSend {
+			close(f.stopRunning)
+			f.cancelDialings()
+		}
+
+		close(f.pending)
+
+		if !f.ForceStopAsyncSend {
+			f.wg.Wait()
+		}
+	}
+
+	f.pendingMutex.Lock()
+	f.closed = true
+	f.pendingMutex.Unlock()
+
+	f.muconn.Lock()
+	f.disconnect()
+	f.muconn.Unlock()
+
+	if f.ForceStopAsyncSend {
+		f.wg.Wait()
+	}
+
+	return
+}
+
+// appendBuffer queues a message for the background goroutine.
+func (f *Fluent) appendBuffer(msg *msgToSend) error {
+	f.pendingMutex.RLock()
+	defer f.pendingMutex.RUnlock()
+
+	if f.closed {
+		return fmt.Errorf("fluent#appendBuffer: Logger already closed")
+	}
+
+	select {
+	case f.pending <- msg:
+	default:
+		return fmt.Errorf("fluent#appendBuffer: Buffer full, limit %v", f.BufferLimit)
+	}
+
+	return nil
+}
+
+// run sends the queued messages in async mode until Close is called.
+func (f *Fluent) run(ctx context.Context) {
+	defer f.wg.Done()
+
+	for {
+		select {
+		case msg, ok := <-f.pending:
+			if !ok {
+				return
+			}
+
+			err := f.writeWithRetry(ctx, msg)
+
+			if f.AsyncResultCallback != nil {
+				f.AsyncResultCallback(msg.data, err)
+			}
+		case <-f.stopRunning:
+			return
+		}
+	}
+}
+
+// connect opens a session. Callers must hold muconn.
+func (f *Fluent) connect() error {
+	if err := f.client.Connect(); err != nil {
+		return err
+	}
+
+	f.connected = true
+
+	return nil
+}
+
+// disconnect closes the session, if any. Callers must hold muconn.
+func (f *Fluent) disconnect() {
+	if f.connected {
+		_ = f.client.Disconnect()
+		f.connected = false
+	}
+}
+
+// connectWithRetry connects, retrying up to MaxRetry times with an
+// exponential back-off. Callers must hold muconn.
+func (f *Fluent) connectWithRetry(ctx context.Context) error {
+	timeout := time.NewTimer(0)
+	defer func() {
+		timeout.Stop()
+	}()
+
+	for i := 0; i < f.MaxRetry; i++ {
+		select {
+		case <-timeout.C:
+			err := f.connect()
+			if err == nil {
+				return nil
+			}
+
+			var unknown *ErrUnknownNetwork
+			if errors.As(err, &unknown) {
+				return err
+			}
+
+			waitTime := int(float64(f.RetryWait) * math.Pow(defaultReconnectWaitIncreRate, float64(i-1)))
+			if waitTime > f.MaxRetryWait {
+				waitTime = f.MaxRetryWait
+			}
+
+			timeout = time.NewTimer(time.Duration(waitTime) * time.Millisecond)
+		case <-ctx.Done():
+			return errIsClosing
+		}
+	}
+
+	return fmt.Errorf("could not connect to fluentd after %d retries", f.MaxRetry)
+}
+
+func (f *Fluent) writeWithRetry(ctx context.Context, msg *msgToSend) error {
+	f.muconn.Lock()
+	defer f.muconn.Unlock()
+
+	for i := 0; i < f.MaxRetry; i++ {
+		if !f.connected {
+			// connectWithRetry has already retried, so the write is not
+			// retried again.
+			if err := f.connectWithRetry(ctx); err != nil {
+				return fmt.Errorf("fluent#write: %w", err)
+			}
+		}
+
+		if err := f.client.Send(msg.data); err == nil {
+			return nil
+		}
+
+		f.disconnect()
+	}
+
+	return fmt.Errorf("fluent#write: failed to write after %d attempts", f.MaxRetry)
+}
+
+// dialer is the client.ConnectionFactory for a Config.
+type dialer struct {
+	config *Config
+}
+
+func (d *dialer) New() (net.Conn, error) {
+	var (
+		factory client.ConnFactory
+		address = net.JoinHostPort(d.config.FluentHost, strconv.Itoa(d.config.FluentPort))
+	)
+
+	switch d.config.FluentNetwork {
+	case "tcp":
+		factory = client.ConnFactory{Network: "tcp", Address: address}
+	case "tls":
+		factory = client.ConnFactory{
+			Network:   "tcp",
+			Address:   address,
+			TLSConfig: &tls.Config{InsecureSkipVerify: d.config.TlsInsecureSkipVerify}, //nolint
+		}
+	case "unix":
+		factory = client.ConnFactory{Network: "unix", Address: d.config.FluentSocketPath}
+	default:
+		return nil, NewErrUnknownNetwork(d.config.FluentNetwork)
+	}
+
+	factory.Timeout = d.config.Timeout
+
+	conn, err := factory.New()
+	if err != nil || d.config.WriteTimeout <= 0 {
+		return conn, err
+	}
+
+	return &deadlineConn{Conn: conn, timeout: d.config.WriteTimeout}, nil
+}
+
+// deadlineConn sets the write deadline before every write.
+type deadlineConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c *deadlineConn) Write(b []byte) (int, error) {
+	if err := c.Conn.SetWriteDeadline(time.Now().Add(c.timeout)); err != nil {
+		return 0, err
+	}
+
+	return c.Conn.Write(b)
+}
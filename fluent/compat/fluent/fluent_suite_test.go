@@ -0,0 +1,13 @@
+package fluent_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestFluent(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Fluent Compat Suite")
+}
@@ -0,0 +1,263 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package fluent_test
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	. "github.com/IBM/fluent-forward-go/fluent/compat/fluent"
+	"github.com/IBM/fluent-forward-go/fluent/protocol"
+	"github.com/IBM/fluent-forward-go/fluent/server"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type received struct {
+	tag     string
+	entries protocol.EntryList
+}
+
+var _ = Describe("Fluent", func() {
+	var (
+		config Config
+		svr    *server.Server
+		events chan received
+		logger *Fluent
+	)
+
+	BeforeEach(func() {
+		events = make(chan received, 10)
+		eventCh := events
+
+		svr = server.NewServer(server.ServerOptions{
+			Handler: server.EventHandlerFunc(func(_ context.Context, tag string, entries protocol.EntryList) error {
+				eventCh <- received{tag, entries}
+				return nil
+			}),
+		})
+
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).ToNot(HaveOccurred())
+
+		go func() {
+			_ = svr.Serve(ln)
+		}()
+
+		host, port, err := net.SplitHostPort(ln.Addr().String())
+		Expect(err).ToNot(HaveOccurred())
+
+		config = Config{
+			FluentHost: host,
+			MaxRetry:   2,
+			RetryWait:  10,
+		}
+		config.FluentPort, err = strconv.Atoi(port)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		logger, err = New(config)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(logger.Close()).ToNot(HaveOccurred())
+		Expect(svr.Close()).ToNot(HaveOccurred())
+	})
+
+	receive := func() received {
+		var r received
+		Eventually(events).Should(Receive(&r))
+		Expect(r.entries).To(HaveLen(1))
+		return r
+	}
+
+	Describe("Post", func() {
+		It("sends maps", func() {
+			Expect(logger.Post("foo", map[string]string{"a": "b"})).ToNot(HaveOccurred())
+
+			r := receive()
+			Expect(r.tag).To(Equal("foo"))
+			Expect(r.entries[0].Record).To(Equal(map[string]interface{}{"a": "b"}))
+		})
+
+		It("sends structs using their msg and codec tags", func() {
+			rec := struct {
+				Name    string `msg:"name"`
+				Age     int    `codec:"age"`
+				Town    string
+				private string
+			}{"john smith", 42, "x", "y"}
+
+			Expect(logger.Post("foo", rec)).ToNot(HaveOccurred())
+
+			r := receive()
+			Expect(r.entries[0].Record).To(Equal(map[string]interface{}{
+				"name": "john smith",
+				"age":  int64(42),
+				"Town": "x",
+			}))
+		})
+
+		It("rejects other types", func() {
+			Expect(logger.Post("foo", "bar")).To(MatchError("fluent#PostWithTime: message must be a map"))
+			Expect(logger.Post("foo", map[int]string{1: "a"})).To(MatchError("fluent#PostWithTime: map keys must be strings"))
+		})
+
+		It("sends the time in seconds", func() {
+			tm := time.Unix(1234567890, 123456789)
+			Expect(logger.PostWithTime("foo", tm, map[string]string{"a": "b"})).ToNot(HaveOccurred())
+
+			r := receive()
+			Expect(r.entries[0].Timestamp.Time.Unix()).To(Equal(tm.Unix()))
+			Expect(r.entries[0].Timestamp.Time.Nanosecond()).To(BeZero())
+		})
+
+		It("fails once the logger is closed", func() {
+			Expect(logger.Close()).ToNot(HaveOccurred())
+			Expect(logger.Post("foo", map[string]string{"a": "b"})).To(MatchError(ContainSubstring("already closed")))
+		})
+
+		It("can be closed while posting", func() {
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				_ = logger.Post("foo", map[string]string{"a": "b"})
+			}()
+
+			Expect(logger.Close()).ToNot(HaveOccurred())
+			Eventually(done).Should(BeClosed())
+		})
+
+		When("TagPrefix is set", func() {
+			BeforeEach(func() {
+				config.TagPrefix = "app"
+			})
+
+			It("prefixes the tag", func() {
+				Expect(logger.Post("foo", map[string]string{"a": "b"})).ToNot(HaveOccurred())
+				Expect(receive().tag).To(Equal("app.foo"))
+			})
+
+			It("does not prefix the tag in EncodeAndPostData", func() {
+				Expect(logger.EncodeAndPostData("foo", time.Now(), map[string]string{"a": "b"})).ToNot(HaveOccurred())
+				Expect(receive().tag).To(Equal("foo"))
+			})
+		})
+
+		When("SubSecondPrecision is set", func() {
+			BeforeEach(func() {
+				config.SubSecondPrecision = true
+			})
+
+			It("sends the time in nanoseconds", func() {
+				tm := time.Unix(1234567890, 123456789)
+				Expect(logger.PostWithTime("foo", tm, map[string]string{"a": "b"})).ToNot(HaveOccurred())
+				Expect(receive().entries[0].Timestamp.Time.UnixNano()).To(Equal(tm.UnixNano()))
+			})
+		})
+
+		When("RequestAck is set", func() {
+			BeforeEach(func() {
+				config.RequestAck = true
+			})
+
+			It("waits for the ack", func() {
+				Expect(logger.Post("foo", map[string]string{"a": "b"})).ToNot(HaveOccurred())
+				Expect(receive().tag).To(Equal("foo"))
+			})
+		})
+	})
+
+	When("Async is set", func() {
+		var (
+			results []error
+			lock    sync.Mutex
+		)
+
+		BeforeEach(func() {
+			results = nil
+			config.Async = true
+			config.AsyncResultCallback = func(data []byte, err error) {
+				lock.Lock()
+				defer lock.Unlock()
+				results = append(results, err)
+			}
+		})
+
+		It("sends the queued messages before closing", func() {
+			for i := 0; i < 3; i++ {
+				Expect(logger.Post("foo", map[string]int{"i": i})).ToNot(HaveOccurred())
+			}
+
+			Expect(logger.Close()).ToNot(HaveOccurred())
+			Expect(results).To(Equal([]error{nil, nil, nil}))
+
+			for i := 0; i < 3; i++ {
+				Expect(receive().entries[0].Record).To(HaveKeyWithValue("i", int64(i)))
+			}
+		})
+
+		It("fails once the logger is closed", func() {
+			Expect(logger.Close()).ToNot(HaveOccurred())
+			Expect(logger.Post("foo", map[string]string{"a": "b"})).To(MatchError("fluent#appendBuffer: Logger already closed"))
+		})
+
+		When("the server cannot be reached", func() {
+			BeforeEach(func() {
+				Expect(svr.Close()).ToNot(HaveOccurred())
+				config.MaxRetry = 100
+				config.RetryWait = 1000
+				config.ForceStopAsyncSend = true
+			})
+
+			It("stops retrying when ForceStopAsyncSend is set", func() {
+				Expect(logger.Post("foo", map[string]string{"a": "b"})).ToNot(HaveOccurred())
+
+				start := time.Now()
+				Expect(logger.Close()).ToNot(HaveOccurred())
+				Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+			})
+		})
+	})
+})
+
+var _ = Describe("New", func() {
+	It("does not support JSON", func() {
+		_, err := New(Config{MarshalAsJSON: true})
+		Expect(err).To(MatchError(ErrJSONUnsupported))
+	})
+
+	It("rejects unknown networks", func() {
+		_, err := New(Config{FluentNetwork: "udp"})
+		Expect(err).To(MatchError(NewErrUnknownNetwork("udp")))
+	})
+})
@@ -0,0 +1,183 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+// Package conformance holds the fixtures and targets of the Forward protocol
+// conformance suite. The suite, which runs with go test, sends every message
+// mode, with and without compression, to a Target and checks the acks, the
+// handshake, the Size option and the decoded events. It runs against the
+// in-repo server as well as against Fluentd or Fluent Bit.
+package conformance
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/IBM/fluent-forward-go/fluent/client"
+	"github.com/IBM/fluent-forward-go/fluent/protocol"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// DefaultTimeout is the default ack timeout.
+const DefaultTimeout = 2 * time.Second
+
+// Target is the endpoint under test.
+type Target struct {
+	// Factory connects to the endpoint.
+	Factory client.ConnectionFactory
+	// SharedKey is the key the endpoint requires. The handshake specs are
+	// skipped if it is empty.
+	SharedKey []byte
+	// Events, if set, returns the events received by the endpoint with the
+	// tag, once there are at least n of them. The specs do not check the
+	// decoded events if it is nil, as is the case for Fluent Bit.
+	Events func(tag string, n int) ([]protocol.EntryExt, error)
+	// NewClient creates the client under test. The default is client.New.
+	NewClient func(opts client.ConnectionOptions) client.MessageClient
+	// Timeout bounds the wait for acks. The default is DefaultTimeout.
+	Timeout time.Duration
+	// Close, if set, is called after every spec.
+	Close func()
+}
+
+// Fixture is a message in one of the Forward modes and the events that an
+// endpoint must decode from it. Fixtures are deterministic, so that their
+// encodings can be compared with golden files.
+type Fixture struct {
+	// Name identifies the fixture.
+	Name    string
+	Tag     string
+	Message msgp.Marshaler
+	Events  protocol.EntryList
+}
+
+// Fixtures returns a fixture for each message mode, all of which request an
+// ack.
+func Fixtures() ([]Fixture, error) {
+	var (
+		sec      = time.Unix(1600000000, 0).UTC()
+		nsec     = time.Unix(1600000000, 123456789).UTC()
+		entries  = events("conformance", sec, nsec, nsec.Add(time.Second))
+		edges    = events("edges", time.Unix(0, 0).UTC(), time.Unix(1600000000, 999999999).UTC(), time.Unix(math.MaxUint32, 0).UTC())
+		size     = len(entries)
+		edgeSize = len(edges)
+		fixtures []Fixture
+	)
+
+	packed, err := pack(entries)
+	if err != nil {
+		return nil, err
+	}
+
+	compressed, err := compress(packed)
+	if err != nil {
+		return nil, err
+	}
+
+	add := func(name string, newMessage func(tag string, options *protocol.MessageOptions) msgp.Marshaler, events protocol.EntryList, options protocol.MessageOptions) {
+		tag := "conformance." + name
+		options.Chunk = base64.StdEncoding.EncodeToString([]byte(tag))
+
+		fixtures = append(fixtures, Fixture{
+			Name:    name,
+			Tag:     tag,
+			Message: newMessage(tag, &options),
+			Events:  events,
+		})
+	}
+
+	add("message", func(tag string, options *protocol.MessageOptions) msgp.Marshaler {
+		return &protocol.Message{Tag: tag, Timestamp: sec.Unix(), Record: entries[0].Record, Options: options}
+	}, entries[:1], protocol.MessageOptions{})
+
+	add("message_ext", func(tag string, options *protocol.MessageOptions) msgp.Marshaler {
+		return &protocol.MessageExt{Tag: tag, Timestamp: entries[1].Timestamp, Record: entries[1].Record, Options: options}
+	}, entries[1:2], protocol.MessageOptions{})
+
+	add("forward", func(tag string, options *protocol.MessageOptions) msgp.Marshaler {
+		return &protocol.ForwardMessage{Tag: tag, Entries: entries, Options: options}
+	}, entries, protocol.MessageOptions{Size: &size})
+
+	add("packed_forward", func(tag string, options *protocol.MessageOptions) msgp.Marshaler {
+		return &protocol.PackedForwardMessage{Tag: tag, EventStream: packed, Options: options}
+	}, entries, protocol.MessageOptions{Size: &size})
+
+	add("compressed_packed_forward", func(tag string, options *protocol.MessageOptions) msgp.Marshaler {
+		return &protocol.PackedForwardMessage{Tag: tag, EventStream: compressed, Options: options}
+	}, entries, protocol.MessageOptions{Size: &size, Compressed: "gzip"})
+
+	add("event_time_edges", func(tag string, options *protocol.MessageOptions) msgp.Marshaler {
+		return &protocol.ForwardMessage{Tag: tag, Entries: edges, Options: options}
+	}, edges, protocol.MessageOptions{Size: &edgeSize})
+
+	return fixtures, nil
+}
+
+// events returns an event with a single-key record for each time.
+func events(prefix string, times ...time.Time) protocol.EntryList {
+	entries := make(protocol.EntryList, len(times))
+	for i, t := range times {
+		entries[i] = protocol.EntryExt{
+			Timestamp: protocol.EventTime{Time: t},
+			Record:    map[string]interface{}{"message": fmt.Sprintf("%s %d", prefix, i)},
+		}
+	}
+
+	return entries
+}
+
+// pack encodes entries as an event stream. It does not use
+// EntryList.MarshalPacked, whose result is only valid until its next call.
+func pack(entries protocol.EntryList) ([]byte, error) {
+	var (
+		bits []byte
+		err  error
+	)
+
+	for _, e := range entries {
+		if bits, err = e.MarshalMsg(bits); err != nil {
+			return nil, err
+		}
+	}
+
+	return bits, nil
+}
+
+func compress(bits []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(bits); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,189 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package conformance_test
+
+import (
+	"context"
+	"flag"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/IBM/fluent-forward-go/fluent/client"
+	. "github.com/IBM/fluent-forward-go/fluent/conformance"
+	"github.com/IBM/fluent-forward-go/fluent/fluenttest"
+	"github.com/IBM/fluent-forward-go/fluent/protocol"
+	"github.com/IBM/fluent-forward-go/fluent/server"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var update = flag.Bool("update", false, "update the golden fixtures in fixtures/conformance")
+
+// goldenDir holds the golden fixtures, one MessagePack file per fixture.
+var goldenDir = filepath.Join("..", "..", "fixtures", "conformance")
+
+// fluenttestTarget runs the suite against a fluenttest.Server, which records
+// the events it receives, so that the decoded events are checked too.
+func fluenttestTarget(sharedKey []byte) func() *Target {
+	return func() *Target {
+		svr, err := fluenttest.NewServer(fluenttest.Options{SharedKey: sharedKey})
+		Expect(err).ToNot(HaveOccurred())
+
+		return &Target{
+			Factory:   svr.Factory(),
+			SharedKey: sharedKey,
+			Events: func(tag string, n int) ([]protocol.EntryExt, error) {
+				return svr.WaitForEvents(tag, n, time.Second)
+			},
+			Close: func() {
+				Expect(svr.Close()).To(Succeed())
+			},
+		}
+	}
+}
+
+// serverTarget runs the suite against a bare server.Server. Its handler
+// drops the events, so only the acks and the handshake are checked.
+func serverTarget(sharedKey []byte) func() *Target {
+	return func() *Target {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).ToNot(HaveOccurred())
+
+		svr := server.NewServer(server.ServerOptions{
+			Handler: server.EventHandlerFunc(func(context.Context, string, protocol.EntryList) error {
+				return nil
+			}),
+			SharedKey: sharedKey,
+		})
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_ = svr.Serve(ln)
+		}()
+
+		return &Target{
+			Factory:   &client.ConnFactory{Network: "tcp", Address: ln.Addr().String()},
+			SharedKey: sharedKey,
+			Close: func() {
+				Expect(svr.Close()).To(Succeed())
+				<-done
+			},
+		}
+	}
+}
+
+var _ = describeTarget("fluenttest.Server", fluenttestTarget(nil))
+
+var _ = describeTarget("fluenttest.Server with a shared key", fluenttestTarget([]byte("conformance")))
+
+var _ = describeTarget("server.Server", serverTarget(nil))
+
+var _ = describeTarget("server.Server with a shared key", serverTarget([]byte("conformance")))
+
+// The suite runs against an external endpoint, such as Fluent Bit started
+// with fixtures/conformance/fluent-bit.conf, when FLUENT_CONFORMANCE_ADDR is
+// set.
+var _ = describeTarget("external endpoint", func() *Target {
+	addr := os.Getenv("FLUENT_CONFORMANCE_ADDR")
+	if addr == "" {
+		Skip("FLUENT_CONFORMANCE_ADDR is not set")
+	}
+
+	return &Target{
+		Factory:   &client.ConnFactory{Address: addr},
+		SharedKey: []byte(os.Getenv("FLUENT_CONFORMANCE_SHARED_KEY")),
+	}
+})
+
+var _ = Describe("Fixtures", func() {
+	var svr *fluenttest.Server
+
+	fixtures, err := Fixtures()
+
+	It("builds every fixture", func() {
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	BeforeEach(func() {
+		var err error
+		svr, err = fluenttest.NewServer(fluenttest.Options{})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(svr.Close()).To(Succeed())
+	})
+
+	for _, f := range fixtures {
+		f := f
+
+		It("matches the golden "+f.Name+" fixture", func() {
+			path := filepath.Join(goldenDir, f.Name+".msgpack")
+
+			bits, err := f.Message.MarshalMsg(nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			if *update {
+				Expect(os.WriteFile(path, bits, 0o644)).To(Succeed())
+			}
+
+			golden, err := os.ReadFile(path)
+			Expect(err).ToNot(HaveOccurred())
+
+			// gzip output can change between Go releases, so compressed
+			// fixtures are only compared once decoded
+			if f.Name != "compressed_packed_forward" {
+				Expect(bits).To(Equal(golden))
+			}
+
+			c := client.New(client.ConnectionOptions{
+				Factory:    svr.Factory(),
+				RequireAck: true,
+			})
+			Expect(c.Connect()).To(Succeed())
+			defer c.Disconnect() //nolint
+
+			Expect(c.Send(protocol.RawMessage(golden))).To(Succeed())
+
+			events, err := svr.WaitForEvents(f.Tag, len(f.Events), time.Second)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(protocol.EntryList(events).Equal(f.Events)).To(BeTrue())
+		})
+	}
+
+	It("sets the Size option of every multi-event fixture", func() {
+		for _, f := range fixtures {
+			switch m := f.Message.(type) {
+			case *protocol.ForwardMessage:
+				Expect(m.Options.Size).To(HaveValue(Equal(len(f.Events))), f.Name)
+			case *protocol.PackedForwardMessage:
+				Expect(m.Options.Size).To(HaveValue(Equal(len(f.Events))), f.Name)
+			}
+		}
+	})
+})
@@ -0,0 +1,255 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package conformance_test
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/IBM/fluent-forward-go/fluent/client"
+	. "github.com/IBM/fluent-forward-go/fluent/conformance"
+	"github.com/IBM/fluent-forward-go/fluent/protocol"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// describeTarget registers the conformance specs in a container. target is
+// called before every spec.
+func describeTarget(text string, target func() *Target) bool {
+	return Describe(text, func() {
+		var t *Target
+
+		BeforeEach(func() {
+			t = target()
+			if t.Close != nil {
+				DeferCleanup(t.Close)
+			}
+		})
+
+		connect := func(requireAck bool, sharedKey []byte) client.MessageClient {
+			opts := client.ConnectionOptions{
+				Factory:           t.Factory,
+				RequireAck:        requireAck,
+				ConnectionTimeout: t.Timeout,
+				AuthInfo:          client.AuthInfo{SharedKey: sharedKey},
+			}
+
+			if opts.ConnectionTimeout == 0 {
+				opts.ConnectionTimeout = DefaultTimeout
+			}
+
+			var c client.MessageClient
+			if t.NewClient != nil {
+				c = t.NewClient(opts)
+			} else {
+				c = client.New(opts)
+			}
+
+			Expect(c.Connect()).To(Succeed())
+			DeferCleanup(c.Disconnect)
+
+			return c
+		}
+
+		handshake := func(c client.MessageClient) error {
+			h, ok := c.(interface{ Handshake() error })
+			if !ok {
+				Skip("the client does not implement Handshake")
+			}
+
+			return h.Handshake()
+		}
+
+		// newClient returns a connected client that has completed the
+		// handshake if the target requires it.
+		newClient := func(requireAck bool) client.MessageClient {
+			c := connect(requireAck, t.SharedKey)
+			if len(t.SharedKey) > 0 {
+				Expect(handshake(c)).To(Succeed())
+			}
+
+			return c
+		}
+
+		expectEvents := func(tag string, expected protocol.EntryList) {
+			if t.Events == nil {
+				return
+			}
+
+			events, err := t.Events(tag, len(expected))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(protocol.EntryList(events).Equal(expected)).To(BeTrue(),
+				"received %v, expected %v", events, expected)
+		}
+
+		Describe("fixtures", func() {
+			fixtures, err := Fixtures()
+
+			It("builds the fixtures", func() {
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			for _, f := range fixtures {
+				f := f
+
+				It("acks the "+f.Name+" fixture and decodes its events", func() {
+					bits, err := f.Message.MarshalMsg(nil)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(newClient(true).Send(protocol.RawMessage(bits))).To(Succeed())
+					expectEvents(f.Tag, f.Events)
+				})
+			}
+		})
+
+		Describe("client", func() {
+			entries := Events("client", time.Unix(1600000000, 1).UTC(), time.Unix(1600000001, 2).UTC())
+
+			It("sends Message mode", func() {
+				c := newClient(true)
+				Expect(c.SendMessage("conformance.client.message", entries[0].Record)).To(Succeed())
+
+				if t.Events != nil {
+					events, err := t.Events("conformance.client.message", 1)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(events[0].Record).To(Equal(entries[0].Record))
+				}
+			})
+
+			It("sends Message mode with an EventTime", func() {
+				c := newClient(true)
+				Expect(c.SendMessageExt("conformance.client.message_ext", entries[0].Record)).To(Succeed())
+
+				if t.Events != nil {
+					events, err := t.Events("conformance.client.message_ext", 1)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(events[0].Record).To(Equal(entries[0].Record))
+				}
+			})
+
+			It("sends Forward mode", func() {
+				Expect(newClient(true).SendForward("conformance.client.forward", entries)).To(Succeed())
+				expectEvents("conformance.client.forward", entries)
+			})
+
+			It("sends PackedForward mode", func() {
+				Expect(newClient(true).SendPacked("conformance.client.packed", entries)).To(Succeed())
+				expectEvents("conformance.client.packed", entries)
+			})
+
+			It("sends CompressedPackedForward mode", func() {
+				Expect(newClient(true).SendCompressed("conformance.client.compressed", entries)).To(Succeed())
+				expectEvents("conformance.client.compressed", entries)
+			})
+
+			It("sets the Size option to the number of events", func() {
+				var (
+					tag      = "conformance.client.size"
+					expected protocol.EntryList
+					c        = newClient(true)
+				)
+
+				// every message has distinct events, which EntryList.Equal
+				// requires
+				send := func(newMessage func(entries protocol.EntryList) (protocol.ChunkEncoder, *protocol.MessageOptions, error)) {
+					entries := Events(fmt.Sprint("size ", len(expected)), time.Unix(1600000000, 1).UTC(), time.Unix(1600000001, 2).UTC())
+					expected = append(expected, entries...)
+
+					msg, options, err := newMessage(entries)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(options.Size).To(HaveValue(Equal(len(entries))))
+					Expect(c.Send(msg)).To(Succeed())
+				}
+
+				send(func(entries protocol.EntryList) (protocol.ChunkEncoder, *protocol.MessageOptions, error) {
+					fm := protocol.NewForwardMessage(tag, entries)
+					return fm, fm.Options, nil
+				})
+
+				send(func(entries protocol.EntryList) (protocol.ChunkEncoder, *protocol.MessageOptions, error) {
+					pfm, err := protocol.NewPackedForwardMessage(tag, entries)
+					if err != nil {
+						return nil, nil, err
+					}
+
+					return pfm, pfm.Options, nil
+				})
+
+				send(func(entries protocol.EntryList) (protocol.ChunkEncoder, *protocol.MessageOptions, error) {
+					cfm, err := protocol.NewCompressedPackedForwardMessage(tag, entries)
+					if err != nil {
+						return nil, nil, err
+					}
+
+					return cfm, cfm.Options, nil
+				})
+
+				expectEvents(tag, expected)
+			})
+		})
+
+		Describe("acks", func() {
+			It("acks each chunk in order", func() {
+				c := newClient(true)
+
+				for i := 0; i < 3; i++ {
+					Expect(c.SendMessage("conformance.acks", map[string]interface{}{"i": i})).To(Succeed())
+				}
+			})
+
+			It("does not ack messages without a chunk", func() {
+				c := newClient(true)
+
+				msg := protocol.NewMessage("conformance.acks.none", map[string]interface{}{"a": "b"})
+				bits, err := msg.MarshalMsg(nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				// an ack for the first message would not match the chunk
+				// of the second
+				Expect(c.SendRaw(bits)).To(Succeed())
+				Expect(c.SendMessage("conformance.acks.none", map[string]interface{}{"c": "d"})).To(Succeed())
+			})
+		})
+
+		Describe("handshake", func() {
+			BeforeEach(func() {
+				if len(t.SharedKey) == 0 {
+					Skip("the target does not require a shared key")
+				}
+			})
+
+			It("accepts the shared key", func() {
+				c := connect(true, t.SharedKey)
+				Expect(handshake(c)).To(Succeed())
+				Expect(c.SendMessage("conformance.handshake", map[string]interface{}{"a": "b"})).To(Succeed())
+			})
+
+			It("rejects a wrong shared key", func() {
+				c := connect(true, append([]byte("wrong-"), t.SharedKey...))
+				Expect(handshake(c)).ToNot(Succeed())
+			})
+		})
+	})
+}
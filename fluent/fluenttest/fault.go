@@ -0,0 +1,309 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package fluenttest
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/IBM/fluent-forward-go/fluent/client"
+	"github.com/IBM/fluent-forward-go/fluent/client/ws/ext"
+)
+
+// ErrFaultReset is returned by the write that makes a connection reach
+// Faults.ResetAfter. Like the error of a real reset, it is a net.Error.
+var ErrFaultReset error = &net.OpError{
+	Op:  "write",
+	Net: "tcp",
+	Err: errors.New("connection reset by fault injection"),
+}
+
+// Faults are the faults injected into a connection. The zero value injects
+// none. Byte counts are per connection.
+type Faults struct {
+	// Latency delays every write.
+	Latency time.Duration
+	// Bandwidth, if greater than zero, limits writes to that many bytes per
+	// second.
+	Bandwidth int
+	// TruncateAfter, if greater than zero, silently discards everything
+	// written after the first TruncateAfter bytes. The writes still succeed.
+	TruncateAfter int
+	// ResetAfter, if greater than zero, resets the connection once
+	// ResetAfter bytes have been written. The write that crosses the limit
+	// is partial and returns ErrFaultReset.
+	ResetAfter int
+	// ReadStall delays every read.
+	ReadStall time.Duration
+	// CorruptReads inverts every byte read, which corrupts acks and
+	// handshake messages.
+	CorruptReads bool
+}
+
+// faultSettings holds the Faults of a factory, which can be changed while
+// its connections are open.
+type faultSettings struct {
+	lock   sync.RWMutex
+	faults Faults
+}
+
+func (s *faultSettings) get() Faults {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.faults
+}
+
+func (s *faultSettings) set(faults Faults) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.faults = faults
+}
+
+// faultState applies the faults to the bytes of a single connection.
+type faultState struct {
+	settings *faultSettings
+	reset    func()
+	lock     sync.Mutex
+	written  int
+}
+
+// write writes p with w, which must write all of p or fail.
+func (s *faultState) write(p []byte, w func([]byte) (int, error)) (int, error) {
+	f := s.settings.get()
+
+	time.Sleep(f.Latency)
+
+	if f.Bandwidth > 0 {
+		time.Sleep(time.Duration(len(p)) * time.Second / time.Duration(f.Bandwidth))
+	}
+
+	s.lock.Lock()
+	written := s.written
+	s.written += len(p)
+	s.lock.Unlock()
+
+	if f.ResetAfter > 0 && written+len(p) >= f.ResetAfter {
+		n, _ := w(p[:remaining(f.ResetAfter, written)])
+		s.reset()
+
+		return n, ErrFaultReset
+	}
+
+	if f.TruncateAfter > 0 && written+len(p) > f.TruncateAfter {
+		if _, err := w(p[:remaining(f.TruncateAfter, written)]); err != nil {
+			return 0, err
+		}
+
+		return len(p), nil
+	}
+
+	return w(p)
+}
+
+func (s *faultState) read(p []byte, r func([]byte) (int, error)) (int, error) {
+	f := s.settings.get()
+
+	time.Sleep(f.ReadStall)
+
+	n, err := r(p)
+	if f.CorruptReads {
+		corrupt(p[:n])
+	}
+
+	return n, err
+}
+
+func corrupt(p []byte) {
+	for i := range p {
+		p[i] = ^p[i]
+	}
+}
+
+// remaining returns the number of bytes that can be written before the
+// limit.
+func remaining(limit, written int) int {
+	if written > limit {
+		return 0
+	}
+
+	return limit - written
+}
+
+// reset closes conn, with a TCP RST if possible.
+func reset(conn net.Conn) {
+	if tcp, ok := conn.(*net.TCPConn); ok {
+		_ = tcp.SetLinger(0)
+	}
+
+	_ = conn.Close()
+}
+
+// FaultyFactory is a client.ConnectionFactory that injects faults into the
+// connections created by the embedded factory.
+type FaultyFactory struct {
+	client.ConnectionFactory
+	settings faultSettings
+}
+
+func NewFaultyFactory(factory client.ConnectionFactory, faults Faults) *FaultyFactory {
+	f := &FaultyFactory{ConnectionFactory: factory}
+	f.settings.set(faults)
+
+	return f
+}
+
+// SetFaults changes the faults of new and open connections.
+func (f *FaultyFactory) SetFaults(faults Faults) {
+	f.settings.set(faults)
+}
+
+func (f *FaultyFactory) New() (net.Conn, error) {
+	conn, err := f.ConnectionFactory.New()
+	if err != nil {
+		return nil, err
+	}
+
+	return &faultyConn{
+		Conn: conn,
+		state: &faultState{
+			settings: &f.settings,
+			reset:    func() { reset(conn) },
+		},
+	}, nil
+}
+
+type faultyConn struct {
+	net.Conn
+	state *faultState
+}
+
+func (c *faultyConn) Write(p []byte) (int, error) {
+	return c.state.write(p, c.Conn.Write)
+}
+
+func (c *faultyConn) Read(p []byte) (int, error) {
+	return c.state.read(p, c.Conn.Read)
+}
+
+// FaultyWSFactory is a client.WSConnectionFactory that injects faults into
+// the websocket connections created by the embedded factory. The faults
+// apply to message payloads, so a truncated message is still a valid
+// websocket message.
+type FaultyWSFactory struct {
+	client.WSConnectionFactory
+	settings faultSettings
+}
+
+func NewFaultyWSFactory(factory client.WSConnectionFactory, faults Faults) *FaultyWSFactory {
+	f := &FaultyWSFactory{WSConnectionFactory: factory}
+	f.settings.set(faults)
+
+	return f
+}
+
+// SetFaults changes the faults of new and open connections.
+func (f *FaultyWSFactory) SetFaults(faults Faults) {
+	f.settings.set(faults)
+}
+
+func (f *FaultyWSFactory) New() (ext.Conn, error) {
+	conn, err := f.WSConnectionFactory.New()
+	if err != nil {
+		return nil, err
+	}
+
+	return &faultyWSConn{
+		Conn: conn,
+		state: &faultState{
+			settings: &f.settings,
+			reset:    func() { reset(conn.UnderlyingConn()) },
+		},
+	}, nil
+}
+
+type faultyWSConn struct {
+	ext.Conn
+	state *faultState
+}
+
+func (c *faultyWSConn) WriteMessage(messageType int, data []byte) error {
+	_, err := c.state.write(data, func(p []byte) (int, error) {
+		return len(p), c.Conn.WriteMessage(messageType, p)
+	})
+
+	return err
+}
+
+func (c *faultyWSConn) NextWriter(messageType int) (io.WriteCloser, error) {
+	w, err := c.Conn.NextWriter(messageType)
+	if err != nil {
+		return nil, err
+	}
+
+	return &faultyWriter{WriteCloser: w, state: c.state}, nil
+}
+
+func (c *faultyWSConn) ReadMessage() (int, []byte, error) {
+	time.Sleep(c.state.settings.get().ReadStall)
+
+	mt, p, err := c.Conn.ReadMessage()
+	if c.state.settings.get().CorruptReads {
+		corrupt(p)
+	}
+
+	return mt, p, err
+}
+
+func (c *faultyWSConn) NextReader() (int, io.Reader, error) {
+	mt, r, err := c.Conn.NextReader()
+	if err != nil {
+		return mt, r, err
+	}
+
+	return mt, &faultyReader{Reader: r, state: c.state}, nil
+}
+
+type faultyWriter struct {
+	io.WriteCloser
+	state *faultState
+}
+
+func (w *faultyWriter) Write(p []byte) (int, error) {
+	return w.state.write(p, w.WriteCloser.Write)
+}
+
+type faultyReader struct {
+	io.Reader
+	state *faultState
+}
+
+func (r *faultyReader) Read(p []byte) (int, error) {
+	return r.state.read(p, r.Reader.Read)
+}
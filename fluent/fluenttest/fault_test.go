@@ -0,0 +1,225 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package fluenttest_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/IBM/fluent-forward-go/fluent/client"
+	"github.com/IBM/fluent-forward-go/fluent/client/ws"
+	. "github.com/IBM/fluent-forward-go/fluent/fluenttest"
+	"github.com/IBM/fluent-forward-go/fluent/protocol"
+	"github.com/IBM/fluent-forward-go/fluent/server"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FaultyFactory", func() {
+	var (
+		svr     *Server
+		factory *FaultyFactory
+		cli     *client.Client
+		record  map[string]interface{}
+	)
+
+	BeforeEach(func() {
+		var err error
+		svr, err = NewServer(Options{})
+		Expect(err).ToNot(HaveOccurred())
+
+		factory = NewFaultyFactory(svr.Factory(), Faults{})
+		cli = client.New(client.ConnectionOptions{
+			Factory:           factory,
+			RequireAck:        true,
+			ConnectionTimeout: 100 * time.Millisecond,
+		})
+		Expect(cli.Connect()).ToNot(HaveOccurred())
+
+		record = map[string]interface{}{"a": strings.Repeat("b", 100)}
+	})
+
+	AfterEach(func() {
+		_ = cli.Disconnect()
+		Expect(svr.Close()).ToNot(HaveOccurred())
+	})
+
+	It("injects no faults by default", func() {
+		Expect(cli.SendMessage("foo", record)).ToNot(HaveOccurred())
+		Expect(svr.Events("foo")).To(HaveLen(1))
+	})
+
+	It("delays writes", func() {
+		factory.SetFaults(Faults{Latency: 50 * time.Millisecond})
+
+		start := time.Now()
+		Expect(cli.SendMessage("foo", record)).ToNot(HaveOccurred())
+		Expect(time.Since(start)).To(BeNumerically(">=", 50*time.Millisecond))
+	})
+
+	It("limits the bandwidth", func() {
+		factory.SetFaults(Faults{Bandwidth: 2000})
+
+		start := time.Now()
+		Expect(cli.SendMessage("foo", record)).ToNot(HaveOccurred())
+		Expect(time.Since(start)).To(BeNumerically(">=", 50*time.Millisecond))
+	})
+
+	It("truncates writes", func() {
+		cli.RequireAck = false
+		factory.SetFaults(Faults{TruncateAfter: 10})
+
+		Expect(cli.SendMessage("foo", record)).ToNot(HaveOccurred())
+		Consistently(svr.Messages, 100*time.Millisecond).Should(BeZero())
+	})
+
+	It("resets the connection", func() {
+		factory.SetFaults(Faults{ResetAfter: 10})
+
+		err := cli.SendMessage("foo", record)
+		Expect(errors.Is(err, ErrFaultReset)).To(BeTrue())
+		Expect(client.IsRetryable(err)).To(BeTrue())
+
+		factory.SetFaults(Faults{})
+		Expect(cli.Reconnect()).ToNot(HaveOccurred())
+		Expect(cli.SendMessage("foo", record)).ToNot(HaveOccurred())
+	})
+
+	It("stalls reads", func() {
+		factory.SetFaults(Faults{ReadStall: 200 * time.Millisecond})
+
+		err := cli.SendMessage("foo", record)
+		Expect(client.IsRetryable(err)).To(BeTrue())
+		Expect(svr.Events("foo")).To(HaveLen(1))
+	})
+
+	It("corrupts reads", func() {
+		factory.SetFaults(Faults{CorruptReads: true})
+
+		Expect(cli.SendMessage("foo", record)).To(HaveOccurred())
+		Expect(svr.Events("foo")).To(HaveLen(1))
+	})
+})
+
+var _ = Describe("FaultyWSFactory", func() {
+	var (
+		handler  *server.WSHandler
+		svr      *httptest.Server
+		factory  *FaultyWSFactory
+		cli      *client.WSClient
+		received chan string
+		replies  chan []byte
+	)
+
+	BeforeEach(func() {
+		received = make(chan string, 10)
+		replies = make(chan []byte, 10)
+
+		rcvd, rpls := received, replies
+
+		handler = server.NewWSHandler(server.WSHandlerOptions{
+			Handler: server.EventHandlerFunc(func(_ context.Context, tag string, _ protocol.EntryList) error {
+				rcvd <- tag
+				return nil
+			}),
+		})
+		svr = httptest.NewServer(handler)
+
+		factory = NewFaultyWSFactory(&client.DefaultWSConnectionFactory{
+			URL: "ws" + strings.TrimPrefix(svr.URL, "http"),
+		}, Faults{})
+
+		cli = client.NewWS(client.WSConnectionOptions{
+			Factory: factory,
+			ConnectionOptions: ws.ConnectionOptions{
+				ReadHandler: func(_ ws.Connection, _ int, p []byte, err error) error {
+					if err == nil {
+						rpls <- p
+					}
+
+					return err
+				},
+			},
+		})
+		Expect(cli.Connect()).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		_ = cli.Disconnect()
+		Expect(handler.Close()).ToNot(HaveOccurred())
+		svr.Close()
+	})
+
+	send := func() error {
+		msg := protocol.NewMessage("foo", map[string]interface{}{"a": "b"})
+		msg.Options = &protocol.MessageOptions{Chunk: "abc"}
+
+		return cli.Send(msg)
+	}
+
+	It("injects no faults by default", func() {
+		Expect(send()).ToNot(HaveOccurred())
+		Eventually(received).Should(Receive(Equal("foo")))
+
+		var p []byte
+		Eventually(replies).Should(Receive(&p))
+
+		var ack protocol.AckMessage
+		_, err := ack.UnmarshalMsg(p)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ack.Ack).To(Equal("abc"))
+	})
+
+	It("truncates messages", func() {
+		factory.SetFaults(Faults{TruncateAfter: 10})
+
+		Expect(send()).ToNot(HaveOccurred())
+		Consistently(received, 100*time.Millisecond).ShouldNot(Receive())
+	})
+
+	It("resets the connection", func() {
+		factory.SetFaults(Faults{ResetAfter: 10})
+
+		Expect(send()).To(HaveOccurred())
+		Consistently(received, 100*time.Millisecond).ShouldNot(Receive())
+	})
+
+	It("corrupts reads", func() {
+		factory.SetFaults(Faults{CorruptReads: true})
+
+		Expect(send()).ToNot(HaveOccurred())
+		Eventually(received).Should(Receive(Equal("foo")))
+
+		var p []byte
+		Eventually(replies).Should(Receive(&p))
+
+		var ack protocol.AckMessage
+		_, err := ack.UnmarshalMsg(p)
+		Expect(err).To(HaveOccurred())
+	})
+})
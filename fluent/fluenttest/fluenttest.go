@@ -0,0 +1,299 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+// Package fluenttest provides a Forward server for tests. It listens on a
+// loopback address, records the events it receives, and can be configured
+// to require a shared key, delay or drop acks, and close connections
+// mid-stream. FaultyFactory and FaultyWSFactory inject network faults on the
+// client side.
+package fluenttest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/IBM/fluent-forward-go/fluent/client"
+	"github.com/IBM/fluent-forward-go/fluent/protocol"
+	"github.com/IBM/fluent-forward-go/fluent/server"
+)
+
+// errNoAck is returned by the handler to prevent the ack.
+var errNoAck = errors.New("fluenttest: ack dropped")
+
+// Options configures a Server.
+type Options struct {
+	// SharedKey, if set, requires clients to complete the shared key
+	// handshake.
+	SharedKey []byte
+	// Hostname is sent to clients in the handshake PONG.
+	Hostname string
+	// AckDelay delays every ack.
+	AckDelay time.Duration
+	// DropAcks records events without acking them.
+	DropAcks bool
+	// CloseAfter, if greater than zero, closes every connection once that
+	// many messages have been received. The last message is recorded but
+	// not acked.
+	CloseAfter int
+}
+
+// Server is a Forward server that records the events it receives, by tag.
+// The ack behavior can be changed while it runs.
+type Server struct {
+	svr      *server.Server
+	ln       net.Listener
+	lock     sync.Mutex
+	opts     Options
+	events   map[string][]protocol.EntryExt
+	messages int
+	accepted int
+	conns    map[net.Conn]struct{}
+	// received is closed and replaced whenever events are recorded.
+	received chan struct{}
+	done     chan struct{}
+}
+
+// NewServer starts a Server on a loopback TCP address.
+func NewServer(opts Options) (*Server, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		opts:     opts,
+		events:   map[string][]protocol.EntryExt{},
+		conns:    map[net.Conn]struct{}{},
+		received: make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	s.ln = &listener{Listener: ln, s: s}
+	s.svr = server.NewServer(server.ServerOptions{
+		Handler:   server.EventHandlerFunc(s.handle),
+		SharedKey: opts.SharedKey,
+		Hostname:  opts.Hostname,
+	})
+
+	go func() {
+		defer close(s.done)
+		_ = s.svr.Serve(s.ln)
+	}()
+
+	return s, nil
+}
+
+// Addr returns the address the server listens on.
+func (s *Server) Addr() string {
+	return s.ln.Addr().String()
+}
+
+// Factory returns a client.ConnectionFactory that connects to the server.
+func (s *Server) Factory() *client.ConnFactory {
+	return &client.ConnFactory{
+		Network: "tcp",
+		Address: s.Addr(),
+	}
+}
+
+// Close stops the server and closes every connection.
+func (s *Server) Close() error {
+	err := s.svr.Close()
+	<-s.done
+
+	return err
+}
+
+func (s *Server) handle(_ context.Context, tag string, entries protocol.EntryList) error {
+	s.lock.Lock()
+
+	s.events[tag] = append(s.events[tag], entries...)
+	s.messages++
+	close(s.received)
+	s.received = make(chan struct{})
+
+	opts := s.opts
+	closeConns := opts.CloseAfter > 0 && s.messages == opts.CloseAfter
+
+	s.lock.Unlock()
+
+	if closeConns {
+		s.CloseConnections()
+		return errNoAck
+	}
+
+	if opts.DropAcks {
+		return errNoAck
+	}
+
+	time.Sleep(opts.AckDelay)
+
+	return nil
+}
+
+// SetAckDelay changes the delay of subsequent acks.
+func (s *Server) SetAckDelay(d time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.opts.AckDelay = d
+}
+
+// SetDropAcks changes whether subsequent messages are acked.
+func (s *Server) SetDropAcks(drop bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.opts.DropAcks = drop
+}
+
+// CloseConnections closes every open connection. The server keeps accepting
+// new ones.
+func (s *Server) CloseConnections() {
+	s.lock.Lock()
+	conns := make([]net.Conn, 0, len(s.conns))
+	for conn := range s.conns {
+		conns = append(conns, conn)
+	}
+	s.lock.Unlock()
+
+	for _, conn := range conns {
+		_ = conn.Close()
+	}
+}
+
+// Accepted returns the number of connections accepted so far.
+func (s *Server) Accepted() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.accepted
+}
+
+// Messages returns the number of messages received so far.
+func (s *Server) Messages() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.messages
+}
+
+// Tags returns the sorted tags of the events received so far.
+func (s *Server) Tags() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	tags := make([]string, 0, len(s.events))
+	for tag := range s.events {
+		tags = append(tags, tag)
+	}
+
+	sort.Strings(tags)
+
+	return tags
+}
+
+// Events returns the events received so far with the tag.
+func (s *Server) Events(tag string) []protocol.EntryExt {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return append([]protocol.EntryExt(nil), s.events[tag]...)
+}
+
+// Reset discards the events and counts recorded so far.
+func (s *Server) Reset() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.events = map[string][]protocol.EntryExt{}
+	s.messages = 0
+	s.accepted = 0
+}
+
+// WaitForEvents waits until at least n events with the tag have been
+// received and returns them. It returns an error if the timeout elapses
+// first.
+func (s *Server) WaitForEvents(tag string, n int, timeout time.Duration) ([]protocol.EntryExt, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		s.lock.Lock()
+		events := s.events[tag]
+		received := s.received
+		s.lock.Unlock()
+
+		if len(events) >= n {
+			return append([]protocol.EntryExt(nil), events...), nil
+		}
+
+		select {
+		case <-received:
+		case <-timer.C:
+			return nil, fmt.Errorf("fluenttest: received %d of %d events with tag %q after %v", len(events), n, tag, timeout)
+		}
+	}
+}
+
+// listener tracks the accepted connections so that they can be closed
+// mid-stream.
+type listener struct {
+	net.Listener
+	s *Server
+}
+
+func (l *listener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &trackedConn{Conn: conn, s: l.s}
+
+	l.s.lock.Lock()
+	l.s.conns[c] = struct{}{}
+	l.s.accepted++
+	l.s.lock.Unlock()
+
+	return c, nil
+}
+
+type trackedConn struct {
+	net.Conn
+	s *Server
+}
+
+func (c *trackedConn) Close() error {
+	c.s.lock.Lock()
+	delete(c.s.conns, c)
+	c.s.lock.Unlock()
+
+	return c.Conn.Close()
+}
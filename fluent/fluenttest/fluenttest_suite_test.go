@@ -0,0 +1,13 @@
+package fluenttest_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestFluenttest(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Fluenttest Suite")
+}
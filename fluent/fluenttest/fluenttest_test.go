@@ -0,0 +1,172 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package fluenttest_test
+
+import (
+	"errors"
+	"time"
+
+	"github.com/IBM/fluent-forward-go/fluent/client"
+	. "github.com/IBM/fluent-forward-go/fluent/fluenttest"
+	"github.com/IBM/fluent-forward-go/fluent/protocol"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Server", func() {
+	var (
+		opts    Options
+		svr     *Server
+		cli     *client.Client
+		cliOpts client.ConnectionOptions
+	)
+
+	BeforeEach(func() {
+		opts = Options{}
+		cliOpts = client.ConnectionOptions{
+			RequireAck:        true,
+			ConnectionTimeout: 200 * time.Millisecond,
+		}
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		svr, err = NewServer(opts)
+		Expect(err).ToNot(HaveOccurred())
+
+		cliOpts.Factory = svr.Factory()
+		cli = client.New(cliOpts)
+		Expect(cli.Connect()).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		_ = cli.Disconnect()
+		Expect(svr.Close()).ToNot(HaveOccurred())
+	})
+
+	It("records and acks events by tag", func() {
+		Expect(cli.SendMessage("foo", map[string]interface{}{"a": "b"})).ToNot(HaveOccurred())
+		Expect(cli.SendForward("bar", protocol.EntryList{
+			{Timestamp: protocol.EventTimeNow(), Record: map[string]interface{}{"c": "d"}},
+			{Timestamp: protocol.EventTimeNow(), Record: map[string]interface{}{"e": "f"}},
+		})).ToNot(HaveOccurred())
+
+		events, err := svr.WaitForEvents("bar", 2, time.Second)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(events[1].Record).To(HaveKeyWithValue("e", "f"))
+
+		Expect(svr.Events("foo")).To(HaveLen(1))
+		Expect(svr.Tags()).To(Equal([]string{"bar", "foo"}))
+		Expect(svr.Messages()).To(Equal(2))
+		Expect(svr.Accepted()).To(Equal(1))
+
+		svr.Reset()
+		Expect(svr.Tags()).To(BeEmpty())
+		Expect(svr.Messages()).To(BeZero())
+	})
+
+	It("waits for events sent later", func() {
+		go func() {
+			defer GinkgoRecover()
+
+			time.Sleep(20 * time.Millisecond)
+			Expect(cli.SendMessage("foo", map[string]interface{}{"a": "b"})).ToNot(HaveOccurred())
+		}()
+
+		events, err := svr.WaitForEvents("foo", 1, time.Second)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(events).To(HaveLen(1))
+	})
+
+	It("times out waiting for events", func() {
+		_, err := svr.WaitForEvents("foo", 1, 10*time.Millisecond)
+		Expect(err).To(MatchError(`fluenttest: received 0 of 1 events with tag "foo" after 10ms`))
+	})
+
+	When("SharedKey is set", func() {
+		BeforeEach(func() {
+			opts.SharedKey = []byte("secret")
+			opts.Hostname = "fluenttest"
+			cliOpts.AuthInfo = client.AuthInfo{SharedKey: opts.SharedKey}
+		})
+
+		It("requires the handshake", func() {
+			Expect(cli.Handshake()).ToNot(HaveOccurred())
+			Expect(cli.SendMessage("foo", map[string]interface{}{"a": "b"})).ToNot(HaveOccurred())
+			Expect(svr.Events("foo")).To(HaveLen(1))
+		})
+	})
+
+	When("DropAcks is set", func() {
+		BeforeEach(func() {
+			opts.DropAcks = true
+		})
+
+		It("records events without acking them", func() {
+			err := cli.SendMessage("foo", map[string]interface{}{"a": "b"})
+
+			var timeout *client.AckTimeoutError
+			Expect(errors.As(err, &timeout)).To(BeTrue())
+			Expect(svr.Events("foo")).To(HaveLen(1))
+
+			svr.SetDropAcks(false)
+			Expect(cli.Reconnect()).ToNot(HaveOccurred())
+			Expect(cli.SendMessage("foo", map[string]interface{}{"a": "b"})).ToNot(HaveOccurred())
+		})
+	})
+
+	When("AckDelay is set", func() {
+		BeforeEach(func() {
+			opts.AckDelay = 50 * time.Millisecond
+		})
+
+		It("delays the acks", func() {
+			start := time.Now()
+			Expect(cli.SendMessage("foo", map[string]interface{}{"a": "b"})).ToNot(HaveOccurred())
+			Expect(time.Since(start)).To(BeNumerically(">=", 50*time.Millisecond))
+
+			svr.SetAckDelay(300 * time.Millisecond)
+			Expect(cli.SendMessage("foo", map[string]interface{}{"a": "b"})).To(HaveOccurred())
+		})
+	})
+
+	When("CloseAfter is set", func() {
+		BeforeEach(func() {
+			opts.CloseAfter = 2
+		})
+
+		It("closes the connection mid-stream", func() {
+			Expect(cli.SendMessage("foo", map[string]interface{}{"a": "b"})).ToNot(HaveOccurred())
+
+			err := cli.SendMessage("foo", map[string]interface{}{"a": "b"})
+			Expect(client.IsRetryable(err)).To(BeTrue())
+			Expect(svr.Events("foo")).To(HaveLen(2))
+
+			Expect(cli.Reconnect()).ToNot(HaveOccurred())
+			Expect(cli.SendMessage("foo", map[string]interface{}{"a": "b"})).ToNot(HaveOccurred())
+			Expect(svr.Accepted()).To(Equal(2))
+		})
+	})
+})
@@ -0,0 +1,451 @@
+//go:build go1.21
+
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package logging
+
+import (
+	"context"
+	"encoding"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/IBM/fluent-forward-go/fluent/client"
+	"github.com/IBM/fluent-forward-go/fluent/protocol"
+	"github.com/tinylib/msgp/msgp"
+)
+
+const (
+	DefaultBufferSize = 1024
+)
+
+// ErrHandlerClosed is passed to OnError for records logged after Close.
+var ErrHandlerClosed = errors.New("handler closed")
+
+// HandlerOptions configures a Handler.
+type HandlerOptions struct {
+	// Tag is the tag of every message.
+	Tag string
+	// Level is the minimum level that is logged. The default is
+	// slog.LevelInfo.
+	Level slog.Leveler
+	// AddSource adds a "source" map with the function, file and line of the
+	// logging call.
+	AddSource bool
+	// BufferSize is the number of records that can wait to be sent. Records
+	// logged while the buffer is full are dropped. The default is
+	// DefaultBufferSize.
+	BufferSize int
+	// OnError, if set, is called with every send error and for every
+	// dropped record. It is called from the sending goroutine or, for
+	// dropped records, from the logging goroutine, and must not block.
+	OnError func(err error)
+}
+
+// Handler is a log/slog Handler that sends each record as a MessageExt.
+// Records are encoded into MessagePack when they are logged and sent by a
+// background goroutine, so logging never waits on the network. The level
+// and message are stored under slog.LevelKey and slog.MessageKey, and groups
+// become nested maps. The record's time is the event's timestamp; records
+// without one are given the current time.
+type Handler struct {
+	*handlerCore
+	opts   HandlerOptions
+	groups []string
+	// attrs[i] holds the attributes added while len(groups) == i
+	attrs [][]slog.Attr
+}
+
+// handlerCore is shared by a Handler and every Handler derived from it.
+type handlerCore struct {
+	client  client.MessageClient
+	queue   chan *protocol.MessageExt
+	onError func(err error)
+	dropped atomic.Uint64
+	closed  bool
+	lock    sync.RWMutex
+	wg      sync.WaitGroup
+}
+
+// NewHandler returns a Handler that sends through the client. The client
+// must be connected; call Close to flush the buffer and stop sending.
+func NewHandler(c client.MessageClient, opts HandlerOptions) *Handler {
+	if opts.Level == nil {
+		opts.Level = slog.LevelInfo
+	}
+
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = DefaultBufferSize
+	}
+
+	core := &handlerCore{
+		client:  c,
+		queue:   make(chan *protocol.MessageExt, opts.BufferSize),
+		onError: opts.OnError,
+	}
+
+	core.wg.Add(1)
+
+	go core.run()
+
+	return &Handler{
+		handlerCore: core,
+		opts:        opts,
+		attrs:       make([][]slog.Attr, 1),
+	}
+}
+
+func (hc *handlerCore) run() {
+	defer hc.wg.Done()
+
+	for msg := range hc.queue {
+		if err := hc.client.Send(msg); err != nil {
+			hc.error(err)
+		}
+	}
+}
+
+func (hc *handlerCore) error(err error) {
+	if hc.onError != nil {
+		hc.onError(err)
+	}
+}
+
+// Dropped returns the number of records dropped because the buffer was full
+// or the Handler was closed.
+func (hc *handlerCore) Dropped() uint64 {
+	return hc.dropped.Load()
+}
+
+// Close stops accepting records and waits until the buffered ones have been
+// sent. It does not disconnect the client.
+func (hc *handlerCore) Close() error {
+	hc.lock.Lock()
+
+	if !hc.closed {
+		hc.closed = true
+		close(hc.queue)
+	}
+
+	hc.lock.Unlock()
+
+	hc.wg.Wait()
+
+	return nil
+}
+
+func (hc *handlerCore) enqueue(msg *protocol.MessageExt) {
+	hc.lock.RLock()
+	defer hc.lock.RUnlock()
+
+	if hc.closed {
+		hc.dropped.Add(1)
+		hc.error(ErrHandlerClosed)
+
+		return
+	}
+
+	select {
+	case hc.queue <- msg:
+	default:
+		hc.dropped.Add(1)
+		hc.error(fmt.Errorf("buffer full: dropped record %q", msg.Tag))
+	}
+}
+
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.opts.Level.Level()
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+
+	h2 := *h
+	h2.attrs = append([][]slog.Attr{}, h.attrs...)
+
+	i := len(h.groups)
+	h2.attrs[i] = append(append([]slog.Attr{}, h.attrs[i]...), resolveAttrs(attrs)...)
+
+	return &h2
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	h2 := *h
+	h2.groups = append(append([]string{}, h.groups...), name)
+	h2.attrs = append(append([][]slog.Attr{}, h.attrs...), nil)
+
+	return &h2
+}
+
+// Handle encodes the record and queues it for sending. It only returns an
+// error if the record cannot be encoded.
+func (h *Handler) Handle(_ context.Context, r slog.Record) error {
+	ts := r.Time
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+
+	var recAttrs []slog.Attr
+
+	if r.NumAttrs() > 0 {
+		recAttrs = make([]slog.Attr, 0, r.NumAttrs())
+		r.Attrs(func(a slog.Attr) bool {
+			recAttrs = append(recAttrs, a)
+			return true
+		})
+
+		recAttrs = resolveAttrs(recAttrs)
+	}
+
+	bits, err := h.encode(nil, r, recAttrs)
+	if err != nil {
+		return err
+	}
+
+	h.enqueue(&protocol.MessageExt{
+		Tag:       h.opts.Tag,
+		Timestamp: protocol.EventTime{Time: ts.UTC()},
+		Record:    msgp.Raw(bits),
+	})
+
+	return nil
+}
+
+// encode appends the record as a map.
+func (h *Handler) encode(b []byte, r slog.Record, recAttrs []slog.Attr) ([]byte, error) {
+	last := len(h.groups)
+
+	// counts[i] is the number of entries in the map at group depth i
+	counts := make([]int, last+1)
+	for i, attrs := range h.attrs {
+		counts[i] = countAttrs(attrs)
+	}
+
+	counts[last] += countAttrs(recAttrs)
+
+	// groups are omitted unless they, or a group within them, have entries
+	depth := last
+	for depth > 0 && counts[depth] == 0 {
+		depth--
+	}
+
+	for i := 0; i < depth; i++ {
+		counts[i]++
+	}
+
+	withSource := h.opts.AddSource && r.PC != 0
+	counts[0] += 2 + btoi(withSource)
+
+	b = msgp.AppendMapHeader(b, uint32(counts[0]))
+	b = msgp.AppendString(b, slog.LevelKey)
+	b = msgp.AppendString(b, r.Level.String())
+	b = msgp.AppendString(b, slog.MessageKey)
+	b = msgp.AppendString(b, r.Message)
+
+	if withSource {
+		b = appendSource(b, r.PC)
+	}
+
+	var err error
+
+	for i := 0; ; i++ {
+		if b, err = appendAttrs(b, h.attrs[i]); err != nil {
+			return b, err
+		}
+
+		if i == last {
+			return appendAttrs(b, recAttrs)
+		}
+
+		if i == depth {
+			return b, nil
+		}
+
+		b = msgp.AppendString(b, h.groups[i])
+		b = msgp.AppendMapHeader(b, uint32(counts[i+1]))
+	}
+}
+
+func btoi(b bool) int {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
+// resolveAttrs returns a copy of the attributes with every value, including
+// those within groups, resolved. Resolving once keeps the map sizes counted
+// by countAttrs consistent with what appendAttrs writes.
+func resolveAttrs(attrs []slog.Attr) []slog.Attr {
+	out := make([]slog.Attr, len(attrs))
+
+	for i, a := range attrs {
+		a.Value = a.Value.Resolve()
+
+		if a.Value.Kind() == slog.KindGroup {
+			a.Value = slog.GroupValue(resolveAttrs(a.Value.Group())...)
+		}
+
+		out[i] = a
+	}
+
+	return out
+}
+
+// countAttrs returns the number of map entries that appendAttrs writes.
+func countAttrs(attrs []slog.Attr) int {
+	n := 0
+
+	for _, a := range attrs {
+		switch {
+		case a.Equal(slog.Attr{}):
+		case a.Value.Kind() != slog.KindGroup:
+			n++
+		case a.Key == "":
+			n += countAttrs(a.Value.Group())
+		case countAttrs(a.Value.Group()) > 0:
+			n++
+		}
+	}
+
+	return n
+}
+
+// appendAttrs appends the attributes as map entries. Empty attributes and
+// empty groups are skipped, and groups without a key are inlined.
+func appendAttrs(b []byte, attrs []slog.Attr) ([]byte, error) {
+	var err error
+
+	for _, a := range attrs {
+		if a.Equal(slog.Attr{}) {
+			continue
+		}
+
+		if a.Value.Kind() == slog.KindGroup {
+			group := a.Value.Group()
+
+			if a.Key == "" {
+				if b, err = appendAttrs(b, group); err != nil {
+					return b, err
+				}
+
+				continue
+			}
+
+			n := countAttrs(group)
+			if n == 0 {
+				continue
+			}
+
+			b = msgp.AppendString(b, a.Key)
+			b = msgp.AppendMapHeader(b, uint32(n))
+
+			if b, err = appendAttrs(b, group); err != nil {
+				return b, err
+			}
+
+			continue
+		}
+
+		b = msgp.AppendString(b, a.Key)
+
+		if b, err = appendValue(b, a.Value); err != nil {
+			return b, fmt.Errorf("encode %q: %w", a.Key, err)
+		}
+	}
+
+	return b, nil
+}
+
+func appendValue(b []byte, v slog.Value) ([]byte, error) {
+	switch v.Kind() {
+	case slog.KindString:
+		return msgp.AppendString(b, v.String()), nil
+	case slog.KindInt64:
+		return msgp.AppendInt64(b, v.Int64()), nil
+	case slog.KindUint64:
+		return msgp.AppendUint64(b, v.Uint64()), nil
+	case slog.KindFloat64:
+		return msgp.AppendFloat64(b, v.Float64()), nil
+	case slog.KindBool:
+		return msgp.AppendBool(b, v.Bool()), nil
+	case slog.KindDuration:
+		return msgp.AppendInt64(b, int64(v.Duration())), nil
+	case slog.KindTime:
+		return msgp.AppendString(b, v.Time().Format(time.RFC3339Nano)), nil
+	}
+
+	switch x := v.Any().(type) {
+	case nil:
+		return msgp.AppendNil(b), nil
+	case msgp.Marshaler:
+		return x.MarshalMsg(b)
+	case error:
+		return msgp.AppendString(b, x.Error()), nil
+	case encoding.TextMarshaler:
+		text, err := x.MarshalText()
+		if err != nil {
+			return b, err
+		}
+
+		return msgp.AppendStringFromBytes(b, text), nil
+	case []byte:
+		return msgp.AppendBytes(b, x), nil
+	default:
+		if o, err := msgp.AppendIntf(b, x); err == nil {
+			return o, nil
+		}
+
+		return msgp.AppendString(b, fmt.Sprintf("%+v", x)), nil
+	}
+}
+
+func appendSource(b []byte, pc uintptr) []byte {
+	frames := runtime.CallersFrames([]uintptr{pc})
+	f, _ := frames.Next()
+
+	b = msgp.AppendString(b, slog.SourceKey)
+	b = msgp.AppendMapHeader(b, 3)
+	b = msgp.AppendString(b, "function")
+	b = msgp.AppendString(b, f.Function)
+	b = msgp.AppendString(b, "file")
+	b = msgp.AppendString(b, f.File)
+	b = msgp.AppendString(b, "line")
+	b = msgp.AppendInt(b, f.Line)
+
+	return b
+}
@@ -0,0 +1,226 @@
+//go:build go1.21
+
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package logging_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"testing/slogtest"
+	"time"
+
+	"github.com/IBM/fluent-forward-go/fluent/client/clientfakes"
+	. "github.com/IBM/fluent-forward-go/fluent/logging"
+	"github.com/IBM/fluent-forward-go/fluent/protocol"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// decodeRecord returns the record of a message sent by the Handler.
+func decodeRecord(e protocol.ChunkEncoder) (*protocol.MessageExt, map[string]interface{}) {
+	msg, ok := e.(*protocol.MessageExt)
+	Expect(ok).To(BeTrue())
+
+	raw, ok := msg.Record.(msgp.Raw)
+	Expect(ok).To(BeTrue())
+
+	v, _, err := msgp.ReadIntfBytes(raw)
+	Expect(err).ToNot(HaveOccurred())
+
+	return msg, v.(map[string]interface{})
+}
+
+var _ = Describe("Handler", func() {
+	var (
+		fake    *clientfakes.FakeMessageClient
+		opts    HandlerOptions
+		handler *Handler
+		errs    []error
+		lock    sync.Mutex
+	)
+
+	BeforeEach(func() {
+		fake = &clientfakes.FakeMessageClient{}
+		errs = nil
+		opts = HandlerOptions{
+			Tag: "app",
+			OnError: func(err error) {
+				lock.Lock()
+				defer lock.Unlock()
+
+				errs = append(errs, err)
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		handler = NewHandler(fake, opts)
+	})
+
+	records := func() []map[string]interface{} {
+		Expect(handler.Close()).To(Succeed())
+
+		out := make([]map[string]interface{}, fake.SendCallCount())
+		for i := range out {
+			msg, record := decodeRecord(fake.SendArgsForCall(i))
+			Expect(msg.Tag).To(Equal("app"))
+
+			out[i] = record
+		}
+
+		return out
+	}
+
+	It("passes the standard library handler tests", func() {
+		var sent []map[string]interface{}
+
+		err := slogtest.TestHandler(handler, func() []map[string]any {
+			sent = records()
+
+			// the time is sent as the event's timestamp, not in the record
+			for i, r := range sent {
+				if ts := fake.SendArgsForCall(i).(*protocol.MessageExt).Timestamp; !ts.IsZero() {
+					r[slog.TimeKey] = ts.Time
+				}
+			}
+
+			return sent
+		})
+
+		// every event needs a timestamp, so records without one are sent
+		// with the current time instead of none
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			for _, e := range joined.Unwrap() {
+				Expect(e).To(MatchError(ContainSubstring("should ignore a zero Record.Time")))
+			}
+		} else {
+			Expect(err).ToNot(HaveOccurred())
+		}
+	})
+
+	It("encodes levels, messages, groups and values", func() {
+		logger := slog.New(handler).With("service", "api").WithGroup("req")
+		logger.Warn("slow",
+			"took", time.Second,
+			"err", errors.New("nope"),
+			slog.Group("user", "id", 7, "admin", true),
+			slog.Group("empty"),
+		)
+
+		rs := records()
+		Expect(rs).To(HaveLen(1))
+		Expect(rs[0]).To(Equal(map[string]interface{}{
+			"level":   "WARN",
+			"msg":     "slow",
+			"service": "api",
+			"req": map[string]interface{}{
+				"took": int64(time.Second),
+				"err":  "nope",
+				"user": map[string]interface{}{"id": int64(7), "admin": true},
+			},
+		}))
+	})
+
+	It("omits groups without attributes", func() {
+		slog.New(handler).WithGroup("a").With("b", 1).WithGroup("c").Info("hi")
+
+		rs := records()
+		Expect(rs[0]).To(Equal(map[string]interface{}{
+			"level": "INFO",
+			"msg":   "hi",
+			"a":     map[string]interface{}{"b": int64(1)},
+		}))
+	})
+
+	It("skips records below the level", func() {
+		slog.New(handler).Debug("hi")
+		Expect(records()).To(BeEmpty())
+	})
+
+	When("AddSource is set", func() {
+		BeforeEach(func() {
+			opts.AddSource = true
+		})
+
+		It("adds the source of the logging call", func() {
+			slog.New(handler).Info("hi")
+
+			source := records()[0]["source"]
+			Expect(source).To(HaveKeyWithValue("file", ContainSubstring("handler_test.go")))
+			Expect(source).To(HaveKey("line"))
+		})
+	})
+
+	When("the client fails", func() {
+		BeforeEach(func() {
+			fake.SendReturns(errors.New("nope"))
+		})
+
+		It("reports the error", func() {
+			slog.New(handler).Info("hi")
+			Expect(handler.Close()).To(Succeed())
+
+			Expect(errs).To(ConsistOf(MatchError("nope")))
+		})
+	})
+
+	When("the buffer is full", func() {
+		var release chan struct{}
+
+		BeforeEach(func() {
+			opts.BufferSize = 1
+			release = make(chan struct{})
+			fake.SendCalls(func(protocol.ChunkEncoder) error {
+				<-release
+				return nil
+			})
+		})
+
+		It("drops records instead of blocking", func() {
+			logger := slog.New(handler)
+			for i := 0; i < 5; i++ {
+				logger.Info("hi")
+			}
+
+			Expect(handler.Dropped()).To(BeNumerically(">=", 3))
+
+			close(release)
+			Expect(handler.Close()).To(Succeed())
+			Expect(uint64(fake.SendCallCount()) + handler.Dropped()).To(BeEquivalentTo(5))
+		})
+	})
+
+	It("drops records logged after Close", func() {
+		Expect(handler.Close()).To(Succeed())
+
+		Expect(handler.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "hi", 0))).To(Succeed())
+		Expect(handler.Dropped()).To(BeEquivalentTo(1))
+		Expect(errs).To(ConsistOf(MatchError(ErrHandlerClosed)))
+	})
+})
@@ -0,0 +1,295 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+// Package logging ships application logs to Fluent endpoints through any
+// client.MessageClient.
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/IBM/fluent-forward-go/fluent/client"
+	"github.com/IBM/fluent-forward-go/fluent/protocol"
+)
+
+const (
+	DefaultLogKey                            = "log"
+	DefaultMaxLineLength                     = 16 * 1024
+	DefaultWriterBatchSize                   = 100
+	DefaultWriterFlushInterval time.Duration = time.Second
+)
+
+// ErrWriterClosed is returned by a Writer after Close is called.
+var ErrWriterClosed = errors.New("writer closed")
+
+// WriterOptions configures a Writer.
+type WriterOptions struct {
+	// Tag is the tag of every message.
+	Tag string
+	// Key is the record key that holds each line. The default is
+	// DefaultLogKey.
+	Key string
+	// MaxLineLength is the longest line, in bytes, that is sent in a single
+	// record. Longer lines are split. The default is DefaultMaxLineLength.
+	MaxLineLength int
+	// ParseJSON sends lines that hold a JSON object as that object instead
+	// of under Key.
+	ParseJSON bool
+	// BatchSize is the number of lines that triggers a flush. The default is
+	// DefaultWriterBatchSize.
+	BatchSize int
+	// FlushInterval is the longest a line waits in the batch before it is
+	// flushed. The default is DefaultWriterFlushInterval. A negative value
+	// disables timed flushes.
+	FlushInterval time.Duration
+	// Packed sends each batch as a PackedForwardMessage instead of a
+	// ForwardMessage.
+	Packed bool
+}
+
+// Writer is an io.Writer that sends every line written to it as a record,
+// for libraries that only accept an io.Writer for their logs. Lines may be
+// split across calls to Write; a trailing partial line is held until its
+// newline arrives or Close is called. Empty lines are skipped. It is safe to
+// use a Writer from multiple goroutines.
+type Writer struct {
+	client  client.MessageClient
+	opts    WriterOptions
+	line    []byte
+	entries protocol.EntryList
+	timer   *time.Timer
+	err     error
+	closed  bool
+	lock    sync.Mutex
+}
+
+// NewWriter returns a Writer that sends through the client. The client must
+// be connected before the first flush.
+func NewWriter(c client.MessageClient, opts WriterOptions) *Writer {
+	if opts.Key == "" {
+		opts.Key = DefaultLogKey
+	}
+
+	if opts.MaxLineLength <= 0 {
+		opts.MaxLineLength = DefaultMaxLineLength
+	}
+
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultWriterBatchSize
+	}
+
+	if opts.FlushInterval == 0 {
+		opts.FlushInterval = DefaultWriterFlushInterval
+	}
+
+	return &Writer{
+		client: c,
+		opts:   opts,
+	}
+}
+
+// Write splits p into lines and adds them to the batch. It returns any
+// error from a previous timed flush, or from a flush that p triggered; the
+// count is the number of bytes consumed before the error.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if err := w.checkState(); err != nil {
+		return 0, err
+	}
+
+	n := len(p)
+
+	for len(p) > 0 {
+		end := bytes.IndexByte(p, '\n')
+
+		if room := w.opts.MaxLineLength - len(w.line); end < 0 && len(p) < room {
+			// a partial line
+			w.line = append(w.line, p...)
+			break
+		} else if end < 0 || end > room {
+			// a line that is too long is split
+			w.line = append(w.line, p[:room]...)
+			p = p[room:]
+		} else {
+			w.line = append(w.line, p[:end]...)
+			p = p[end+1:]
+		}
+
+		if err := w.emit(); err != nil {
+			return n - len(p), err
+		}
+	}
+
+	return n, nil
+}
+
+// Flush sends the batched lines. A trailing partial line is not sent.
+func (w *Writer) Flush() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if err := w.checkState(); err != nil {
+		return err
+	}
+
+	return w.flush()
+}
+
+// Close sends the batched lines, including a trailing partial line. It does
+// not disconnect the client.
+func (w *Writer) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.closed {
+		return nil
+	}
+
+	err := w.err
+
+	if len(w.line) > 0 {
+		w.addLine()
+	}
+
+	if ferr := w.flush(); err == nil {
+		err = ferr
+	}
+
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+
+	w.closed = true
+
+	return err
+}
+
+// checkState returns an error if the writer can no longer be used, or if a
+// timed flush failed since the last call.
+func (w *Writer) checkState() error {
+	if w.closed {
+		return ErrWriterClosed
+	}
+
+	err := w.err
+	w.err = nil
+
+	return err
+}
+
+// emit adds the current line to the batch and flushes the batch if it is
+// full.
+func (w *Writer) emit() error {
+	w.addLine()
+
+	if len(w.entries) == 1 && w.opts.FlushInterval > 0 {
+		if w.timer == nil {
+			w.timer = time.AfterFunc(w.opts.FlushInterval, w.timedFlush)
+		} else {
+			w.timer.Reset(w.opts.FlushInterval)
+		}
+	}
+
+	if len(w.entries) >= w.opts.BatchSize {
+		return w.flush()
+	}
+
+	return nil
+}
+
+func (w *Writer) addLine() {
+	line := bytes.TrimSuffix(w.line, []byte{'\r'})
+	w.line = w.line[:0]
+
+	if len(line) == 0 {
+		return
+	}
+
+	w.entries = append(w.entries, protocol.EntryExt{
+		Timestamp: protocol.EventTimeNow(),
+		Record:    w.record(line),
+	})
+}
+
+func (w *Writer) record(line []byte) interface{} {
+	if w.opts.ParseJSON {
+		if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 && trimmed[0] == '{' {
+			var m map[string]interface{}
+			if json.Unmarshal(trimmed, &m) == nil {
+				return m
+			}
+		}
+	}
+
+	return map[string]interface{}{w.opts.Key: string(line)}
+}
+
+func (w *Writer) timedFlush() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.closed || len(w.entries) == 0 {
+		return
+	}
+
+	if err := w.flush(); err != nil {
+		w.err = err
+	}
+}
+
+// flush sends the batch. If sending fails, the batch is discarded and the
+// error is returned.
+func (w *Writer) flush() error {
+	if len(w.entries) == 0 {
+		return nil
+	}
+
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+
+	entries := w.entries
+	w.entries = nil
+
+	var msg protocol.ChunkEncoder
+
+	if w.opts.Packed {
+		pfm, err := protocol.NewPackedForwardMessage(w.opts.Tag, entries)
+		if err != nil {
+			return err
+		}
+
+		msg = pfm
+	} else {
+		msg = protocol.NewForwardMessage(w.opts.Tag, entries)
+	}
+
+	return w.client.Send(msg)
+}
@@ -0,0 +1,190 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package logging_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/IBM/fluent-forward-go/fluent/client/clientfakes"
+	. "github.com/IBM/fluent-forward-go/fluent/logging"
+	"github.com/IBM/fluent-forward-go/fluent/protocol"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Writer", func() {
+	var (
+		fake   *clientfakes.FakeMessageClient
+		opts   WriterOptions
+		writer *Writer
+	)
+
+	BeforeEach(func() {
+		fake = &clientfakes.FakeMessageClient{}
+		opts = WriterOptions{
+			Tag:           "app",
+			FlushInterval: -1,
+		}
+	})
+
+	JustBeforeEach(func() {
+		writer = NewWriter(fake, opts)
+	})
+
+	// records returns the records of every message sent so far.
+	records := func() []interface{} {
+		var out []interface{}
+
+		for i := 0; i < fake.SendCallCount(); i++ {
+			msg, ok := fake.SendArgsForCall(i).(*protocol.ForwardMessage)
+			Expect(ok).To(BeTrue())
+			Expect(msg.Tag).To(Equal("app"))
+
+			for _, e := range msg.Entries {
+				out = append(out, e.Record)
+			}
+		}
+
+		return out
+	}
+
+	It("sends every line as a record", func() {
+		n, err := fmt.Fprint(writer, "one\ntw")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n).To(Equal(6))
+
+		_, _ = fmt.Fprint(writer, "o\r\n\nthree")
+		Expect(writer.Flush()).To(Succeed())
+
+		Expect(records()).To(Equal([]interface{}{
+			map[string]interface{}{"log": "one"},
+			map[string]interface{}{"log": "two"},
+		}))
+
+		Expect(writer.Close()).To(Succeed())
+		Expect(records()).To(HaveLen(3))
+		Expect(records()[2]).To(Equal(map[string]interface{}{"log": "three"}))
+	})
+
+	It("sends a batch when it is full", func() {
+		opts.BatchSize = 2
+		writer = NewWriter(fake, opts)
+
+		_, _ = fmt.Fprint(writer, "1\n2\n3\n")
+		Expect(fake.SendCallCount()).To(Equal(1))
+		Expect(fake.SendArgsForCall(0).(*protocol.ForwardMessage).Entries).To(HaveLen(2))
+	})
+
+	When("MaxLineLength is set", func() {
+		BeforeEach(func() {
+			opts.MaxLineLength = 4
+		})
+
+		It("splits long lines", func() {
+			_, _ = fmt.Fprint(writer, "abcdefghij\nabcd\n")
+			Expect(writer.Close()).To(Succeed())
+
+			Expect(records()).To(Equal([]interface{}{
+				map[string]interface{}{"log": "abcd"},
+				map[string]interface{}{"log": "efgh"},
+				map[string]interface{}{"log": "ij"},
+				map[string]interface{}{"log": "abcd"},
+			}))
+		})
+	})
+
+	When("ParseJSON and Key are set", func() {
+		BeforeEach(func() {
+			opts.ParseJSON = true
+			opts.Key = "message"
+		})
+
+		It("sends JSON objects as maps", func() {
+			_, _ = fmt.Fprint(writer, `{"level":"info","n":1}`+"\n[1]\nplain\n")
+			Expect(writer.Close()).To(Succeed())
+
+			Expect(records()).To(Equal([]interface{}{
+				map[string]interface{}{"level": "info", "n": float64(1)},
+				map[string]interface{}{"message": "[1]"},
+				map[string]interface{}{"message": "plain"},
+			}))
+		})
+	})
+
+	When("Packed is set", func() {
+		BeforeEach(func() {
+			opts.Packed = true
+		})
+
+		It("sends a PackedForwardMessage", func() {
+			_, _ = fmt.Fprint(writer, "one\ntwo\n")
+			Expect(writer.Close()).To(Succeed())
+
+			msg, ok := fake.SendArgsForCall(0).(*protocol.PackedForwardMessage)
+			Expect(ok).To(BeTrue())
+			Expect(*msg.Options.Size).To(Equal(2))
+		})
+	})
+
+	When("FlushInterval is set", func() {
+		BeforeEach(func() {
+			opts.FlushInterval = 10 * time.Millisecond
+		})
+
+		It("flushes on a timer", func() {
+			_, _ = fmt.Fprint(writer, "one\n")
+			Eventually(fake.SendCallCount).Should(Equal(1))
+		})
+
+		It("returns a timed flush error on the next call", func() {
+			fake.SendReturns(errors.New("nope"))
+
+			_, _ = fmt.Fprint(writer, "one\n")
+			Eventually(fake.SendCallCount).Should(Equal(1))
+
+			_, err := fmt.Fprint(writer, "two\n")
+			Expect(err).To(MatchError("nope"))
+		})
+	})
+
+	It("returns send errors", func() {
+		fake.SendReturns(errors.New("nope"))
+
+		_, _ = fmt.Fprint(writer, "one\n")
+		Expect(writer.Flush()).To(MatchError("nope"))
+	})
+
+	It("cannot be used after Close", func() {
+		Expect(writer.Close()).To(Succeed())
+		Expect(writer.Close()).To(Succeed())
+
+		_, err := writer.Write([]byte(strings.Repeat("a", 3)))
+		Expect(err).To(MatchError(ErrWriterClosed))
+		Expect(fake.SendCallCount()).To(Equal(0))
+	})
+})
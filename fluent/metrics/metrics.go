@@ -0,0 +1,74 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+// Package metrics defines the measurements that the Fluent clients record
+// and provides a Recorder that exposes them in the Prometheus text format.
+package metrics
+
+import (
+	"time"
+)
+
+//go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 -generate
+
+// SendStats describes a single completed send.
+type SendStats struct {
+	// Endpoint identifies the server, e.g. its address or URL.
+	Endpoint string
+	// Tag is the message's tag. It is empty for raw messages.
+	Tag string
+	// Events is the number of events in the message, if known.
+	Events int
+	// Bytes is the number of bytes written.
+	Bytes int
+	// Chunked is true if the message requested an ack.
+	Chunked bool
+	// Latency includes the time spent waiting for the ack.
+	Latency time.Duration
+	// Err is the error returned to the caller, if any.
+	Err error
+}
+
+// Recorder receives measurements from the clients. Implementations must be
+// safe for concurrent use and must return quickly.
+//
+//counterfeiter:generate . Recorder
+type Recorder interface {
+	// Sent is called after every send.
+	Sent(stats SendStats)
+	// AckReceived is called when an expected ack arrives. The rtt is
+	// measured from the end of the write.
+	AckReceived(endpoint string, rtt time.Duration)
+	// AckTimedOut is called when an ack does not arrive in time.
+	AckTimedOut(endpoint string)
+	// Handshake is called after every shared key handshake.
+	Handshake(endpoint string, duration time.Duration, err error)
+	// Reconnected is called after every reconnection attempt.
+	Reconnected(endpoint string, err error)
+	// Compressed is called when the client gzips an event stream, which
+	// only the TCP client's SendCompressed methods do. Websocket
+	// permessage-deflate compression happens inside the websocket library
+	// and is not reported.
+	Compressed(endpoint, tag string, rawBytes, compressedBytes int)
+}
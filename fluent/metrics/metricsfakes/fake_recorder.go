@@ -0,0 +1,286 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package metricsfakes
+
+import (
+	"sync"
+	"time"
+
+	"github.com/IBM/fluent-forward-go/fluent/metrics"
+)
+
+type FakeRecorder struct {
+	AckReceivedStub        func(string, time.Duration)
+	ackReceivedMutex       sync.RWMutex
+	ackReceivedArgsForCall []struct {
+		arg1 string
+		arg2 time.Duration
+	}
+	AckTimedOutStub        func(string)
+	ackTimedOutMutex       sync.RWMutex
+	ackTimedOutArgsForCall []struct {
+		arg1 string
+	}
+	CompressedStub        func(string, string, int, int)
+	compressedMutex       sync.RWMutex
+	compressedArgsForCall []struct {
+		arg1 string
+		arg2 string
+		arg3 int
+		arg4 int
+	}
+	HandshakeStub        func(string, time.Duration, error)
+	handshakeMutex       sync.RWMutex
+	handshakeArgsForCall []struct {
+		arg1 string
+		arg2 time.Duration
+		arg3 error
+	}
+	ReconnectedStub        func(string, error)
+	reconnectedMutex       sync.RWMutex
+	reconnectedArgsForCall []struct {
+		arg1 string
+		arg2 error
+	}
+	SentStub        func(metrics.SendStats)
+	sentMutex       sync.RWMutex
+	sentArgsForCall []struct {
+		arg1 metrics.SendStats
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeRecorder) AckReceived(arg1 string, arg2 time.Duration) {
+	fake.ackReceivedMutex.Lock()
+	fake.ackReceivedArgsForCall = append(fake.ackReceivedArgsForCall, struct {
+		arg1 string
+		arg2 time.Duration
+	}{arg1, arg2})
+	stub := fake.AckReceivedStub
+	fake.recordInvocation("AckReceived", []interface{}{arg1, arg2})
+	fake.ackReceivedMutex.Unlock()
+	if stub != nil {
+		fake.AckReceivedStub(arg1, arg2)
+	}
+}
+
+func (fake *FakeRecorder) AckReceivedCallCount() int {
+	fake.ackReceivedMutex.RLock()
+	defer fake.ackReceivedMutex.RUnlock()
+	return len(fake.ackReceivedArgsForCall)
+}
+
+func (fake *FakeRecorder) AckReceivedCalls(stub func(string, time.Duration)) {
+	fake.ackReceivedMutex.Lock()
+	defer fake.ackReceivedMutex.Unlock()
+	fake.AckReceivedStub = stub
+}
+
+func (fake *FakeRecorder) AckReceivedArgsForCall(i int) (string, time.Duration) {
+	fake.ackReceivedMutex.RLock()
+	defer fake.ackReceivedMutex.RUnlock()
+	argsForCall := fake.ackReceivedArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeRecorder) AckTimedOut(arg1 string) {
+	fake.ackTimedOutMutex.Lock()
+	fake.ackTimedOutArgsForCall = append(fake.ackTimedOutArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.AckTimedOutStub
+	fake.recordInvocation("AckTimedOut", []interface{}{arg1})
+	fake.ackTimedOutMutex.Unlock()
+	if stub != nil {
+		fake.AckTimedOutStub(arg1)
+	}
+}
+
+func (fake *FakeRecorder) AckTimedOutCallCount() int {
+	fake.ackTimedOutMutex.RLock()
+	defer fake.ackTimedOutMutex.RUnlock()
+	return len(fake.ackTimedOutArgsForCall)
+}
+
+func (fake *FakeRecorder) AckTimedOutCalls(stub func(string)) {
+	fake.ackTimedOutMutex.Lock()
+	defer fake.ackTimedOutMutex.Unlock()
+	fake.AckTimedOutStub = stub
+}
+
+func (fake *FakeRecorder) AckTimedOutArgsForCall(i int) string {
+	fake.ackTimedOutMutex.RLock()
+	defer fake.ackTimedOutMutex.RUnlock()
+	argsForCall := fake.ackTimedOutArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeRecorder) Compressed(arg1 string, arg2 string, arg3 int, arg4 int) {
+	fake.compressedMutex.Lock()
+	fake.compressedArgsForCall = append(fake.compressedArgsForCall, struct {
+		arg1 string
+		arg2 string
+		arg3 int
+		arg4 int
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.CompressedStub
+	fake.recordInvocation("Compressed", []interface{}{arg1, arg2, arg3, arg4})
+	fake.compressedMutex.Unlock()
+	if stub != nil {
+		fake.CompressedStub(arg1, arg2, arg3, arg4)
+	}
+}
+
+func (fake *FakeRecorder) CompressedCallCount() int {
+	fake.compressedMutex.RLock()
+	defer fake.compressedMutex.RUnlock()
+	return len(fake.compressedArgsForCall)
+}
+
+func (fake *FakeRecorder) CompressedCalls(stub func(string, string, int, int)) {
+	fake.compressedMutex.Lock()
+	defer fake.compressedMutex.Unlock()
+	fake.CompressedStub = stub
+}
+
+func (fake *FakeRecorder) CompressedArgsForCall(i int) (string, string, int, int) {
+	fake.compressedMutex.RLock()
+	defer fake.compressedMutex.RUnlock()
+	argsForCall := fake.compressedArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeRecorder) Handshake(arg1 string, arg2 time.Duration, arg3 error) {
+	fake.handshakeMutex.Lock()
+	fake.handshakeArgsForCall = append(fake.handshakeArgsForCall, struct {
+		arg1 string
+		arg2 time.Duration
+		arg3 error
+	}{arg1, arg2, arg3})
+	stub := fake.HandshakeStub
+	fake.recordInvocation("Handshake", []interface{}{arg1, arg2, arg3})
+	fake.handshakeMutex.Unlock()
+	if stub != nil {
+		fake.HandshakeStub(arg1, arg2, arg3)
+	}
+}
+
+func (fake *FakeRecorder) HandshakeCallCount() int {
+	fake.handshakeMutex.RLock()
+	defer fake.handshakeMutex.RUnlock()
+	return len(fake.handshakeArgsForCall)
+}
+
+func (fake *FakeRecorder) HandshakeCalls(stub func(string, time.Duration, error)) {
+	fake.handshakeMutex.Lock()
+	defer fake.handshakeMutex.Unlock()
+	fake.HandshakeStub = stub
+}
+
+func (fake *FakeRecorder) HandshakeArgsForCall(i int) (string, time.Duration, error) {
+	fake.handshakeMutex.RLock()
+	defer fake.handshakeMutex.RUnlock()
+	argsForCall := fake.handshakeArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeRecorder) Reconnected(arg1 string, arg2 error) {
+	fake.reconnectedMutex.Lock()
+	fake.reconnectedArgsForCall = append(fake.reconnectedArgsForCall, struct {
+		arg1 string
+		arg2 error
+	}{arg1, arg2})
+	stub := fake.ReconnectedStub
+	fake.recordInvocation("Reconnected", []interface{}{arg1, arg2})
+	fake.reconnectedMutex.Unlock()
+	if stub != nil {
+		fake.ReconnectedStub(arg1, arg2)
+	}
+}
+
+func (fake *FakeRecorder) ReconnectedCallCount() int {
+	fake.reconnectedMutex.RLock()
+	defer fake.reconnectedMutex.RUnlock()
+	return len(fake.reconnectedArgsForCall)
+}
+
+func (fake *FakeRecorder) ReconnectedCalls(stub func(string, error)) {
+	fake.reconnectedMutex.Lock()
+	defer fake.reconnectedMutex.Unlock()
+	fake.ReconnectedStub = stub
+}
+
+func (fake *FakeRecorder) ReconnectedArgsForCall(i int) (string, error) {
+	fake.reconnectedMutex.RLock()
+	defer fake.reconnectedMutex.RUnlock()
+	argsForCall := fake.reconnectedArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeRecorder) Sent(arg1 metrics.SendStats) {
+	fake.sentMutex.Lock()
+	fake.sentArgsForCall = append(fake.sentArgsForCall, struct {
+		arg1 metrics.SendStats
+	}{arg1})
+	stub := fake.SentStub
+	fake.recordInvocation("Sent", []interface{}{arg1})
+	fake.sentMutex.Unlock()
+	if stub != nil {
+		fake.SentStub(arg1)
+	}
+}
+
+func (fake *FakeRecorder) SentCallCount() int {
+	fake.sentMutex.RLock()
+	defer fake.sentMutex.RUnlock()
+	return len(fake.sentArgsForCall)
+}
+
+func (fake *FakeRecorder) SentCalls(stub func(metrics.SendStats)) {
+	fake.sentMutex.Lock()
+	defer fake.sentMutex.Unlock()
+	fake.SentStub = stub
+}
+
+func (fake *FakeRecorder) SentArgsForCall(i int) metrics.SendStats {
+	fake.sentMutex.RLock()
+	defer fake.sentMutex.RUnlock()
+	argsForCall := fake.sentArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeRecorder) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.ackReceivedMutex.RLock()
+	defer fake.ackReceivedMutex.RUnlock()
+	fake.ackTimedOutMutex.RLock()
+	defer fake.ackTimedOutMutex.RUnlock()
+	fake.compressedMutex.RLock()
+	defer fake.compressedMutex.RUnlock()
+	fake.handshakeMutex.RLock()
+	defer fake.handshakeMutex.RUnlock()
+	fake.reconnectedMutex.RLock()
+	defer fake.reconnectedMutex.RUnlock()
+	fake.sentMutex.RLock()
+	defer fake.sentMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeRecorder) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ metrics.Recorder = new(FakeRecorder)
@@ -0,0 +1,375 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package metrics
+
+import (
+	"bufio"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultNamespace = "fluent"
+
+	prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+var (
+	// DefaultLatencyBuckets are the upper bounds, in seconds, of the latency
+	// histograms.
+	DefaultLatencyBuckets = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+	// DefaultCompressionBuckets are the upper bounds of the compression
+	// ratio histogram, where the ratio is raw bytes / compressed bytes.
+	DefaultCompressionBuckets = []float64{1, 1.5, 2, 3, 4, 6, 8, 12, 16, 24, 32}
+)
+
+// PrometheusOptions configures a PrometheusRecorder.
+type PrometheusOptions struct {
+	// Namespace prefixes every metric name. The default is
+	// DefaultNamespace.
+	Namespace string
+	// LatencyBuckets overrides DefaultLatencyBuckets.
+	LatencyBuckets []float64
+	// CompressionBuckets overrides DefaultCompressionBuckets.
+	CompressionBuckets []float64
+	// OmitTags drops the tag label, which keeps the number of series small
+	// when clients send many distinct tags.
+	OmitTags bool
+}
+
+// PrometheusRecorder is a Recorder that keeps its measurements in memory and
+// serves them in the Prometheus text exposition format. It has no
+// dependencies outside of the standard library.
+type PrometheusRecorder struct {
+	omitTags bool
+	families []*family
+
+	messages    *family
+	events      *family
+	bytes       *family
+	chunks      *family
+	acks        *family
+	ackTimeouts *family
+	handshakes  *family
+	reconnects  *family
+	sendLatency *family
+	ackRTT      *family
+	handshakeD  *family
+	compression *family
+
+	lock sync.Mutex
+}
+
+// NewPrometheusRecorder returns a PrometheusRecorder. Mount it on an HTTP
+// server, usually at "/metrics", to expose the measurements.
+func NewPrometheusRecorder(opts PrometheusOptions) *PrometheusRecorder {
+	ns := opts.Namespace
+	if ns == "" {
+		ns = DefaultNamespace
+	}
+
+	latency := opts.LatencyBuckets
+	if latency == nil {
+		latency = DefaultLatencyBuckets
+	}
+
+	compression := opts.CompressionBuckets
+	if compression == nil {
+		compression = DefaultCompressionBuckets
+	}
+
+	tagLabels := []string{"endpoint", "tag"}
+	if opts.OmitTags {
+		tagLabels = []string{"endpoint"}
+	}
+
+	resultLabels := append(append([]string{}, tagLabels...), "result")
+
+	p := &PrometheusRecorder{omitTags: opts.OmitTags}
+
+	add := func(f *family) *family {
+		f.name = ns + "_" + f.name
+		f.series = map[string]*series{}
+		p.families = append(p.families, f)
+
+		return f
+	}
+
+	p.messages = add(&family{name: "client_messages_total", help: "Messages sent, by result.", typ: "counter", labels: resultLabels})
+	p.events = add(&family{name: "client_events_total", help: "Events sent successfully.", typ: "counter", labels: tagLabels})
+	p.bytes = add(&family{name: "client_bytes_total", help: "Bytes written.", typ: "counter", labels: tagLabels})
+	p.chunks = add(&family{name: "client_chunks_total", help: "Messages sent that requested an ack.", typ: "counter", labels: []string{"endpoint"}})
+	p.acks = add(&family{name: "client_acks_total", help: "Acks received.", typ: "counter", labels: []string{"endpoint"}})
+	p.ackTimeouts = add(&family{name: "client_ack_timeouts_total", help: "Acks that did not arrive in time.", typ: "counter", labels: []string{"endpoint"}})
+	p.handshakes = add(&family{name: "client_handshakes_total", help: "Shared key handshakes, by result.", typ: "counter", labels: []string{"endpoint", "result"}})
+	p.reconnects = add(&family{name: "client_reconnects_total", help: "Reconnection attempts, by result.", typ: "counter", labels: []string{"endpoint", "result"}})
+	p.sendLatency = add(&family{name: "client_send_duration_seconds", help: "Time to send a message, including the wait for its ack.", typ: "histogram", labels: tagLabels, buckets: latency})
+	p.ackRTT = add(&family{name: "client_ack_rtt_seconds", help: "Time from the end of a write until its ack arrives.", typ: "histogram", labels: []string{"endpoint"}, buckets: latency})
+	p.handshakeD = add(&family{name: "client_handshake_duration_seconds", help: "Time to complete a shared key handshake.", typ: "histogram", labels: []string{"endpoint"}, buckets: latency})
+	p.compression = add(&family{name: "client_compression_ratio", help: "Ratio of raw to compressed event stream size.", typ: "histogram", labels: tagLabels, buckets: compression})
+
+	return p
+}
+
+func result(err error) string {
+	if err != nil {
+		return "error"
+	}
+
+	return "ok"
+}
+
+func (p *PrometheusRecorder) tagValues(endpoint, tag string) []string {
+	if p.omitTags {
+		return []string{endpoint}
+	}
+
+	return []string{endpoint, tag}
+}
+
+func (p *PrometheusRecorder) Sent(stats SendStats) {
+	values := p.tagValues(stats.Endpoint, stats.Tag)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.messages.get(append(values, result(stats.Err))).add(1)
+	p.bytes.get(values).add(float64(stats.Bytes))
+	p.sendLatency.get(values).observe(stats.Latency.Seconds())
+
+	if stats.Err == nil {
+		p.events.get(values).add(float64(stats.Events))
+	}
+
+	if stats.Chunked {
+		p.chunks.get([]string{stats.Endpoint}).add(1)
+	}
+}
+
+func (p *PrometheusRecorder) AckReceived(endpoint string, rtt time.Duration) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.acks.get([]string{endpoint}).add(1)
+	p.ackRTT.get([]string{endpoint}).observe(rtt.Seconds())
+}
+
+func (p *PrometheusRecorder) AckTimedOut(endpoint string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.ackTimeouts.get([]string{endpoint}).add(1)
+}
+
+func (p *PrometheusRecorder) Handshake(endpoint string, duration time.Duration, err error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.handshakes.get([]string{endpoint, result(err)}).add(1)
+	p.handshakeD.get([]string{endpoint}).observe(duration.Seconds())
+}
+
+func (p *PrometheusRecorder) Reconnected(endpoint string, err error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.reconnects.get([]string{endpoint, result(err)}).add(1)
+}
+
+func (p *PrometheusRecorder) Compressed(endpoint, tag string, rawBytes, compressedBytes int) {
+	if compressedBytes <= 0 {
+		return
+	}
+
+	values := p.tagValues(endpoint, tag)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.compression.get(values).observe(float64(rawBytes) / float64(compressedBytes))
+}
+
+// ServeHTTP writes every metric in the Prometheus text exposition format.
+func (p *PrometheusRecorder) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", prometheusContentType)
+
+	bw := bufio.NewWriter(w)
+
+	p.lock.Lock()
+	for _, f := range p.families {
+		f.write(bw)
+	}
+	p.lock.Unlock()
+
+	_ = bw.Flush()
+}
+
+type family struct {
+	name    string
+	help    string
+	typ     string
+	labels  []string
+	buckets []float64
+	series  map[string]*series
+}
+
+type series struct {
+	values []string
+	value  float64
+	bounds []float64
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func (f *family) get(values []string) *series {
+	key := strings.Join(values, "\xff")
+
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{
+			values: values,
+			bounds: f.buckets,
+			counts: make([]uint64, len(f.buckets)),
+		}
+
+		f.series[key] = s
+	}
+
+	return s
+}
+
+func (s *series) add(v float64) {
+	s.value += v
+}
+
+func (s *series) observe(v float64) {
+	for i, bound := range s.bounds {
+		if v <= bound {
+			s.counts[i]++
+		}
+	}
+
+	s.sum += v
+	s.count++
+}
+
+func (f *family) write(w *bufio.Writer) {
+	if len(f.series) == 0 {
+		return
+	}
+
+	w.WriteString("# HELP " + f.name + " " + f.help + "\n")
+	w.WriteString("# TYPE " + f.name + " " + f.typ + "\n")
+
+	keys := make([]string, 0, len(f.series))
+	for k := range f.series {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		s := f.series[k]
+		labels := f.formatLabels(s.values)
+
+		if f.buckets == nil {
+			writeSample(w, f.name, labels, "", formatFloat(s.value))
+			continue
+		}
+
+		for i, bound := range f.buckets {
+			writeSample(w, f.name+"_bucket", labels, formatFloat(bound), strconv.FormatUint(s.counts[i], 10))
+		}
+
+		writeSample(w, f.name+"_bucket", labels, "+Inf", strconv.FormatUint(s.count, 10))
+		writeSample(w, f.name+"_sum", labels, "", formatFloat(s.sum))
+		writeSample(w, f.name+"_count", labels, "", strconv.FormatUint(s.count, 10))
+	}
+}
+
+func (f *family) formatLabels(values []string) string {
+	var sb strings.Builder
+
+	for i, name := range f.labels {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+
+		sb.WriteString(name)
+		sb.WriteString(`="`)
+		sb.WriteString(escapeLabel(values[i]))
+		sb.WriteByte('"')
+	}
+
+	return sb.String()
+}
+
+func writeSample(w *bufio.Writer, name, labels, le, value string) {
+	w.WriteString(name)
+
+	if labels != "" || le != "" {
+		w.WriteByte('{')
+		w.WriteString(labels)
+
+		if le != "" {
+			if labels != "" {
+				w.WriteByte(',')
+			}
+
+			w.WriteString(`le="` + le + `"`)
+		}
+
+		w.WriteByte('}')
+	}
+
+	w.WriteByte(' ')
+	w.WriteString(value)
+	w.WriteByte('\n')
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
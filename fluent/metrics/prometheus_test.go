@@ -0,0 +1,141 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package metrics_test
+
+import (
+	"errors"
+	"net/http/httptest"
+	"time"
+
+	"github.com/IBM/fluent-forward-go/fluent/metrics"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PrometheusRecorder", func() {
+	var (
+		p *metrics.PrometheusRecorder
+	)
+
+	BeforeEach(func() {
+		p = metrics.NewPrometheusRecorder(metrics.PrometheusOptions{
+			LatencyBuckets: []float64{0.1, 1},
+		})
+	})
+
+	scrape := func() string {
+		rec := httptest.NewRecorder()
+		p.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+		Expect(rec.Header().Get("Content-Type")).To(HavePrefix("text/plain; version=0.0.4"))
+
+		return rec.Body.String()
+	}
+
+	It("renders nothing before anything is recorded", func() {
+		Expect(scrape()).To(BeEmpty())
+	})
+
+	It("renders send counters and latency histograms", func() {
+		p.Sent(metrics.SendStats{
+			Endpoint: "localhost:24224",
+			Tag:      "foo",
+			Events:   3,
+			Bytes:    100,
+			Chunked:  true,
+			Latency:  500 * time.Millisecond,
+		})
+		p.Sent(metrics.SendStats{
+			Endpoint: "localhost:24224",
+			Tag:      "foo",
+			Events:   2,
+			Bytes:    10,
+			Latency:  50 * time.Millisecond,
+			Err:      errors.New("nope"),
+		})
+
+		out := scrape()
+		Expect(out).To(ContainSubstring("# TYPE fluent_client_messages_total counter\n"))
+		Expect(out).To(ContainSubstring(`fluent_client_messages_total{endpoint="localhost:24224",tag="foo",result="error"} 1` + "\n"))
+		Expect(out).To(ContainSubstring(`fluent_client_messages_total{endpoint="localhost:24224",tag="foo",result="ok"} 1` + "\n"))
+		Expect(out).To(ContainSubstring(`fluent_client_events_total{endpoint="localhost:24224",tag="foo"} 3` + "\n"))
+		Expect(out).To(ContainSubstring(`fluent_client_bytes_total{endpoint="localhost:24224",tag="foo"} 110` + "\n"))
+		Expect(out).To(ContainSubstring(`fluent_client_chunks_total{endpoint="localhost:24224"} 1` + "\n"))
+
+		Expect(out).To(ContainSubstring("# TYPE fluent_client_send_duration_seconds histogram\n"))
+		Expect(out).To(ContainSubstring(`fluent_client_send_duration_seconds_bucket{endpoint="localhost:24224",tag="foo",le="0.1"} 1` + "\n"))
+		Expect(out).To(ContainSubstring(`fluent_client_send_duration_seconds_bucket{endpoint="localhost:24224",tag="foo",le="1"} 2` + "\n"))
+		Expect(out).To(ContainSubstring(`fluent_client_send_duration_seconds_bucket{endpoint="localhost:24224",tag="foo",le="+Inf"} 2` + "\n"))
+		Expect(out).To(ContainSubstring(`fluent_client_send_duration_seconds_sum{endpoint="localhost:24224",tag="foo"} 0.55` + "\n"))
+		Expect(out).To(ContainSubstring(`fluent_client_send_duration_seconds_count{endpoint="localhost:24224",tag="foo"} 2` + "\n"))
+	})
+
+	It("renders acks, handshakes, reconnects and compression", func() {
+		p.AckReceived("a", 2*time.Second)
+		p.AckTimedOut("a")
+		p.Handshake("a", time.Millisecond, nil)
+		p.Reconnected("a", errors.New("nope"))
+		p.Compressed("a", "foo", 400, 100)
+
+		out := scrape()
+		Expect(out).To(ContainSubstring(`fluent_client_acks_total{endpoint="a"} 1` + "\n"))
+		Expect(out).To(ContainSubstring(`fluent_client_ack_rtt_seconds_bucket{endpoint="a",le="1"} 0` + "\n"))
+		Expect(out).To(ContainSubstring(`fluent_client_ack_rtt_seconds_sum{endpoint="a"} 2` + "\n"))
+		Expect(out).To(ContainSubstring(`fluent_client_ack_timeouts_total{endpoint="a"} 1` + "\n"))
+		Expect(out).To(ContainSubstring(`fluent_client_handshakes_total{endpoint="a",result="ok"} 1` + "\n"))
+		Expect(out).To(ContainSubstring(`fluent_client_handshake_duration_seconds_count{endpoint="a"} 1` + "\n"))
+		Expect(out).To(ContainSubstring(`fluent_client_reconnects_total{endpoint="a",result="error"} 1` + "\n"))
+		Expect(out).To(ContainSubstring(`fluent_client_compression_ratio_bucket{endpoint="a",tag="foo",le="4"} 1` + "\n"))
+		Expect(out).To(ContainSubstring(`fluent_client_compression_ratio_bucket{endpoint="a",tag="foo",le="3"} 0` + "\n"))
+	})
+
+	It("sorts series and escapes label values", func() {
+		p.Reconnected("b", nil)
+		p.Reconnected(`a"\`+"\n", nil)
+
+		out := scrape()
+		Expect(out).To(ContainSubstring(
+			`fluent_client_reconnects_total{endpoint="a\"\\\n",result="ok"} 1` + "\n" +
+				`fluent_client_reconnects_total{endpoint="b",result="ok"} 1` + "\n"))
+	})
+
+	When("configured", func() {
+		BeforeEach(func() {
+			p = metrics.NewPrometheusRecorder(metrics.PrometheusOptions{
+				Namespace: "app",
+				OmitTags:  true,
+			})
+		})
+
+		It("uses the namespace and omits tags", func() {
+			p.Sent(metrics.SendStats{Endpoint: "a", Tag: "foo", Events: 1})
+
+			out := scrape()
+			Expect(out).To(ContainSubstring(`app_client_events_total{endpoint="a"} 1` + "\n"))
+			Expect(out).To(ContainSubstring(`app_client_messages_total{endpoint="a",result="ok"} 1` + "\n"))
+			Expect(out).NotTo(ContainSubstring("foo"))
+		})
+	})
+})
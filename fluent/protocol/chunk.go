@@ -77,15 +77,7 @@ var chunkKeyBits = []byte("chunk")
 // ack checks without the overhead of unmarshalling.
 // GetChunk returns an error if no value is found.
 func GetChunk(b []byte) (string, error) {
-	chunkReader := chunkReaderPool.Get().(*ChunkReader)
-	chunkReader.Reset(b)
-	reader := chunkReader.R
-
-	defer func() {
-		chunkReaderPool.Put(chunkReader)
-	}()
-
-	sz, err := reader.ReadArrayHeader()
+	sz, b, err := msgp.ReadArrayHeaderBytes(b)
 	if err != nil {
 		return "", fmt.Errorf("read array header: %w", err)
 	}
@@ -94,56 +86,73 @@ func GetChunk(b []byte) (string, error) {
 		return "", errors.New("chunk not found")
 	}
 
-	if err = reader.Skip(); err != nil {
+	if b, err = skipObjectBytes(b); err != nil {
 		return "", fmt.Errorf("skip tag: %w", err)
 	}
 
-	t, err := reader.NextType()
-	if err != nil {
-		return "", fmt.Errorf("next type: %w", err)
+	t := msgp.NextType(b)
+	if t == msgp.InvalidType {
+		return "", fmt.Errorf("next type: %w", msgp.ErrShortBytes)
 	}
 
-	if t == msgp.ExtensionType || t == msgp.IntType {
+	if m := modeOf(t); m == ModeMessage || m == ModeMessageExt {
 		// this is Message or MessageExt, which is sz 3
 		// when there are no options
 		if sz == 3 {
 			return "", errors.New("chunk not found")
 		}
 
-		if err = reader.Skip(); err != nil {
+		if b, err = skipObjectBytes(b); err != nil {
 			return "", fmt.Errorf("skip timestamp: %w", err)
 		}
 	}
 
-	if err = reader.Skip(); err != nil {
+	if b, err = skipObjectBytes(b); err != nil {
 		return "", fmt.Errorf("skip records: %w", err)
 	}
 
-	if t, err = reader.NextType(); t != msgp.MapType || err != nil {
-		return "", fmt.Errorf("chunk not found: %w", err)
+	if msgp.NextType(b) != msgp.MapType {
+		return "", errors.New("chunk not found")
+	}
+
+	v, found, err := lookupOptionBytes(b, chunkKeyBits)
+	if err != nil {
+		return "", err
+	}
+
+	if !found {
+		return "", errors.New("chunk not found")
 	}
 
-	sz, err = reader.ReadMapHeader()
+	return string(v), nil
+}
+
+// lookupOptionBytes returns the raw str or bin value of key in the
+// marshaled options map b. The length of the value is checked against b
+// before anything is allocated for it.
+func lookupOptionBytes(b, key []byte) ([]byte, bool, error) {
+	sz, b, err := msgp.ReadMapHeaderBytes(b)
 	if err != nil {
-		return "", fmt.Errorf("read map header: %w", err)
+		return nil, false, fmt.Errorf("read map header: %w", err)
 	}
 
 	for i := uint32(0); i < sz; i++ {
-		keyBits, err := reader.ReadMapKeyPtr()
-		if err != nil {
-			return "", fmt.Errorf("read map key: %w", err)
+		var keyBits []byte
+
+		if keyBits, b, err = msgp.ReadMapKeyZC(b); err != nil {
+			return nil, false, fmt.Errorf("read map key: %w", err)
 		}
 
-		if bytes.Equal(keyBits, chunkKeyBits) {
-			v, err := reader.ReadMapKey(nil)
-			return string(v), err
+		if bytes.Equal(keyBits, key) {
+			v, _, err := msgp.ReadMapKeyZC(b)
+			return v, err == nil, err
 		}
 
-		// didn't find "chunk", so skip to next key
-		if err = reader.Skip(); err != nil {
-			return "", fmt.Errorf("skip value: %w", err)
+		// didn't find the key, so skip to next one
+		if b, err = skipObjectBytes(b); err != nil {
+			return nil, false, fmt.Errorf("skip value: %w", err)
 		}
 	}
 
-	return "", errors.New("chunk not found")
+	return nil, false, nil
 }
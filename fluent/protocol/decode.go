@@ -0,0 +1,523 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package protocol
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/tinylib/msgp/msgp"
+)
+
+const (
+	// DefaultMaxMessageSize is the default limit, in bytes, on a single
+	// message.
+	DefaultMaxMessageSize = 64 * 1024 * 1024
+	// DefaultMaxEntries is the default limit on the number of entries in a
+	// Forward or PackedForward message.
+	DefaultMaxEntries = 1024 * 1024
+	// DefaultMaxRecordDepth is the default limit on how deeply arrays and
+	// maps may be nested within a record.
+	DefaultMaxRecordDepth = 64
+	// DefaultMaxTagLength is the default limit, in bytes, on a tag.
+	DefaultMaxTagLength = 4096
+	// DefaultMaxDecompressedSize is the default limit, in bytes, on a
+	// decompressed event stream.
+	DefaultMaxDecompressedSize = 256 * 1024 * 1024
+)
+
+// LimitError is returned when input exceeds one of the limits in
+// DecodeOptions. The peer is either misconfigured or hostile, and the
+// rest of its stream cannot be trusted, so a server should log the error
+// and close the connection.
+type LimitError struct {
+	// Limit is the name of the DecodeOptions field that was exceeded.
+	Limit string
+	// Max is the value of that field.
+	Max int
+}
+
+func (e *LimitError) Error() string {
+	if e.Max == 0 {
+		return fmt.Sprintf("%s exceeded", e.Limit)
+	}
+
+	return fmt.Sprintf("%s of %d exceeded", e.Limit, e.Max)
+}
+
+// Is reports whether target is a LimitError for the same limit, so that
+// errors.Is(err, ErrTagTooLong) matches regardless of the value of Max.
+func (e *LimitError) Is(target error) bool {
+	t, ok := target.(*LimitError)
+	return ok && t.Limit == e.Limit
+}
+
+var (
+	// ErrMessageTooLarge matches errors for DecodeOptions.MaxMessageSize.
+	ErrMessageTooLarge = &LimitError{Limit: "MaxMessageSize"}
+	// ErrTooManyEntries matches errors for DecodeOptions.MaxEntries.
+	ErrTooManyEntries = &LimitError{Limit: "MaxEntries"}
+	// ErrRecordTooDeep matches errors for DecodeOptions.MaxRecordDepth.
+	ErrRecordTooDeep = &LimitError{Limit: "MaxRecordDepth"}
+	// ErrTagTooLong matches errors for DecodeOptions.MaxTagLength.
+	ErrTagTooLong = &LimitError{Limit: "MaxTagLength"}
+	// ErrDecompressedTooLarge matches errors for
+	// DecodeOptions.MaxDecompressedSize.
+	ErrDecompressedTooLarge = &LimitError{Limit: "MaxDecompressedSize"}
+)
+
+// IsLimitError reports whether an error in err's chain is a LimitError.
+func IsLimitError(err error) bool {
+	var le *LimitError
+
+	return errors.As(err, &le)
+}
+
+// DecodeOptions bounds the memory a decoder will commit to on behalf of
+// untrusted input. A zero value for any field disables that limit, except
+// for MaxRecordDepth.
+//
+// msgp trusts the array, map, str and bin headers it reads, so without
+// these limits a few bytes claiming a huge array would make it allocate
+// gigabytes. The DecodeMsg and UnmarshalMsg methods of the messages in
+// this package apply DefaultDecodeOptions; use the Decode and Unmarshal
+// methods to apply other limits.
+type DecodeOptions struct {
+	// MaxMessageSize is the largest message, in bytes, that will be read
+	// from a stream or split from a byte slice by SplitRaw. A byte slice
+	// passed to Unmarshal has already been read, so it is bounded by its
+	// own length instead.
+	MaxMessageSize int
+	// MaxEntries is the largest number of entries accepted in a Forward or
+	// PackedForward message.
+	MaxEntries int
+	// MaxRecordDepth is the deepest nesting of arrays and maps accepted in
+	// a record or metadata map. It is never more than 10000, which is also
+	// the limit when it is zero, because records are decoded recursively.
+	MaxRecordDepth int
+	// MaxTagLength is the longest tag accepted, in bytes.
+	MaxTagLength int
+	// MaxDecompressedSize is the largest size, in bytes, a compressed
+	// event stream may expand to.
+	MaxDecompressedSize int
+	// RawRecords leaves records in their msgpack encoding, as a
+	// RawRecord, instead of decoding them to maps. The limits above
+	// still apply.
+	RawRecords bool
+}
+
+// defaultDecodeOptions are the limits applied by the DecodeMsg and
+// UnmarshalMsg methods of the message types in this package. They never
+// change, so messages can be decoded from any number of goroutines.
+var defaultDecodeOptions = DecodeOptions{
+	MaxMessageSize:      DefaultMaxMessageSize,
+	MaxEntries:          DefaultMaxEntries,
+	MaxRecordDepth:      DefaultMaxRecordDepth,
+	MaxTagLength:        DefaultMaxTagLength,
+	MaxDecompressedSize: DefaultMaxDecompressedSize,
+}
+
+// DefaultDecodeOptions returns the limits applied by the DecodeMsg and
+// UnmarshalMsg methods of the message types in this package. To apply
+// other limits, change the copy it returns and pass it to Decode or
+// Unmarshal.
+func DefaultDecodeOptions() DecodeOptions {
+	return defaultDecodeOptions
+}
+
+// limitedUnmarshaler is implemented by the messages that apply the limits
+// as they go. Anything else passed to Unmarshal is checked as a whole
+// before it is unmarshaled.
+type limitedUnmarshaler interface {
+	unmarshalLimited(bts []byte, opts *DecodeOptions) ([]byte, error)
+}
+
+// envelopeDepth is how deeply a record may sit within a message: in the
+// entries array of a ForwardMessage, inside an entry, beneath a Fluent Bit
+// v2 header.
+const envelopeDepth = 4
+
+// maxRecordDepthCap bounds MaxRecordDepth even when it is zero. Records
+// are checked and decoded recursively, so deeper nesting could exhaust the
+// stack.
+const maxRecordDepthCap = 10000
+
+func (opts *DecodeOptions) maxRecordDepth() int {
+	if opts.MaxRecordDepth <= 0 || opts.MaxRecordDepth > maxRecordDepthCap {
+		return maxRecordDepthCap
+	}
+
+	return opts.MaxRecordDepth
+}
+
+func (opts *DecodeOptions) maxMessageDepth() int {
+	return opts.maxRecordDepth() + envelopeDepth
+}
+
+// Decode reads the next message from dc into v. The message is read into
+// a buffer, no larger than MaxMessageSize, before it is unmarshaled, so v
+// must not retain references to the bytes it unmarshals; none of the
+// types in this package do.
+func (opts *DecodeOptions) Decode(dc *msgp.Reader, v msgp.Unmarshaler) error {
+	buf := decodeBufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+
+	defer func() {
+		decodeBufferPool.Put(buf)
+	}()
+
+	if err := opts.ReadRaw(dc, buf); err != nil {
+		return err
+	}
+
+	_, err := opts.Unmarshal(buf.Bytes(), v)
+
+	return err
+}
+
+// Unmarshal unmarshals the first message in bts into v and returns the
+// remaining bytes.
+func (opts *DecodeOptions) Unmarshal(bts []byte, v msgp.Unmarshaler) ([]byte, error) {
+	if lu, ok := v.(limitedUnmarshaler); ok {
+		return lu.unmarshalLimited(bts, opts)
+	}
+
+	if _, err := opts.checkBytes(bts, opts.maxMessageDepth()); err != nil {
+		return bts, err
+	}
+
+	return v.UnmarshalMsg(bts)
+}
+
+// ReadRaw copies the next message in dc to buf without decoding it. Its
+// headers are not trusted: bin and str payloads are copied as they
+// arrive, and array and map headers only add to the count of objects
+// still to be read at each level of nesting. Neither a huge header nor
+// deep nesting can make it allocate more than the bytes actually
+// received, up to MaxMessageSize.
+func (opts *DecodeOptions) ReadRaw(dc *msgp.Reader, buf *bytes.Buffer) error {
+	var (
+		w        = &limitedWriter{buf: buf, max: opts.MaxMessageSize}
+		maxDepth = opts.maxMessageDepth()
+		pending  = []uint64{1}
+		hdr      [5]byte
+	)
+
+	for len(pending) > 0 {
+		top := len(pending) - 1
+		if pending[top] == 0 {
+			pending = pending[:top]
+			continue
+		}
+
+		pending[top]--
+
+		t, err := dc.NextType()
+		if err != nil {
+			return err
+		}
+
+		switch t {
+		case msgp.ArrayType:
+			sz, err := dc.ReadArrayHeader()
+			if err != nil {
+				return err
+			}
+
+			if _, err = w.Write(msgp.AppendArrayHeader(hdr[:0], sz)); err != nil {
+				return err
+			}
+
+			pending = append(pending, uint64(sz))
+		case msgp.MapType:
+			sz, err := dc.ReadMapHeader()
+			if err != nil {
+				return err
+			}
+
+			if _, err = w.Write(msgp.AppendMapHeader(hdr[:0], sz)); err != nil {
+				return err
+			}
+
+			pending = append(pending, 2*uint64(sz))
+		default:
+			if _, err = dc.CopyNext(w); err != nil {
+				return err
+			}
+		}
+
+		if len(pending) > maxDepth {
+			return &LimitError{Limit: ErrRecordTooDeep.Limit, Max: opts.maxRecordDepth()}
+		}
+	}
+
+	return nil
+}
+
+// SplitRaw splits the first message from bts without decoding it, e.g.
+// to relay the messages in a websocket frame one at a time.
+func (opts *DecodeOptions) SplitRaw(bts []byte) (msg, rest []byte, err error) {
+	if rest, err = opts.checkBytes(bts, opts.maxMessageDepth()); err != nil {
+		return nil, bts, err
+	}
+
+	msg = bts[:len(bts)-len(rest)]
+
+	if opts.MaxMessageSize > 0 && len(msg) > opts.MaxMessageSize {
+		return nil, bts, &LimitError{Limit: ErrMessageTooLarge.Limit, Max: opts.MaxMessageSize}
+	}
+
+	return msg, rest, nil
+}
+
+// ReadTagBytes reads a tag of no more than MaxTagLength bytes.
+func (opts *DecodeOptions) ReadTagBytes(bts []byte) (string, []byte, error) {
+	tag, rest, err := msgp.ReadStringZC(bts)
+	if err != nil {
+		return "", bts, err
+	}
+
+	if opts.MaxTagLength > 0 && len(tag) > opts.MaxTagLength {
+		return "", bts, &LimitError{Limit: ErrTagTooLong.Limit, Max: opts.MaxTagLength}
+	}
+
+	return string(tag), rest, nil
+}
+
+// ReadRecordBytes is msgp.ReadIntfBytes with the record checked before
+// anything is allocated for it: it may be nested no more than
+// MaxRecordDepth deep, and no array or map in it may claim more elements
+// than there are bytes left to hold them. If RawRecords is set, the
+// record is returned as a RawRecord.
+func (opts *DecodeOptions) ReadRecordBytes(bts []byte) (interface{}, []byte, error) {
+	if opts.RawRecords {
+		return opts.readRawRecordBytes(bts)
+	}
+
+	if err := opts.checkRecordBytes(bts); err != nil {
+		return nil, bts, err
+	}
+
+	return msgp.ReadIntfBytes(bts)
+}
+
+// readRawRecordBytes copies the next object in bts, once it has passed
+// the same checks as ReadRecordBytes, as a RawRecord.
+func (opts *DecodeOptions) readRawRecordBytes(bts []byte) (RawRecord, []byte, error) {
+	rest, err := opts.checkBytes(bts, opts.maxRecordDepth())
+	if err != nil {
+		return nil, bts, err
+	}
+
+	return append(RawRecord(nil), bts[:len(bts)-len(rest)]...), rest, nil
+}
+
+// ReadMetadataBytes is msgp.ReadMapStrIntfBytes with the same checks as
+// ReadRecordBytes.
+func (opts *DecodeOptions) ReadMetadataBytes(bts []byte) (map[string]interface{}, []byte, error) {
+	if err := opts.checkRecordBytes(bts); err != nil {
+		return nil, bts, err
+	}
+
+	return msgp.ReadMapStrIntfBytes(bts, nil)
+}
+
+// CheckEntries returns a LimitError if n entries exceed MaxEntries.
+func (opts *DecodeOptions) CheckEntries(n int) error {
+	if opts.MaxEntries > 0 && n > opts.MaxEntries {
+		return &LimitError{Limit: ErrTooManyEntries.Limit, Max: opts.MaxEntries}
+	}
+
+	return nil
+}
+
+// Gunzip decompresses a gzip-compressed event stream of no more than
+// MaxDecompressedSize bytes.
+func (opts *DecodeOptions) Gunzip(stream []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(stream))
+	if err != nil {
+		return nil, err
+	}
+
+	var r io.Reader = zr
+
+	if opts.MaxDecompressedSize > 0 {
+		// read one byte past the limit to tell a stream that fits
+		// exactly from one that is too large
+		r = io.LimitReader(zr, int64(opts.MaxDecompressedSize)+1)
+	}
+
+	out, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.MaxDecompressedSize > 0 && len(out) > opts.MaxDecompressedSize {
+		return nil, &LimitError{Limit: ErrDecompressedTooLarge.Limit, Max: opts.MaxDecompressedSize}
+	}
+
+	return out, nil
+}
+
+// unmarshalOptions unmarshals message options once they have passed the
+// same checks as a record, since the generated decoder skips unknown
+// values recursively.
+func (opts *DecodeOptions) unmarshalOptions(bts []byte) (*MessageOptions, []byte, error) {
+	if err := opts.checkRecordBytes(bts); err != nil {
+		return nil, bts, err
+	}
+
+	mo := &MessageOptions{}
+	bts, err := mo.UnmarshalMsg(bts)
+
+	return mo, bts, err
+}
+
+// readEntriesHeaderBytes reads the array header of a Forward message's
+// entries and checks it against MaxEntries.
+func (opts *DecodeOptions) readEntriesHeaderBytes(bts []byte) (uint32, []byte, error) {
+	sz, rest, err := msgp.ReadArrayHeaderBytes(bts)
+	if err != nil {
+		return 0, bts, err
+	}
+
+	if err = opts.CheckEntries(int(sz)); err != nil {
+		return 0, bts, err
+	}
+
+	// every entry takes at least one byte
+	if uint64(sz) > uint64(len(rest)) {
+		return 0, bts, msgp.ErrShortBytes
+	}
+
+	return sz, rest, nil
+}
+
+func (opts *DecodeOptions) checkRecordBytes(bts []byte) error {
+	_, err := opts.checkBytes(bts, opts.maxRecordDepth())
+	return err
+}
+
+// checkBytes is checkObjectBytes with depth errors reporting the
+// effective MaxRecordDepth.
+func (opts *DecodeOptions) checkBytes(bts []byte, depth int) ([]byte, error) {
+	rest, err := checkObjectBytes(bts, depth)
+	if errors.Is(err, ErrRecordTooDeep) {
+		return rest, &LimitError{Limit: ErrRecordTooDeep.Limit, Max: opts.maxRecordDepth()}
+	}
+
+	return rest, err
+}
+
+// checkObjectBytes walks the next object in bts, verifying that it is
+// nested no deeper than depth and that every array and map header claims
+// no more elements than there are bytes left to hold them. It returns
+// ErrRecordTooDeep itself if the object is too deeply nested.
+func checkObjectBytes(bts []byte, depth int) ([]byte, error) {
+	var (
+		n   uint64
+		sz  uint32
+		err error
+	)
+
+	switch msgp.NextType(bts) {
+	case msgp.ArrayType:
+		sz, bts, err = msgp.ReadArrayHeaderBytes(bts)
+		n = uint64(sz)
+	case msgp.MapType:
+		sz, bts, err = msgp.ReadMapHeaderBytes(bts)
+		n = 2 * uint64(sz)
+	default:
+		return msgp.Skip(bts)
+	}
+
+	if err != nil {
+		return bts, err
+	}
+
+	if depth <= 0 {
+		return bts, ErrRecordTooDeep
+	}
+
+	// every element takes at least one byte
+	if n > uint64(len(bts)) {
+		return bts, msgp.ErrShortBytes
+	}
+
+	for i := uint64(0); i < n; i++ {
+		if bts, err = checkObjectBytes(bts, depth-1); err != nil {
+			return bts, err
+		}
+	}
+
+	return bts, nil
+}
+
+// skipObjectBytes is msgp.Skip without the recursion, so that the nesting
+// of the object is limited only by the length of bts.
+func skipObjectBytes(bts []byte) ([]byte, error) {
+	var (
+		sz  uint32
+		err error
+	)
+
+	for remaining := uint64(1); remaining > 0; remaining-- {
+		switch msgp.NextType(bts) {
+		case msgp.ArrayType:
+			sz, bts, err = msgp.ReadArrayHeaderBytes(bts)
+			remaining += uint64(sz)
+		case msgp.MapType:
+			sz, bts, err = msgp.ReadMapHeaderBytes(bts)
+			remaining += 2 * uint64(sz)
+		default:
+			bts, err = msgp.Skip(bts)
+		}
+
+		if err != nil {
+			return bts, err
+		}
+	}
+
+	return bts, nil
+}
+
+// limitedWriter is a bytes.Buffer that refuses to grow past max bytes. It
+// deliberately does not implement io.ReaderFrom, so that copies into it
+// go through Write in bounded increments.
+type limitedWriter struct {
+	buf *bytes.Buffer
+	max int
+}
+
+func (lw *limitedWriter) Write(p []byte) (int, error) {
+	if lw.max > 0 && lw.buf.Len()+len(p) > lw.max {
+		return 0, &LimitError{Limit: ErrMessageTooLarge.Limit, Max: lw.max}
+	}
+
+	return lw.buf.Write(p)
+}
@@ -0,0 +1,239 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package protocol_test
+
+import (
+	"bytes"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/tinylib/msgp/msgp"
+
+	"github.com/IBM/fluent-forward-go/fluent/protocol"
+)
+
+// nested returns a record holding a value depth arrays deep.
+func nested(depth int) []byte {
+	return append(bytes.Repeat([]byte{0x91}, depth), 0xc0)
+}
+
+func messageWithRecord(record []byte) []byte {
+	bits := msgp.AppendArrayHeader(nil, 3)
+	bits = msgp.AppendString(bits, "tag")
+	bits = msgp.AppendInt64(bits, 1)
+
+	return append(bits, record...)
+}
+
+var _ = Describe("Decode limits", func() {
+	var opts protocol.DecodeOptions
+
+	BeforeEach(func() {
+		opts = protocol.DefaultDecodeOptions()
+	})
+
+	It("rejects array headers that claim more elements than there are bytes", func() {
+		// array32 claiming 4294967295 elements
+		bits := messageWithRecord([]byte{0xdd, 0xff, 0xff, 0xff, 0xff, 0xc0})
+
+		var msg protocol.Message
+		_, err := msg.UnmarshalMsg(bits)
+		Expect(err).To(MatchError(msgp.ErrShortBytes))
+
+		var el protocol.EntryList
+		_, err = el.UnmarshalMsg([]byte{0xdd, 0xff, 0xff, 0xff, 0xff, 0xc0})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("rejects records nested deeper than MaxRecordDepth", func() {
+		opts.MaxRecordDepth = 8
+
+		var msg protocol.Message
+		_, err := opts.Unmarshal(messageWithRecord(nested(8)), &msg)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = opts.Unmarshal(messageWithRecord(nested(9)), &msg)
+		Expect(err).To(MatchError(protocol.ErrRecordTooDeep))
+
+		err = opts.Decode(msgp.NewReader(bytes.NewReader(messageWithRecord(nested(1<<20)))), &msg)
+		Expect(err).To(MatchError(protocol.ErrRecordTooDeep))
+	})
+
+	It("caps the record depth when MaxRecordDepth is zero", func() {
+		opts.MaxRecordDepth = 0
+
+		var msg protocol.Message
+		_, err := opts.Unmarshal(messageWithRecord(nested(1000)), &msg)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = opts.Unmarshal(messageWithRecord(nested(1<<20)), &msg)
+		Expect(err).To(MatchError(protocol.ErrRecordTooDeep))
+
+		var le *protocol.LimitError
+		Expect(errors.As(err, &le)).To(BeTrue())
+		Expect(le.Max).To(Equal(10000))
+
+		err = opts.Decode(msgp.NewReader(bytes.NewReader(messageWithRecord(nested(1<<20)))), &msg)
+		Expect(err).To(MatchError(protocol.ErrRecordTooDeep))
+
+		_, _, err = (&protocol.DecodeOptions{}).ReadRecordBytes(nested(1 << 20))
+		Expect(err).To(MatchError(protocol.ErrRecordTooDeep))
+	})
+
+	It("rejects messages larger than MaxMessageSize when streaming", func() {
+		opts.MaxMessageSize = 1024
+
+		small := protocol.NewPackedForwardMessageFromBytes("tag", make([]byte, 512))
+		bits, err := small.MarshalMsg(nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		var pfm protocol.PackedForwardMessage
+		Expect(opts.Decode(msgp.NewReader(bytes.NewReader(bits)), &pfm)).To(Succeed())
+		Expect(pfm.EventStream).To(HaveLen(512))
+
+		large := protocol.NewPackedForwardMessageFromBytes("tag", make([]byte, 2048))
+		bits, err = large.MarshalMsg(nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		err = opts.Decode(msgp.NewReader(bytes.NewReader(bits)), &pfm)
+		Expect(err).To(MatchError(protocol.ErrMessageTooLarge))
+	})
+
+	It("does not allocate for a bin header larger than the input", func() {
+		// bin32 claiming 4GiB, followed by two bytes
+		bits := msgp.AppendArrayHeader(nil, 2)
+		bits = msgp.AppendString(bits, "tag")
+		bits = append(bits, 0xc6, 0xff, 0xff, 0xff, 0xff, 0x00, 0x00)
+
+		var pfm protocol.PackedForwardMessage
+		err := pfm.DecodeMsg(msgp.NewReader(bytes.NewReader(bits)))
+		Expect(err).To(HaveOccurred())
+
+		_, err = pfm.UnmarshalMsg(bits)
+		Expect(err).To(MatchError(msgp.ErrShortBytes))
+	})
+
+	It("rejects tags longer than MaxTagLength", func() {
+		opts := &protocol.DecodeOptions{MaxTagLength: 3}
+
+		bits, err := protocol.NewMessage("tag", nil).MarshalMsg(nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		var msg protocol.Message
+		_, err = opts.Unmarshal(bits, &msg)
+		Expect(err).NotTo(HaveOccurred())
+
+		bits, err = protocol.NewMessage("long.tag", nil).MarshalMsg(nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = opts.Unmarshal(bits, &msg)
+		Expect(err).To(MatchError(protocol.ErrTagTooLong))
+
+		var le *protocol.LimitError
+		Expect(errors.As(err, &le)).To(BeTrue())
+		Expect(le.Max).To(Equal(3))
+		Expect(protocol.IsLimitError(err)).To(BeTrue())
+	})
+
+	It("rejects more entries than MaxEntries", func() {
+		opts := &protocol.DecodeOptions{MaxEntries: 1}
+		entries := protocol.EntryList{
+			{Timestamp: protocol.EventTimeNow(), Record: map[string]interface{}{"a": 1}},
+			{Timestamp: protocol.EventTimeNow(), Record: map[string]interface{}{"b": 2}},
+		}
+
+		bits, err := protocol.NewForwardMessage("tag", entries).MarshalMsg(nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		var fm protocol.ForwardMessage
+		_, err = opts.Unmarshal(bits, &fm)
+		Expect(err).To(MatchError(protocol.ErrTooManyEntries))
+
+		stream, err := entries.MarshalPacked()
+		Expect(err).NotTo(HaveOccurred())
+
+		var el protocol.EntryList
+		_, err = opts.UnmarshalPacked(stream, &el)
+		Expect(err).To(MatchError(protocol.ErrTooManyEntries))
+	})
+
+	It("rejects event streams that decompress to more than MaxDecompressedSize", func() {
+		opts := &protocol.DecodeOptions{MaxDecompressedSize: 1024}
+
+		for _, size := range []int{1024, 1025} {
+			msg, err := protocol.NewCompressedPackedForwardMessageFromBytes("tag", make([]byte, size))
+			Expect(err).NotTo(HaveOccurred())
+
+			out, err := opts.Gunzip(msg.EventStream)
+			if size == 1024 {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(out).To(HaveLen(size))
+			} else {
+				Expect(err).To(MatchError(protocol.ErrDecompressedTooLarge))
+			}
+		}
+	})
+
+	It("splits messages no larger than MaxMessageSize", func() {
+		opts := &protocol.DecodeOptions{MaxMessageSize: 64}
+
+		small, err := protocol.NewMessage("tag", "small").MarshalMsg(nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		bits, err := protocol.NewMessage("tag", string(make([]byte, 64))).MarshalMsg(small)
+		Expect(err).NotTo(HaveOccurred())
+
+		msg, rest, err := opts.SplitRaw(bits)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(msg).To(Equal(small))
+
+		_, _, err = opts.SplitRaw(rest)
+		Expect(err).To(MatchError(protocol.ErrMessageTooLarge))
+	})
+
+	It("checks types without limits of their own as a whole", func() {
+		opts := &protocol.DecodeOptions{MaxRecordDepth: 1}
+
+		var ack protocol.AckMessage
+		_, err := opts.Unmarshal([]byte{0x81, 0xa3, 'a', 'c', 'k', 0xa1, 'x'}, &ack)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ack.Ack).To(Equal("x"))
+
+		_, err = opts.Unmarshal(append([]byte{0x81, 0xa3, 'f', 'o', 'o'}, nested(8)...), &ack)
+		Expect(err).To(MatchError(protocol.ErrRecordTooDeep))
+	})
+
+	It("lets GetChunk skip deeply nested records", func() {
+		bits := msgp.AppendArrayHeader(nil, 3)
+		bits = msgp.AppendString(bits, "tag")
+		bits = append(bits, nested(1<<20)...)
+		bits = msgp.AppendMapHeader(bits, 1)
+		bits = msgp.AppendString(bits, "chunk")
+		bits = msgp.AppendString(bits, "abc")
+
+		Expect(protocol.GetChunk(bits)).To(Equal("abc"))
+	})
+})
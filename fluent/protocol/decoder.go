@@ -0,0 +1,308 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package protocol
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/tinylib/msgp/msgp"
+)
+
+// Mode is one of the ways the Forward protocol carries events. See
+// https://github.com/fluent/fluentd/wiki/Forward-Protocol-Specification-v1#event-modes
+// for more information.
+type Mode int
+
+const (
+	ModeUnknown Mode = iota
+	// ModeMessage carries one event with an integer timestamp.
+	ModeMessage
+	// ModeMessageExt carries one event with an EventTime timestamp.
+	ModeMessageExt
+	// ModeForward carries an array of entries.
+	ModeForward
+	// ModePackedForward carries entries concatenated into a binary stream.
+	ModePackedForward
+	// ModeCompressedPackedForward is ModePackedForward with the stream
+	// gzip-compressed.
+	ModeCompressedPackedForward
+)
+
+func (m Mode) String() string {
+	switch m {
+	case ModeMessage:
+		return "Message"
+	case ModeMessageExt:
+		return "MessageExt"
+	case ModeForward:
+		return "Forward"
+	case ModePackedForward:
+		return "PackedForward"
+	case ModeCompressedPackedForward:
+		return "CompressedPackedForward"
+	default:
+		return "Unknown"
+	}
+}
+
+// AnyMessage is a message in any mode, as returned by DecodeAny: a
+// *Message, *MessageExt, *ForwardMessage or *PackedForwardMessage.
+type AnyMessage interface {
+	ChunkEncoder
+	EventSource
+	msgp.Marshaler
+	msgp.Unmarshaler
+	Mode() Mode
+}
+
+// Mode returns ModeMessage.
+func (msg *Message) Mode() Mode {
+	return ModeMessage
+}
+
+// Mode returns ModeMessageExt.
+func (msg *MessageExt) Mode() Mode {
+	return ModeMessageExt
+}
+
+// Mode returns ModeForward.
+func (fm *ForwardMessage) Mode() Mode {
+	return ModeForward
+}
+
+// Mode returns ModeCompressedPackedForward if the options say the event
+// stream is compressed, and ModePackedForward otherwise.
+func (msg *PackedForwardMessage) Mode() Mode {
+	if msg.Options != nil && msg.Options.Compressed != "" {
+		return ModeCompressedPackedForward
+	}
+
+	return ModePackedForward
+}
+
+var compressedKeyBits = []byte(OptCompressed)
+
+// modeOf returns the mode implied by the type of the element that follows
+// the tag: an integer timestamp for Message mode, an EventTime for
+// MessageExt mode, an array of entries for Forward mode, and a bin or
+// str event stream for PackedForward mode.
+func modeOf(t msgp.Type) Mode {
+	switch t {
+	case msgp.IntType, msgp.UintType:
+		return ModeMessage
+	case msgp.ExtensionType:
+		return ModeMessageExt
+	case msgp.ArrayType:
+		return ModeForward
+	case msgp.BinType, msgp.StrType:
+		return ModePackedForward
+	default:
+		return ModeUnknown
+	}
+}
+
+// peekMode reads the envelope of the marshaled message b far enough to
+// identify its mode, and checks that the length of the message suits it.
+// Compressed streams are reported as ModePackedForward.
+func peekMode(b []byte) (Mode, uint32, []byte, error) {
+	sz, b, err := msgp.ReadArrayHeaderBytes(b)
+	if err != nil {
+		return ModeUnknown, 0, b, msgp.WrapError(err, "Array Header")
+	}
+
+	if b, err = skipObjectBytes(b); err != nil {
+		return ModeUnknown, sz, b, msgp.WrapError(err, "Tag")
+	}
+
+	t := msgp.NextType(b)
+	if t == msgp.InvalidType {
+		return ModeUnknown, sz, b, msgp.ErrShortBytes
+	}
+
+	mode := modeOf(t)
+
+	switch mode {
+	case ModeMessage, ModeMessageExt:
+		if sz != 3 && sz != 4 {
+			return mode, sz, b, fmt.Errorf("invalid %s message length %d", mode, sz)
+		}
+	case ModeForward, ModePackedForward:
+		if sz != 2 && sz != 3 {
+			return mode, sz, b, fmt.Errorf("invalid %s message length %d", mode, sz)
+		}
+	default:
+		return mode, sz, b, fmt.Errorf("unsupported message mode: %s follows tag", t)
+	}
+
+	return mode, sz, b, nil
+}
+
+// DetectMode identifies the mode of the marshaled message b by the types
+// of its elements, without decoding it. A PackedForward message is
+// reported as ModeCompressedPackedForward if its options say the stream
+// is compressed.
+func DetectMode(b []byte) (Mode, error) {
+	mode, sz, b, err := peekMode(b)
+	if err != nil || mode != ModePackedForward || sz != 3 {
+		return mode, err
+	}
+
+	if b, err = skipObjectBytes(b); err != nil {
+		return mode, msgp.WrapError(err, "EventStream")
+	}
+
+	if msgp.NextType(b) != msgp.MapType {
+		return mode, nil
+	}
+
+	v, found, err := lookupOptionBytes(b, compressedKeyBits)
+	if err != nil {
+		return mode, msgp.WrapError(err, "Options")
+	}
+
+	if found && len(v) > 0 {
+		return ModeCompressedPackedForward, nil
+	}
+
+	return mode, nil
+}
+
+// DecodeAny decodes the first message in bts, whatever its mode, and
+// returns the remaining bytes. It applies DefaultDecodeOptions.
+func DecodeAny(bts []byte) (AnyMessage, []byte, error) {
+	return defaultDecodeOptions.DecodeAny(bts)
+}
+
+// DecodeAny decodes the first message in bts, whatever its mode, and
+// returns the remaining bytes. The mode is identified by the type of the
+// element that follows the tag, as described by DetectMode.
+func (opts *DecodeOptions) DecodeAny(bts []byte) (AnyMessage, []byte, error) {
+	mode, _, _, err := peekMode(bts)
+	if err != nil {
+		return nil, bts, err
+	}
+
+	var msg AnyMessage
+
+	switch mode {
+	case ModeMessage:
+		msg = &Message{}
+	case ModeMessageExt:
+		msg = &MessageExt{}
+	case ModeForward:
+		msg = &ForwardMessage{}
+	default:
+		msg = &PackedForwardMessage{}
+	}
+
+	rest, err := opts.Unmarshal(bts, msg)
+	if err != nil {
+		return nil, bts, err
+	}
+
+	return msg, rest, nil
+}
+
+// Decoder decodes a sequence of messages, in any mode, from a msgp.Reader
+// or a byte slice.
+type Decoder struct {
+	opts *DecodeOptions
+	r    *msgp.Reader
+	bts  []byte
+	buf  bytes.Buffer
+	err  error
+}
+
+// NewDecoder returns a Decoder that reads messages from r. If opts is nil,
+// DefaultDecodeOptions are applied.
+func NewDecoder(r *msgp.Reader, opts *DecodeOptions) *Decoder {
+	if opts == nil {
+		opts = &defaultDecodeOptions
+	}
+
+	return &Decoder{opts: opts, r: r}
+}
+
+// NewBytesDecoder returns a Decoder that decodes the messages in bts, e.g.
+// the contents of a websocket frame. If opts is nil, DefaultDecodeOptions
+// are applied.
+func NewBytesDecoder(bts []byte, opts *DecodeOptions) *Decoder {
+	if opts == nil {
+		opts = &defaultDecodeOptions
+	}
+
+	return &Decoder{opts: opts, bts: bts}
+}
+
+// Decode returns the next message. It returns io.EOF when there are no
+// more messages. Once Decode fails, it returns the same error from then
+// on.
+func (d *Decoder) Decode() (AnyMessage, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	var msg AnyMessage
+
+	if d.r != nil {
+		d.buf.Reset()
+
+		if d.err = d.opts.ReadRaw(d.r, &d.buf); d.err != nil {
+			// msgp reports a stream that ends inside a str or bin
+			// payload as ErrShortBytes
+			if d.buf.Len() > 0 && (d.err == io.EOF || errors.Is(d.err, msgp.ErrShortBytes)) {
+				d.err = io.ErrUnexpectedEOF
+			}
+
+			return nil, d.err
+		}
+
+		// the decoded messages copy what they keep, so the buffer can
+		// be reused
+		msg, _, d.err = d.opts.DecodeAny(d.buf.Bytes())
+	} else {
+		if len(d.bts) == 0 {
+			d.err = io.EOF
+			return nil, d.err
+		}
+
+		msg, d.bts, d.err = d.opts.DecodeAny(d.bts)
+	}
+
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	return msg, nil
+}
+
+// Events returns an iterator over the events in the remaining messages.
+// The iterator's Message method returns the message each event came from.
+func (d *Decoder) Events() *EventIterator {
+	return &EventIterator{dec: d, opts: d.opts}
+}
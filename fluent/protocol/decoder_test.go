@@ -0,0 +1,261 @@
+package protocol_test
+
+import (
+	"bytes"
+	"io"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/tinylib/msgp/msgp"
+
+	"github.com/IBM/fluent-forward-go/fluent/protocol"
+)
+
+var _ = Describe("Decoder", func() {
+	var (
+		ts      time.Time
+		entries protocol.EntryList
+		encoded map[protocol.Mode][]byte
+	)
+
+	marshal := func(msg msgp.Marshaler) []byte {
+		bits, err := msg.MarshalMsg(nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		return bits
+	}
+
+	BeforeEach(func() {
+		ts = time.Unix(1700000000, 123456789).UTC()
+		entries = protocol.EntryList{
+			{Timestamp: protocol.EventTime{Time: ts}, Record: map[string]interface{}{"n": int64(1)}},
+			{Timestamp: protocol.EventTime{Time: ts}, Record: map[string]interface{}{"n": int64(2)}},
+		}
+
+		pfm, err := protocol.NewPackedForwardMessage("packed", entries)
+		Expect(err).NotTo(HaveOccurred())
+		packed := marshal(pfm)
+
+		cfm, err := protocol.NewCompressedPackedForwardMessage("compressed", entries)
+		Expect(err).NotTo(HaveOccurred())
+		compressed := marshal(cfm)
+
+		encoded = map[protocol.Mode][]byte{
+			protocol.ModeMessage: marshal(&protocol.Message{
+				Tag: "message", Timestamp: ts.Unix(), Record: map[string]interface{}{"n": int64(0)},
+			}),
+			protocol.ModeMessageExt: marshal(&protocol.MessageExt{
+				Tag: "message_ext", Timestamp: protocol.EventTime{Time: ts}, Record: map[string]interface{}{"n": int64(0)},
+			}),
+			protocol.ModeForward:                 marshal(protocol.NewForwardMessage("forward", entries)),
+			protocol.ModePackedForward:           packed,
+			protocol.ModeCompressedPackedForward: compressed,
+		}
+	})
+
+	Describe("DetectMode", func() {
+		It("identifies every mode", func() {
+			for mode, bits := range encoded {
+				Expect(protocol.DetectMode(bits)).To(Equal(mode), mode.String())
+			}
+		})
+
+		It("identifies Message mode by an unsigned timestamp", func() {
+			bits := msgp.AppendArrayHeader(nil, 3)
+			bits = msgp.AppendString(bits, "tag")
+			bits = msgp.AppendUint32(bits, uint32(ts.Unix()))
+			bits = msgp.AppendMapHeader(bits, 0)
+
+			Expect(protocol.DetectMode(bits)).To(Equal(protocol.ModeMessage))
+		})
+
+		It("rejects an unsupported element after the tag", func() {
+			bits := msgp.AppendArrayHeader(nil, 2)
+			bits = msgp.AppendString(bits, "tag")
+			bits = msgp.AppendMapHeader(bits, 0)
+
+			mode, err := protocol.DetectMode(bits)
+			Expect(err).To(MatchError(ContainSubstring("unsupported message mode")))
+			Expect(mode).To(Equal(protocol.ModeUnknown))
+		})
+
+		It("rejects a message length that does not suit the mode", func() {
+			bits := msgp.AppendArrayHeader(nil, 5)
+			bits = msgp.AppendString(bits, "tag")
+			bits = msgp.AppendArrayHeader(bits, 0)
+
+			_, err := protocol.DetectMode(bits)
+			Expect(err).To(MatchError(ContainSubstring("invalid Forward message length 5")))
+		})
+	})
+
+	Describe("DecodeAny", func() {
+		It("returns a typed value for every mode", func() {
+			for mode, bits := range encoded {
+				msg, rest, err := protocol.DecodeAny(append(bits, 0xc0))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(rest).To(Equal([]byte{0xc0}))
+				Expect(msg.Mode()).To(Equal(mode))
+			}
+
+			msg, _, err := protocol.DecodeAny(encoded[protocol.ModeForward])
+			Expect(err).NotTo(HaveOccurred())
+			Expect(msg).To(BeAssignableToTypeOf(&protocol.ForwardMessage{}))
+			Expect(msg.(*protocol.ForwardMessage).Entries.Equal(entries)).To(BeTrue())
+		})
+
+		It("applies the given limits", func() {
+			opts := &protocol.DecodeOptions{MaxEntries: 1}
+
+			_, _, err := opts.DecodeAny(encoded[protocol.ModeForward])
+			Expect(err).To(MatchError(protocol.ErrTooManyEntries))
+		})
+
+		It("accepts a str event stream and integer entry timestamps", func() {
+			stream := msgp.AppendArrayHeader(nil, 2)
+			stream = msgp.AppendInt64(stream, ts.Unix())
+			stream = msgp.AppendMapHeader(stream, 0)
+
+			bits := msgp.AppendArrayHeader(nil, 2)
+			bits = msgp.AppendString(bits, "tag")
+			bits = msgp.AppendStringFromBytes(bits, stream)
+
+			msg, _, err := protocol.DecodeAny(bits)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(msg.(*protocol.PackedForwardMessage).EventStream).To(Equal(stream))
+
+			it := protocol.NewBytesDecoder(bits, nil).Events()
+			Expect(it.Next()).To(BeTrue())
+			Expect(it.Event().Timestamp.Time).To(Equal(time.Unix(ts.Unix(), 0).UTC()))
+			Expect(it.Next()).To(BeFalse())
+			Expect(it.Err()).NotTo(HaveOccurred())
+		})
+	})
+
+	Describe("Decoder", func() {
+		var stream []byte
+
+		BeforeEach(func() {
+			stream = nil
+			for _, mode := range []protocol.Mode{
+				protocol.ModeMessage,
+				protocol.ModeMessageExt,
+				protocol.ModeForward,
+				protocol.ModePackedForward,
+				protocol.ModeCompressedPackedForward,
+			} {
+				stream = append(stream, encoded[mode]...)
+			}
+		})
+
+		decoders := func() []*protocol.Decoder {
+			return []*protocol.Decoder{
+				protocol.NewBytesDecoder(stream, nil),
+				protocol.NewDecoder(msgp.NewReader(bytes.NewReader(stream)), nil),
+			}
+		}
+
+		It("decodes messages in any mode until io.EOF", func() {
+			for _, dec := range decoders() {
+				var modes []protocol.Mode
+
+				for {
+					msg, err := dec.Decode()
+					if err == io.EOF {
+						break
+					}
+
+					Expect(err).NotTo(HaveOccurred())
+					modes = append(modes, msg.Mode())
+				}
+
+				Expect(modes).To(Equal([]protocol.Mode{
+					protocol.ModeMessage,
+					protocol.ModeMessageExt,
+					protocol.ModeForward,
+					protocol.ModePackedForward,
+					protocol.ModeCompressedPackedForward,
+				}))
+			}
+		})
+
+		It("iterates over the events in every message", func() {
+			for _, dec := range decoders() {
+				var (
+					tags []string
+					recs []interface{}
+					it   = dec.Events()
+				)
+
+				for it.Next() {
+					ev := it.Event()
+					tags = append(tags, ev.Tag)
+					recs = append(recs, ev.Record)
+					Expect(ev.Timestamp.Unix()).To(Equal(ts.Unix()))
+					Expect(it.Message().Mode().String()).NotTo(Equal("Unknown"))
+				}
+
+				Expect(it.Err()).NotTo(HaveOccurred())
+				Expect(tags).To(Equal([]string{
+					"message", "message_ext",
+					"forward", "forward",
+					"packed", "packed",
+					"compressed", "compressed",
+				}))
+				Expect(recs[7]).To(Equal(map[string]interface{}{"n": int64(2)}))
+			}
+		})
+
+		It("iterates over consecutive PackedForward messages with different tags", func() {
+			bar, err := protocol.NewPackedForwardMessage("bar", entries[:1])
+			Expect(err).NotTo(HaveOccurred())
+
+			stream := append(append([]byte(nil), encoded[protocol.ModePackedForward]...), marshal(bar)...)
+
+			var (
+				tags []string
+				recs []interface{}
+				it   = protocol.NewBytesDecoder(stream, nil).Events()
+			)
+
+			for it.Next() {
+				ev := it.Event()
+				Expect(it.Message().Mode()).To(Equal(protocol.ModePackedForward))
+				Expect(ev.Timestamp.Equal(ts)).To(BeTrue())
+				tags = append(tags, ev.Tag)
+				recs = append(recs, ev.Record)
+			}
+
+			Expect(it.Err()).NotTo(HaveOccurred())
+			Expect(tags).To(Equal([]string{"packed", "packed", "bar"}))
+			Expect(recs).To(Equal([]interface{}{
+				map[string]interface{}{"n": int64(1)},
+				map[string]interface{}{"n": int64(2)},
+				map[string]interface{}{"n": int64(1)},
+			}))
+		})
+
+		It("reports a truncated stream", func() {
+			dec := protocol.NewDecoder(msgp.NewReader(bytes.NewReader(stream[:len(stream)-1])), nil)
+
+			it := dec.Events()
+			for it.Next() {
+			}
+
+			Expect(it.Err()).To(MatchError(io.ErrUnexpectedEOF))
+
+			_, err := dec.Decode()
+			Expect(err).To(MatchError(io.ErrUnexpectedEOF))
+		})
+
+		It("applies limits to packed event streams", func() {
+			opts := &protocol.DecodeOptions{MaxEntries: 1}
+			it := protocol.NewBytesDecoder(encoded[protocol.ModePackedForward], opts).Events()
+
+			Expect(it.Next()).To(BeTrue())
+			Expect(it.Next()).To(BeFalse())
+			Expect(it.Err()).To(MatchError(protocol.ErrTooManyEntries))
+		})
+	})
+})
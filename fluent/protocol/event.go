@@ -0,0 +1,235 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package protocol
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/tinylib/msgp/msgp"
+)
+
+// Event is a single event, whatever the mode of the message that carried
+// it.
+type Event struct {
+	// Tag is the tag of the message that carried the event.
+	Tag string
+	// Timestamp is the event time. Integer timestamps are converted to
+	// EventTime with second precision.
+	Timestamp EventTime
+	// Record is the event record.
+	Record interface{}
+	// Metadata is the optional Fluent Bit v2 entry metadata.
+	Metadata map[string]interface{}
+}
+
+// Entry returns the event as an EntryExt, without its tag.
+func (ev Event) Entry() EntryExt {
+	return EntryExt{
+		Timestamp: ev.Timestamp,
+		Record:    ev.Record,
+		Metadata:  ev.Metadata,
+	}
+}
+
+// EventSource is implemented by the messages of every mode, so that the
+// events they carry can be handled the same way whichever mode was used.
+type EventSource interface {
+	// Events returns an iterator over the events in the message. Packed
+	// event streams are decompressed and decoded as the iterator reaches
+	// them, within the limits in opts. If opts is nil,
+	// DefaultDecodeOptions are applied.
+	Events(opts *DecodeOptions) *EventIterator
+}
+
+// Events returns an iterator over the single event in the message.
+func (msg *Message) Events(opts *DecodeOptions) *EventIterator {
+	return newEventIterator(opts, msg, msg.Tag, EntryList{{
+		Timestamp: EventTime{Time: time.Unix(msg.Timestamp, 0).UTC()},
+		Record:    msg.Record,
+	}})
+}
+
+// Events returns an iterator over the single event in the message.
+func (msg *MessageExt) Events(opts *DecodeOptions) *EventIterator {
+	return newEventIterator(opts, msg, msg.Tag, EntryList{{
+		Timestamp: msg.Timestamp,
+		Record:    msg.Record,
+	}})
+}
+
+// Events returns an iterator over the entries in the message.
+func (fm *ForwardMessage) Events(opts *DecodeOptions) *EventIterator {
+	return newEventIterator(opts, fm, fm.Tag, fm.Entries)
+}
+
+// Events returns an iterator over the entries in the event stream,
+// decompressing it first if the options say it is compressed. Each entry
+// is decoded as the iterator reaches it.
+func (msg *PackedForwardMessage) Events(opts *DecodeOptions) *EventIterator {
+	it := newEventIterator(opts, msg, msg.Tag, nil)
+	it.stream = msg.EventStream
+
+	if msg.Options == nil || msg.Options.Compressed == "" {
+		return it
+	}
+
+	if msg.Options.Compressed != OptValGZIP {
+		it.err = fmt.Errorf("unsupported compression: %s", msg.Options.Compressed)
+		return it
+	}
+
+	if it.stream, it.err = it.opts.Gunzip(it.stream); it.err != nil {
+		it.err = fmt.Errorf("decompress event stream: %w", it.err)
+	}
+
+	return it
+}
+
+// EventIterator iterates over events one at a time. Entries in a packed
+// event stream are decoded as they are reached.
+//
+//	it := msg.Events(nil)
+//	for it.Next() {
+//		ev := it.Event()
+//		// ...
+//	}
+//	if err := it.Err(); err != nil {
+//		// ...
+//	}
+type EventIterator struct {
+	dec     *Decoder
+	opts    *DecodeOptions
+	msg     AnyMessage
+	tag     string
+	entries EntryList
+	stream  []byte
+	n       int
+	ev      Event
+	err     error
+}
+
+func newEventIterator(opts *DecodeOptions, msg AnyMessage, tag string, entries EntryList) *EventIterator {
+	if opts == nil {
+		opts = &defaultDecodeOptions
+	}
+
+	return &EventIterator{opts: opts, msg: msg, tag: tag, entries: entries}
+}
+
+// Next advances to the next event. It returns false when there are no
+// more events or an error occurs.
+func (it *EventIterator) Next() bool {
+	for it.err == nil {
+		if len(it.entries) > 0 {
+			it.setEvent(it.entries[0])
+			it.entries = it.entries[1:]
+
+			return true
+		}
+
+		if len(it.stream) > 0 {
+			if it.err = it.opts.CheckEntries(it.n + 1); it.err != nil {
+				return false
+			}
+
+			var entry EntryExt
+
+			if it.stream, it.err = entry.unmarshalLimited(it.stream, it.opts); it.err != nil {
+				it.err = msgp.WrapError(it.err, "EventStream", it.n)
+				return false
+			}
+
+			it.n++
+			it.setEvent(entry)
+
+			return true
+		}
+
+		if it.dec == nil {
+			return false
+		}
+
+		msg, err := it.dec.Decode()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				it.err = err
+			}
+
+			return false
+		}
+
+		// carry on with the events in the next message
+		dec := it.dec
+		*it = *msg.Events(it.opts)
+		it.dec = dec
+	}
+
+	return false
+}
+
+// Event returns the current event.
+func (it *EventIterator) Event() Event {
+	return it.ev
+}
+
+// Tag returns the tag of the message being iterated over. Unlike the tag
+// of an Event, it is set even if the message carries no events.
+func (it *EventIterator) Tag() string {
+	return it.tag
+}
+
+// Message returns the message being iterated over, e.g. to acknowledge
+// its chunk once all of its events are handled.
+func (it *EventIterator) Message() AnyMessage {
+	return it.msg
+}
+
+// Err returns the first error encountered, if any.
+func (it *EventIterator) Err() error {
+	return it.err
+}
+
+// Entries returns the remaining events as an EntryList.
+func (it *EventIterator) Entries() (EntryList, error) {
+	var entries EntryList
+
+	for it.Next() {
+		entries = append(entries, it.ev.Entry())
+	}
+
+	return entries, it.err
+}
+
+func (it *EventIterator) setEvent(entry EntryExt) {
+	it.ev = Event{
+		Tag:       it.tag,
+		Timestamp: entry.Timestamp,
+		Record:    entry.Record,
+		Metadata:  entry.Metadata,
+	}
+}
@@ -0,0 +1,116 @@
+package protocol_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/IBM/fluent-forward-go/fluent/protocol"
+)
+
+var _ = Describe("EventSource", func() {
+	var (
+		ts      time.Time
+		record  map[string]interface{}
+		entries protocol.EntryList
+	)
+
+	collect := func(src protocol.EventSource, opts *protocol.DecodeOptions) ([]protocol.Event, error) {
+		var (
+			events []protocol.Event
+			it     = src.Events(opts)
+		)
+
+		for it.Next() {
+			events = append(events, it.Event())
+		}
+
+		return events, it.Err()
+	}
+
+	BeforeEach(func() {
+		ts = time.Unix(1700000000, 500).UTC()
+		record = map[string]interface{}{"a": "b"}
+		entries = protocol.EntryList{
+			{Timestamp: protocol.EventTime{Time: ts}, Record: record},
+			{Timestamp: protocol.EventTime{Time: ts}, Record: map[string]interface{}{"c": "d"}, Metadata: map[string]interface{}{"m": "n"}},
+		}
+	})
+
+	It("yields the same events from every mode", func() {
+		pfm, err := protocol.NewPackedForwardMessage("tag", entries)
+		Expect(err).NotTo(HaveOccurred())
+		// the stream aliases a pooled buffer until it is copied
+		pfm.EventStream = append([]byte(nil), pfm.EventStream...)
+
+		cfm, err := protocol.NewCompressedPackedForwardMessage("tag", entries)
+		Expect(err).NotTo(HaveOccurred())
+
+		for _, src := range []protocol.EventSource{
+			protocol.NewForwardMessage("tag", entries),
+			pfm,
+			cfm,
+		} {
+			events, err := collect(src, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(events).To(HaveLen(2))
+
+			for i, ev := range events {
+				Expect(ev.Tag).To(Equal("tag"))
+				Expect(ev.Timestamp.Equal(ts)).To(BeTrue())
+				Expect(ev.Record).To(Equal(entries[i].Record))
+				Expect(ev.Metadata).To(Equal(entries[i].Metadata))
+			}
+		}
+
+		for _, src := range []protocol.EventSource{
+			&protocol.Message{Tag: "tag", Timestamp: ts.Unix(), Record: record},
+			&protocol.MessageExt{Tag: "tag", Timestamp: protocol.EventTime{Time: ts}, Record: record},
+		} {
+			events, err := collect(src, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(events).To(HaveLen(1))
+			Expect(events[0].Tag).To(Equal("tag"))
+			Expect(events[0].Timestamp.Unix()).To(Equal(ts.Unix()))
+			Expect(events[0].Record).To(Equal(record))
+		}
+	})
+
+	It("converts events back to entries", func() {
+		it := protocol.NewForwardMessage("tag", entries).Events(nil)
+
+		el, err := it.Entries()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(el.Equal(entries)).To(BeTrue())
+	})
+
+	It("reports the tag and message of a message without events", func() {
+		pfm := protocol.NewPackedForwardMessageFromBytes("empty", nil)
+		it := pfm.Events(nil)
+
+		Expect(it.Next()).To(BeFalse())
+		Expect(it.Err()).NotTo(HaveOccurred())
+		Expect(it.Tag()).To(Equal("empty"))
+		Expect(it.Message()).To(BeIdenticalTo(pfm))
+	})
+
+	It("rejects unsupported compression", func() {
+		pfm := protocol.NewPackedForwardMessageFromBytes("tag", []byte{0x90})
+		pfm.Options = &protocol.MessageOptions{Compressed: "zstd"}
+
+		_, err := collect(pfm, nil)
+		Expect(err).To(MatchError("unsupported compression: zstd"))
+	})
+
+	It("applies the given limits to compressed streams", func() {
+		cfm, err := protocol.NewCompressedPackedForwardMessage("tag", entries)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = collect(cfm, &protocol.DecodeOptions{MaxDecompressedSize: 8})
+		Expect(err).To(MatchError(protocol.ErrDecompressedTooLarge))
+
+		_, err = collect(cfm, &protocol.DecodeOptions{MaxEntries: 1})
+		Expect(err).To(MatchError(protocol.ErrTooManyEntries))
+	})
+})
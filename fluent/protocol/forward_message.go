@@ -103,37 +103,7 @@ func (fm *ForwardMessage) EncodeMsg(dc *msgp.Writer) error {
 }
 
 func (fm *ForwardMessage) DecodeMsg(dc *msgp.Reader) error {
-	sz, err := dc.ReadArrayHeader()
-	if err != nil {
-		return msgp.WrapError(err, "Array Header")
-	}
-
-	if fm.Tag, err = dc.ReadString(); err != nil {
-		return msgp.WrapError(err, "Tag")
-	}
-
-	fm.Entries = EntryList{}
-	if err = fm.Entries.DecodeMsg(dc); err != nil {
-		return msgp.WrapError(err, "Entries")
-	}
-
-	// has three elements only when options are included
-	if sz == 3 {
-		if t, err := dc.NextType(); t == msgp.NilType || err != nil {
-			if err != nil {
-				return msgp.WrapError(err, "Options")
-			}
-
-			return dc.ReadNil()
-		}
-
-		fm.Options = &MessageOptions{}
-		if err = fm.Options.DecodeMsg(dc); err != nil {
-			return msgp.WrapError(err, "Options")
-		}
-	}
-
-	return nil
+	return defaultDecodeOptions.Decode(dc, fm)
 }
 
 func (fm *ForwardMessage) MarshalMsg(bits []byte) ([]byte, error) {
@@ -164,6 +134,10 @@ func (fm *ForwardMessage) MarshalMsg(bits []byte) ([]byte, error) {
 }
 
 func (fm *ForwardMessage) UnmarshalMsg(bits []byte) ([]byte, error) {
+	return fm.unmarshalLimited(bits, &defaultDecodeOptions)
+}
+
+func (fm *ForwardMessage) unmarshalLimited(bits []byte, opts *DecodeOptions) ([]byte, error) {
 	var (
 		sz  uint32
 		err error
@@ -173,12 +147,12 @@ func (fm *ForwardMessage) UnmarshalMsg(bits []byte) ([]byte, error) {
 		return bits, msgp.WrapError(err, "Array Header")
 	}
 
-	if fm.Tag, bits, err = msgp.ReadStringBytes(bits); err != nil {
+	if fm.Tag, bits, err = opts.ReadTagBytes(bits); err != nil {
 		return bits, msgp.WrapError(err, "Tag")
 	}
 
 	fm.Entries = EntryList{}
-	if bits, err = fm.Entries.UnmarshalMsg(bits); err != nil {
+	if bits, err = fm.Entries.unmarshalLimited(bits, opts); err != nil {
 		return bits, err
 	}
 
@@ -188,8 +162,7 @@ func (fm *ForwardMessage) UnmarshalMsg(bits []byte) ([]byte, error) {
 			return msgp.ReadNilBytes(bits)
 		}
 
-		fm.Options = &MessageOptions{}
-		if bits, err = fm.Options.UnmarshalMsg(bits); err != nil {
+		if fm.Options, bits, err = opts.unmarshalOptions(bits); err != nil {
 			return bits, msgp.WrapError(err, "Options")
 		}
 	}
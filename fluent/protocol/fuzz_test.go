@@ -0,0 +1,329 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package protocol_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tinylib/msgp/msgp"
+
+	"github.com/IBM/fluent-forward-go/fluent/protocol"
+)
+
+// Run a target with, e.g.:
+//
+//	go test -run '^$' -fuzz '^FuzzMessage$' -fuzztime 30s ./fluent/protocol
+
+// fixtureSeeds returns the conformance fixtures plus a few hand-built
+// messages that exercise options, metadata and event time edges.
+func fixtureSeeds(tb testing.TB) [][]byte {
+	tb.Helper()
+
+	paths, err := filepath.Glob(filepath.Join("..", "..", "fixtures", "conformance", "*.msgpack"))
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	seeds := make([][]byte, 0, len(paths)+4)
+
+	for _, p := range paths {
+		b, err := os.ReadFile(p)
+		if err != nil {
+			tb.Fatal(err)
+		}
+
+		seeds = append(seeds, b)
+	}
+
+	record := map[string]interface{}{
+		"first": "Sir",
+		"last":  "Gawain",
+		"level": 3,
+		"tags":  []interface{}{"a", 1.5, nil, true},
+	}
+	ts := protocol.EventTime{Time: time.Unix(1658438400, 123456789)}
+	entries := protocol.EntryList{
+		{Timestamp: ts, Record: record},
+		{Timestamp: ts, Record: record, Metadata: map[string]interface{}{"k": "v"}},
+	}
+
+	msg := protocol.NewMessage("seed", record)
+	_, _ = msg.Chunk()
+
+	msgExt := protocol.NewMessageExt("seed", record)
+	msgExt.Timestamp = ts
+
+	fwd := protocol.NewForwardMessage("seed", entries)
+
+	packed, err := protocol.NewPackedForwardMessage("seed", entries)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	for _, m := range []msgp.Marshaler{msg, msgExt, fwd, packed} {
+		b, err := m.MarshalMsg(nil)
+		if err != nil {
+			tb.Fatal(err)
+		}
+
+		seeds = append(seeds, b)
+	}
+
+	return seeds
+}
+
+// eventStreamSeeds returns the (decompressed) event streams carried by
+// the packed seeds.
+func eventStreamSeeds(tb testing.TB) [][]byte {
+	tb.Helper()
+
+	var streams [][]byte
+
+	for _, seed := range fixtureSeeds(tb) {
+		var msg protocol.PackedForwardMessage
+		if _, err := msg.UnmarshalMsg(seed); err != nil {
+			continue
+		}
+
+		stream := msg.EventStream
+
+		if msg.Options != nil && msg.Options.Compressed == "gzip" {
+			zr, err := gzip.NewReader(bytes.NewReader(stream))
+			if err != nil {
+				tb.Fatal(err)
+			}
+
+			if stream, err = io.ReadAll(zr); err != nil {
+				tb.Fatal(err)
+			}
+		}
+
+		streams = append(streams, stream)
+	}
+
+	return streams
+}
+
+// handshakeSeeds returns marshaled HELO, PING and PONG messages.
+func handshakeSeeds(tb testing.TB) [][]byte {
+	tb.Helper()
+
+	key, salt, nonce := []byte("key"), []byte("salt"), []byte("nonce")
+
+	helo := protocol.NewHelo(&protocol.HeloOpts{Nonce: nonce, Auth: salt, Keepalive: true})
+
+	ping, err := protocol.NewPingWithAuth("client", key, salt, nonce, "user", "pass")
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	pong, err := protocol.NewPong(true, "", "server", key, helo, ping)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	var seeds [][]byte
+
+	for _, m := range []msgp.Marshaler{helo, ping, pong} {
+		b, err := m.MarshalMsg(nil)
+		if err != nil {
+			tb.Fatal(err)
+		}
+
+		seeds = append(seeds, b)
+	}
+
+	return seeds
+}
+
+func addSeeds(f *testing.F, seeds [][]byte) {
+	for _, s := range seeds {
+		f.Add(s)
+	}
+}
+
+// checkDecoder runs both the byte-slice and streaming decoders of a
+// message type over the same input. Whatever they accept must marshal
+// again. Decoding the result is not checked, as msgp reads extension
+// types 3-5 in a record as its own complex and time types only when
+// enough bytes follow them.
+func checkDecoder[T any, PT interface {
+	*T
+	msgp.Decodable
+	msgp.Marshaler
+	msgp.Unmarshaler
+}](t *testing.T, data []byte) {
+	var byBytes T
+	if _, err := PT(&byBytes).UnmarshalMsg(data); err == nil {
+		if _, err := PT(&byBytes).MarshalMsg(nil); err != nil {
+			t.Fatalf("re-marshal: %v", err)
+		}
+	}
+
+	var byReader T
+	_ = PT(&byReader).DecodeMsg(msgp.NewReader(bytes.NewReader(data)))
+}
+
+func FuzzMessage(f *testing.F) {
+	addSeeds(f, fixtureSeeds(f))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		checkDecoder[protocol.Message](t, data)
+	})
+}
+
+func FuzzMessageExt(f *testing.F) {
+	addSeeds(f, fixtureSeeds(f))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		checkDecoder[protocol.MessageExt](t, data)
+	})
+}
+
+func FuzzForwardMessage(f *testing.F) {
+	addSeeds(f, fixtureSeeds(f))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		checkDecoder[protocol.ForwardMessage](t, data)
+	})
+}
+
+func FuzzPackedForwardMessage(f *testing.F) {
+	addSeeds(f, fixtureSeeds(f))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		checkDecoder[protocol.PackedForwardMessage](t, data)
+	})
+}
+
+func FuzzGetChunk(f *testing.F) {
+	addSeeds(f, fixtureSeeds(f))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, _ = protocol.GetChunk(data)
+	})
+}
+
+// FuzzDecodeAny checks that the mode DecodeAny settles on agrees with
+// DetectMode, and that the event iterator never panics, whether it reads
+// from bytes or a stream.
+func FuzzDecodeAny(f *testing.F) {
+	addSeeds(f, fixtureSeeds(f))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if msg, _, err := protocol.DecodeAny(data); err == nil {
+			mode, err := protocol.DetectMode(data)
+			if err != nil {
+				t.Fatalf("detect mode: %v", err)
+			}
+
+			if mode != msg.Mode() {
+				t.Fatalf("detected %s, decoded %s", mode, msg.Mode())
+			}
+		}
+
+		for _, dec := range []*protocol.Decoder{
+			protocol.NewBytesDecoder(data, nil),
+			protocol.NewDecoder(msgp.NewReader(bytes.NewReader(data)), nil),
+		} {
+			it := dec.Events()
+			for it.Next() {
+				_ = it.Event()
+			}
+		}
+	})
+}
+
+// FuzzRawRecord checks that fields can be looked up in any input without
+// a panic, and that whatever Set accepts reads back the value it set.
+func FuzzRawRecord(f *testing.F) {
+	for _, m := range []map[string]interface{}{
+		{"a": "b"},
+		{"a": map[string]interface{}{"b": int64(1)}, "c": []interface{}{1.5, true}},
+	} {
+		rec, err := protocol.NewRawRecord(m)
+		if err != nil {
+			f.Fatal(err)
+		}
+
+		f.Add([]byte(rec))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		rec := protocol.RawRecord(data)
+		_, _ = rec.Get("a", "b")
+
+		updated, err := rec.SetPath([]string{"a", "b"}, "set")
+		if err != nil {
+			return
+		}
+
+		if v, err := updated.GetString("a", "b"); err != nil || v != "set" {
+			t.Fatalf("got %q, %v after set", v, err)
+		}
+	})
+}
+
+func FuzzEntryList(f *testing.F) {
+	addSeeds(f, eventStreamSeeds(f))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var el protocol.EntryList
+		if _, err := el.UnmarshalPacked(data); err != nil {
+			return
+		}
+
+		if _, err := el.MarshalPacked(); err != nil {
+			t.Fatalf("re-marshal: %v", err)
+		}
+	})
+}
+
+func FuzzEntryExt(f *testing.F) {
+	addSeeds(f, eventStreamSeeds(f))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		checkDecoder[protocol.EntryExt](t, data)
+	})
+}
+
+func FuzzHelo(f *testing.F) {
+	addSeeds(f, handshakeSeeds(f))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		checkDecoder[protocol.Helo](t, data)
+	})
+}
+
+func FuzzPing(f *testing.F) {
+	addSeeds(f, handshakeSeeds(f))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		checkDecoder[protocol.Ping](t, data)
+	})
+}
+
+func FuzzPong(f *testing.F) {
+	addSeeds(f, handshakeSeeds(f))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		checkDecoder[protocol.Pong](t, data)
+	})
+}
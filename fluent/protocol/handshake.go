@@ -29,6 +29,8 @@ import (
 	"encoding/hex"
 	"errors"
 	"io"
+
+	"github.com/tinylib/msgp/msgp"
 )
 
 //go:generate msgp
@@ -68,11 +70,18 @@ func NewHelo(opts *HeloOpts) *Helo {
 // by the client.  Client will respond with a Ping.
 //
 //msgp:tuple Helo
+//msgp:decode ignore Helo
 type Helo struct {
 	MessageType string
 	Options     *HeloOpts
 }
 
+// DecodeMsg implements msgp.Decodable. The message is read into a bounded
+// buffer before it is decoded.
+func (h *Helo) DecodeMsg(dc *msgp.Reader) error {
+	return defaultDecodeOptions.Decode(dc, h)
+}
+
 type HeloOpts struct {
 	Nonce     []byte `msg:"nonce"`
 	Auth      []byte `msg:"auth"`
@@ -114,6 +123,7 @@ func makePing(hostname string, sharedKey, salt, nonce []byte, creds ...string) (
 // Helo from the server.  Server will respond with a Pong.
 //
 //msgp:tuple Ping
+//msgp:decode ignore Ping
 type Ping struct {
 	MessageType        string
 	ClientHostname     string
@@ -123,6 +133,12 @@ type Ping struct {
 	Password           string
 }
 
+// DecodeMsg implements msgp.Decodable. The message is read into a bounded
+// buffer before it is decoded.
+func (p *Ping) DecodeMsg(dc *msgp.Reader) error {
+	return defaultDecodeOptions.Decode(dc, p)
+}
+
 // NewPong returns a PONG message.  AuthResult indicates
 // whether the credentials presented by the client were accepted and therefore
 // whether the client can continue using the connection, switching from
@@ -158,6 +174,7 @@ func NewPong(authResult bool, reason string, hostname string, sharedKey []byte,
 // Ping from the client.  A Pong concludes the handshake.
 //
 //msgp:tuple Pong
+//msgp:decode ignore Pong
 type Pong struct {
 	MessageType        string
 	AuthResult         bool
@@ -166,6 +183,12 @@ type Pong struct {
 	SharedKeyHexDigest string
 }
 
+// DecodeMsg implements msgp.Decodable. The message is read into a bounded
+// buffer before it is decoded.
+func (p *Pong) DecodeMsg(dc *msgp.Reader) error {
+	return defaultDecodeOptions.Decode(dc, p)
+}
+
 // ValidatePingDigest validates that the digest contained in the PING message
 // is valid for the client hostname (as contained in the PING).
 // Returns a non-nil error if validation fails, nil otherwise.
@@ -6,80 +6,6 @@ import (
 	"github.com/tinylib/msgp/msgp"
 )
 
-// DecodeMsg implements msgp.Decodable
-func (z *Helo) DecodeMsg(dc *msgp.Reader) (err error) {
-	var zb0001 uint32
-	zb0001, err = dc.ReadArrayHeader()
-	if err != nil {
-		err = msgp.WrapError(err)
-		return
-	}
-	if zb0001 != 2 {
-		err = msgp.ArrayError{Wanted: 2, Got: zb0001}
-		return
-	}
-	z.MessageType, err = dc.ReadString()
-	if err != nil {
-		err = msgp.WrapError(err, "MessageType")
-		return
-	}
-	if dc.IsNil() {
-		err = dc.ReadNil()
-		if err != nil {
-			err = msgp.WrapError(err, "Options")
-			return
-		}
-		z.Options = nil
-	} else {
-		if z.Options == nil {
-			z.Options = new(HeloOpts)
-		}
-		var field []byte
-		_ = field
-		var zb0002 uint32
-		zb0002, err = dc.ReadMapHeader()
-		if err != nil {
-			err = msgp.WrapError(err, "Options")
-			return
-		}
-		for zb0002 > 0 {
-			zb0002--
-			field, err = dc.ReadMapKeyPtr()
-			if err != nil {
-				err = msgp.WrapError(err, "Options")
-				return
-			}
-			switch msgp.UnsafeString(field) {
-			case "nonce":
-				z.Options.Nonce, err = dc.ReadBytes(z.Options.Nonce)
-				if err != nil {
-					err = msgp.WrapError(err, "Options", "Nonce")
-					return
-				}
-			case "auth":
-				z.Options.Auth, err = dc.ReadBytes(z.Options.Auth)
-				if err != nil {
-					err = msgp.WrapError(err, "Options", "Auth")
-					return
-				}
-			case "keepalive":
-				z.Options.Keepalive, err = dc.ReadBool()
-				if err != nil {
-					err = msgp.WrapError(err, "Options", "Keepalive")
-					return
-				}
-			default:
-				err = dc.Skip()
-				if err != nil {
-					err = msgp.WrapError(err, "Options")
-					return
-				}
-			}
-		}
-	}
-	return
-}
-
 // EncodeMsg implements msgp.Encodable
 func (z *Helo) EncodeMsg(en *msgp.Writer) (err error) {
 	// array header, size 2
@@ -394,51 +320,6 @@ func (z *HeloOpts) Msgsize() (s int) {
 	return
 }
 
-// DecodeMsg implements msgp.Decodable
-func (z *Ping) DecodeMsg(dc *msgp.Reader) (err error) {
-	var zb0001 uint32
-	zb0001, err = dc.ReadArrayHeader()
-	if err != nil {
-		err = msgp.WrapError(err)
-		return
-	}
-	if zb0001 != 6 {
-		err = msgp.ArrayError{Wanted: 6, Got: zb0001}
-		return
-	}
-	z.MessageType, err = dc.ReadString()
-	if err != nil {
-		err = msgp.WrapError(err, "MessageType")
-		return
-	}
-	z.ClientHostname, err = dc.ReadString()
-	if err != nil {
-		err = msgp.WrapError(err, "ClientHostname")
-		return
-	}
-	z.SharedKeySalt, err = dc.ReadBytes(z.SharedKeySalt)
-	if err != nil {
-		err = msgp.WrapError(err, "SharedKeySalt")
-		return
-	}
-	z.SharedKeyHexDigest, err = dc.ReadString()
-	if err != nil {
-		err = msgp.WrapError(err, "SharedKeyHexDigest")
-		return
-	}
-	z.Username, err = dc.ReadString()
-	if err != nil {
-		err = msgp.WrapError(err, "Username")
-		return
-	}
-	z.Password, err = dc.ReadString()
-	if err != nil {
-		err = msgp.WrapError(err, "Password")
-		return
-	}
-	return
-}
-
 // EncodeMsg implements msgp.Encodable
 func (z *Ping) EncodeMsg(en *msgp.Writer) (err error) {
 	// array header, size 6
@@ -545,46 +426,6 @@ func (z *Ping) Msgsize() (s int) {
 	return
 }
 
-// DecodeMsg implements msgp.Decodable
-func (z *Pong) DecodeMsg(dc *msgp.Reader) (err error) {
-	var zb0001 uint32
-	zb0001, err = dc.ReadArrayHeader()
-	if err != nil {
-		err = msgp.WrapError(err)
-		return
-	}
-	if zb0001 != 5 {
-		err = msgp.ArrayError{Wanted: 5, Got: zb0001}
-		return
-	}
-	z.MessageType, err = dc.ReadString()
-	if err != nil {
-		err = msgp.WrapError(err, "MessageType")
-		return
-	}
-	z.AuthResult, err = dc.ReadBool()
-	if err != nil {
-		err = msgp.WrapError(err, "AuthResult")
-		return
-	}
-	z.Reason, err = dc.ReadString()
-	if err != nil {
-		err = msgp.WrapError(err, "Reason")
-		return
-	}
-	z.ServerHostname, err = dc.ReadString()
-	if err != nil {
-		err = msgp.WrapError(err, "ServerHostname")
-		return
-	}
-	z.SharedKeyHexDigest, err = dc.ReadString()
-	if err != nil {
-		err = msgp.WrapError(err, "SharedKeyHexDigest")
-		return
-	}
-	return
-}
-
 // EncodeMsg implements msgp.Encodable
 func (z *Pong) EncodeMsg(en *msgp.Writer) (err error) {
 	// array header, size 5
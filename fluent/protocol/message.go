@@ -68,43 +68,14 @@ func NewMessage(
 }
 
 func (msg *Message) DecodeMsg(dc *msgp.Reader) error {
-	sz, err := dc.ReadArrayHeader()
-	if err != nil {
-		return msgp.WrapError(err, "Array Header")
-	}
-
-	if msg.Tag, err = dc.ReadString(); err != nil {
-		return msgp.WrapError(err, "Tag")
-	}
-
-	if msg.Timestamp, err = dc.ReadInt64(); err != nil {
-		return msgp.WrapError(err, "Timestamp")
-	}
-
-	if msg.Record, err = dc.ReadIntf(); err != nil {
-		return msgp.WrapError(err, "Record")
-	}
-
-	// has four elements only when options are included
-	if sz == 4 {
-		if t, err := dc.NextType(); t == msgp.NilType || err != nil {
-			if err != nil {
-				return msgp.WrapError(err, "Options")
-			}
-
-			return dc.ReadNil()
-		}
-
-		msg.Options = &MessageOptions{}
-		if err = msg.Options.DecodeMsg(dc); err != nil {
-			return msgp.WrapError(err, "Options")
-		}
-	}
-
-	return nil
+	return defaultDecodeOptions.Decode(dc, msg)
 }
 
 func (msg *Message) UnmarshalMsg(bits []byte) ([]byte, error) {
+	return msg.unmarshalLimited(bits, &defaultDecodeOptions)
+}
+
+func (msg *Message) unmarshalLimited(bits []byte, opts *DecodeOptions) ([]byte, error) {
 	var (
 		sz  uint32
 		err error
@@ -114,7 +85,7 @@ func (msg *Message) UnmarshalMsg(bits []byte) ([]byte, error) {
 		return bits, msgp.WrapError(err, "Array Header")
 	}
 
-	if msg.Tag, bits, err = msgp.ReadStringBytes(bits); err != nil {
+	if msg.Tag, bits, err = opts.ReadTagBytes(bits); err != nil {
 		return bits, msgp.WrapError(err, "Tag")
 	}
 
@@ -122,7 +93,7 @@ func (msg *Message) UnmarshalMsg(bits []byte) ([]byte, error) {
 		return bits, msgp.WrapError(err, "Timestamp")
 	}
 
-	if msg.Record, bits, err = msgp.ReadIntfBytes(bits); err != nil {
+	if msg.Record, bits, err = opts.ReadRecordBytes(bits); err != nil {
 		return bits, msgp.WrapError(err, "Record")
 	}
 
@@ -132,8 +103,7 @@ func (msg *Message) UnmarshalMsg(bits []byte) ([]byte, error) {
 			return msgp.ReadNilBytes(bits)
 		}
 
-		msg.Options = &MessageOptions{}
-		if bits, err = msg.Options.UnmarshalMsg(bits); err != nil {
+		if msg.Options, bits, err = opts.unmarshalOptions(bits); err != nil {
 			return bits, msgp.WrapError(err, "Options")
 		}
 	}
@@ -200,43 +170,14 @@ func NewMessageExt(
 }
 
 func (msg *MessageExt) DecodeMsg(dc *msgp.Reader) error {
-	sz, err := dc.ReadArrayHeader()
-	if err != nil {
-		return msgp.WrapError(err, "Array Header")
-	}
-
-	if msg.Tag, err = dc.ReadString(); err != nil {
-		return msgp.WrapError(err, "Tag")
-	}
-
-	if err = dc.ReadExtension(&msg.Timestamp); err != nil {
-		return msgp.WrapError(err, "Timestamp")
-	}
-
-	if msg.Record, err = dc.ReadIntf(); err != nil {
-		return msgp.WrapError(err, "Record")
-	}
-
-	// has four elements only when options are included
-	if sz == 4 {
-		if t, err := dc.NextType(); t == msgp.NilType || err != nil {
-			if err != nil {
-				return msgp.WrapError(err, "Options")
-			}
-
-			return dc.ReadNil()
-		}
-
-		msg.Options = &MessageOptions{}
-		if err = msg.Options.DecodeMsg(dc); err != nil {
-			return msgp.WrapError(err, "Options")
-		}
-	}
-
-	return nil
+	return defaultDecodeOptions.Decode(dc, msg)
 }
 
 func (msg *MessageExt) UnmarshalMsg(bits []byte) ([]byte, error) {
+	return msg.unmarshalLimited(bits, &defaultDecodeOptions)
+}
+
+func (msg *MessageExt) unmarshalLimited(bits []byte, opts *DecodeOptions) ([]byte, error) {
 	var (
 		sz  uint32
 		err error
@@ -246,7 +187,7 @@ func (msg *MessageExt) UnmarshalMsg(bits []byte) ([]byte, error) {
 		return bits, msgp.WrapError(err, "Array Header")
 	}
 
-	if msg.Tag, bits, err = msgp.ReadStringBytes(bits); err != nil {
+	if msg.Tag, bits, err = opts.ReadTagBytes(bits); err != nil {
 		return bits, msgp.WrapError(err, "Tag")
 	}
 
@@ -254,7 +195,7 @@ func (msg *MessageExt) UnmarshalMsg(bits []byte) ([]byte, error) {
 		return bits, msgp.WrapError(err, "Timestamp")
 	}
 
-	if msg.Record, bits, err = msgp.ReadIntfBytes(bits); err != nil {
+	if msg.Record, bits, err = opts.ReadRecordBytes(bits); err != nil {
 		return bits, msgp.WrapError(err, "Record")
 	}
 
@@ -264,8 +205,7 @@ func (msg *MessageExt) UnmarshalMsg(bits []byte) ([]byte, error) {
 			return msgp.ReadNilBytes(bits)
 		}
 
-		msg.Options = &MessageOptions{}
-		if bits, err = msg.Options.UnmarshalMsg(bits); err != nil {
+		if msg.Options, bits, err = opts.unmarshalOptions(bits); err != nil {
 			return bits, msgp.WrapError(err, "Options")
 		}
 	}
@@ -90,35 +90,14 @@ func NewPackedForwardMessageFromBytes(
 }
 
 func (msg *PackedForwardMessage) DecodeMsg(dc *msgp.Reader) error {
-	sz, err := dc.ReadArrayHeader()
-	if err != nil {
-		return msgp.WrapError(err, "Array Header")
-	}
-
-	if msg.Tag, err = dc.ReadString(); err != nil {
-		return msgp.WrapError(err, "Tag")
-	}
-
-	if msg.EventStream, err = dc.ReadBytes(msg.EventStream); err != nil {
-		return msgp.WrapError(err, "EventStream")
-	}
-
-	// has three elements only when options are included
-	if sz == 3 {
-		if t, _ := dc.NextType(); t == msgp.NilType {
-			return dc.ReadNil()
-		}
-
-		msg.Options = &MessageOptions{}
-		if err = msg.Options.DecodeMsg(dc); err != nil {
-			return msgp.WrapError(err, "Options")
-		}
-	}
-
-	return nil
+	return defaultDecodeOptions.Decode(dc, msg)
 }
 
 func (msg *PackedForwardMessage) UnmarshalMsg(bits []byte) ([]byte, error) {
+	return msg.unmarshalLimited(bits, &defaultDecodeOptions)
+}
+
+func (msg *PackedForwardMessage) unmarshalLimited(bits []byte, opts *DecodeOptions) ([]byte, error) {
 	var (
 		sz  uint32
 		err error
@@ -128,11 +107,20 @@ func (msg *PackedForwardMessage) UnmarshalMsg(bits []byte) ([]byte, error) {
 		return bits, msgp.WrapError(err, "Array Header")
 	}
 
-	if msg.Tag, bits, err = msgp.ReadStringBytes(bits); err != nil {
+	if msg.Tag, bits, err = opts.ReadTagBytes(bits); err != nil {
 		return bits, msgp.WrapError(err, "Tag")
 	}
 
-	if msg.EventStream, bits, err = msgp.ReadBytesBytes(bits, msg.EventStream); err != nil {
+	// the stream is usually bin, but some clients send it as str
+	if msgp.NextType(bits) == msgp.StrType {
+		var stream []byte
+
+		if stream, bits, err = msgp.ReadStringZC(bits); err != nil {
+			return bits, msgp.WrapError(err, "EventStream")
+		}
+
+		msg.EventStream = append(msg.EventStream[:0], stream...)
+	} else if msg.EventStream, bits, err = msgp.ReadBytesBytes(bits, msg.EventStream); err != nil {
 		return bits, msgp.WrapError(err, "EventStream")
 	}
 
@@ -142,8 +130,7 @@ func (msg *PackedForwardMessage) UnmarshalMsg(bits []byte) ([]byte, error) {
 			return msgp.ReadNilBytes(bits)
 		}
 
-		msg.Options = &MessageOptions{}
-		if bits, err = msg.Options.UnmarshalMsg(bits); err != nil {
+		if msg.Options, bits, err = opts.unmarshalOptions(bits); err != nil {
 			return bits, msgp.WrapError(err, "Options")
 		}
 	}
@@ -0,0 +1,414 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package protocol
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tinylib/msgp/msgp"
+)
+
+// ErrFieldNotFound is returned when a path does not lead to a field in a
+// RawRecord.
+var ErrFieldNotFound = errors.New("field not found")
+
+// RawRecord is a record left in its msgpack encoding. Fields are read
+// from it on demand, without decoding the rest of the record, and set by
+// splicing their new encoding into a copy of it. Records are decoded as
+// RawRecords when DecodeOptions.RawRecords is set, and a RawRecord can be
+// sent as the record of any message.
+type RawRecord []byte
+
+// NewRawRecord encodes v, which must be a map or an object that encodes
+// as one, as a RawRecord.
+func NewRawRecord(v interface{}) (RawRecord, error) {
+	bts, err := msgp.AppendIntf(nil, v)
+	if err != nil {
+		return nil, err
+	}
+
+	if msgp.NextType(bts) != msgp.MapType {
+		return nil, fmt.Errorf("record is %s, not a map", msgp.NextType(bts))
+	}
+
+	return RawRecord(bts), nil
+}
+
+// Get returns the value at path, where each element of path is a key in
+// the map found by the elements before it. It returns an error wrapping
+// ErrFieldNotFound if there is no such value.
+//
+//	ns, err := rec.Get("kubernetes", "namespace_name")
+func (r RawRecord) Get(path ...string) (RawValue, error) {
+	bts := []byte(r)
+
+	for i, key := range path {
+		if len(bts) == 0 {
+			return nil, notFound(path[:i+1])
+		}
+
+		start, end, err := findField(bts, key)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", strings.Join(path[:i+1], "."), err)
+		}
+
+		if start < 0 {
+			return nil, notFound(path[:i+1])
+		}
+
+		bts = bts[start:end]
+	}
+
+	return RawValue(bts), nil
+}
+
+// GetString returns the string at path. Binary values are accepted as
+// well, since some clients send strings as such.
+func (r RawRecord) GetString(path ...string) (string, error) {
+	v, err := r.Get(path...)
+	if err != nil {
+		return "", err
+	}
+
+	return v.String()
+}
+
+// GetInt returns the integer at path.
+func (r RawRecord) GetInt(path ...string) (int64, error) {
+	v, err := r.Get(path...)
+	if err != nil {
+		return 0, err
+	}
+
+	return v.Int()
+}
+
+// GetUint returns the unsigned integer at path.
+func (r RawRecord) GetUint(path ...string) (uint64, error) {
+	v, err := r.Get(path...)
+	if err != nil {
+		return 0, err
+	}
+
+	return v.Uint()
+}
+
+// GetFloat returns the floating point number at path.
+func (r RawRecord) GetFloat(path ...string) (float64, error) {
+	v, err := r.Get(path...)
+	if err != nil {
+		return 0, err
+	}
+
+	return v.Float()
+}
+
+// GetBool returns the boolean at path.
+func (r RawRecord) GetBool(path ...string) (bool, error) {
+	v, err := r.Get(path...)
+	if err != nil {
+		return false, err
+	}
+
+	return v.Bool()
+}
+
+// GetRecord returns the map at path as a RawRecord.
+func (r RawRecord) GetRecord(path ...string) (RawRecord, error) {
+	v, err := r.Get(path...)
+	if err != nil {
+		return nil, err
+	}
+
+	return v.Record()
+}
+
+// Set returns a copy of the record with key set to value, replacing the
+// existing value or appending the key if the record has none.
+func (r RawRecord) Set(key string, value interface{}) (RawRecord, error) {
+	return r.SetPath([]string{key}, value)
+}
+
+// SetPath returns a copy of the record with the value at path set to
+// value. Maps missing along the path are created; a value along the path
+// that is not a map is an error. The rest of the record is copied as it
+// is, without being decoded.
+func (r RawRecord) SetPath(path []string, value interface{}) (RawRecord, error) {
+	if len(path) == 0 {
+		return nil, errors.New("empty path")
+	}
+
+	enc, err := msgp.AppendIntf(nil, value)
+	if err != nil {
+		return nil, err
+	}
+
+	bts := []byte(r)
+	if len(bts) == 0 {
+		bts = msgp.AppendMapHeader(nil, 0)
+	}
+
+	if bts, err = setField(bts, path, enc); err != nil {
+		return nil, fmt.Errorf("%s: %w", strings.Join(path, "."), err)
+	}
+
+	return RawRecord(bts), nil
+}
+
+// Interface decodes the whole record.
+func (r RawRecord) Interface() (interface{}, error) {
+	return RawValue(r).Interface()
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (r RawRecord) MarshalMsg(b []byte) ([]byte, error) {
+	return RawValue(r).MarshalMsg(b)
+}
+
+// EncodeMsg implements msgp.Encodable
+func (r RawRecord) EncodeMsg(en *msgp.Writer) error {
+	return RawValue(r).EncodeMsg(en)
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler. The record is copied from
+// bts once it has passed the checks of DefaultDecodeOptions.
+func (r *RawRecord) UnmarshalMsg(bts []byte) ([]byte, error) {
+	v, rest, err := defaultDecodeOptions.readRawRecordBytes(bts)
+	if err != nil {
+		return bts, err
+	}
+
+	*r = v
+
+	return rest, nil
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
+func (r RawRecord) Msgsize() int {
+	return RawValue(r).Msgsize()
+}
+
+// RawValue is the msgpack encoding of a single value in a RawRecord. It
+// shares memory with the record it was read from.
+type RawValue []byte
+
+// Type returns the msgpack type of the value.
+func (v RawValue) Type() msgp.Type {
+	return msgp.NextType(v)
+}
+
+// IsNil reports whether the value is nil.
+func (v RawValue) IsNil() bool {
+	return msgp.IsNil(v)
+}
+
+// String returns the value as a string. Binary values are accepted as
+// well, since some clients send strings as such.
+func (v RawValue) String() (string, error) {
+	if v.Type() == msgp.BinType {
+		b, _, err := msgp.ReadBytesZC(v)
+		return string(b), err
+	}
+
+	s, _, err := msgp.ReadStringBytes(v)
+
+	return s, err
+}
+
+// Bytes returns the contents of a bin or str value, without copying them.
+func (v RawValue) Bytes() ([]byte, error) {
+	b, _, err := msgp.ReadMapKeyZC(v)
+	return b, err
+}
+
+// Int returns the value as a signed integer.
+func (v RawValue) Int() (int64, error) {
+	i, _, err := msgp.ReadInt64Bytes(v)
+	return i, err
+}
+
+// Uint returns the value as an unsigned integer.
+func (v RawValue) Uint() (uint64, error) {
+	u, _, err := msgp.ReadUint64Bytes(v)
+	return u, err
+}
+
+// Float returns the value as a floating point number.
+func (v RawValue) Float() (float64, error) {
+	f, _, err := msgp.ReadFloat64Bytes(v)
+	return f, err
+}
+
+// Bool returns the value as a boolean.
+func (v RawValue) Bool() (bool, error) {
+	b, _, err := msgp.ReadBoolBytes(v)
+	return b, err
+}
+
+// Record returns a map value as a RawRecord.
+func (v RawValue) Record() (RawRecord, error) {
+	if t := v.Type(); t != msgp.MapType {
+		return nil, msgp.TypeError{Method: msgp.MapType, Encoded: t}
+	}
+
+	return RawRecord(v), nil
+}
+
+// Interface decodes the value within the limits of DefaultDecodeOptions.
+func (v RawValue) Interface() (interface{}, error) {
+	if err := defaultDecodeOptions.checkRecordBytes(v); err != nil {
+		return nil, err
+	}
+
+	i, _, err := msgp.ReadIntfBytes(v)
+
+	return i, err
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (v RawValue) MarshalMsg(b []byte) ([]byte, error) {
+	if len(v) == 0 {
+		return msgp.AppendNil(b), nil
+	}
+
+	return append(b, v...), nil
+}
+
+// EncodeMsg implements msgp.Encodable
+func (v RawValue) EncodeMsg(en *msgp.Writer) error {
+	if len(v) == 0 {
+		return en.WriteNil()
+	}
+
+	return en.Append(v...)
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
+func (v RawValue) Msgsize() int {
+	if len(v) == 0 {
+		return msgp.NilSize
+	}
+
+	return len(v)
+}
+
+func notFound(path []string) error {
+	return fmt.Errorf("%w: %s", ErrFieldNotFound, strings.Join(path, "."))
+}
+
+// findField locates key in the msgpack map at the start of bts and
+// returns the offsets of its value, or -1 if the map has no such key.
+// Keys may be str or bin. Values are skipped without being decoded.
+func findField(bts []byte, key string) (int, int, error) {
+	sz, b, err := msgp.ReadMapHeaderBytes(bts)
+	if err != nil {
+		return -1, -1, err
+	}
+
+	for i := uint32(0); i < sz; i++ {
+		var k []byte
+
+		if k, b, err = msgp.ReadMapKeyZC(b); err != nil {
+			return -1, -1, err
+		}
+
+		start := len(bts) - len(b)
+
+		if b, err = skipObjectBytes(b); err != nil {
+			return -1, -1, err
+		}
+
+		if string(k) == key {
+			return start, len(bts) - len(b), nil
+		}
+	}
+
+	return -1, -1, nil
+}
+
+// setField returns a copy of the map at the start of bts, and whatever
+// follows it, with the value at path replaced by enc.
+func setField(bts []byte, path []string, enc []byte) ([]byte, error) {
+	start, end, err := findField(bts, path[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if start < 0 {
+		return appendField(bts, path, enc)
+	}
+
+	val := enc
+
+	if len(path) > 1 {
+		if t := msgp.NextType(bts[start:end]); t != msgp.MapType {
+			return nil, msgp.TypeError{Method: msgp.MapType, Encoded: t}
+		}
+
+		if val, err = setField(bts[start:end], path[1:], enc); err != nil {
+			return nil, err
+		}
+	}
+
+	out := make([]byte, 0, len(bts)-(end-start)+len(val))
+	out = append(out, bts[:start]...)
+	out = append(out, val...)
+
+	return append(out, bts[end:]...), nil
+}
+
+// appendField returns a copy of the map at the start of bts with a field
+// added for path, nesting enc in new maps for each element of path after
+// the first.
+func appendField(bts []byte, path []string, enc []byte) ([]byte, error) {
+	sz, pairs, err := msgp.ReadMapHeaderBytes(bts)
+	if err != nil {
+		return nil, err
+	}
+
+	rest, err := skipObjectBytes(bts)
+	if err != nil {
+		return nil, err
+	}
+
+	// the header may grow, e.g. from a fixmap to a map16
+	out := make([]byte, 0, len(bts)+len(enc)+(len(path)+1)*(msgp.MapHeaderSize+msgp.StringPrefixSize))
+	out = msgp.AppendMapHeader(out, sz+1)
+	out = append(out, pairs[:len(pairs)-len(rest)]...)
+
+	for i, key := range path {
+		out = msgp.AppendString(out, key)
+
+		if i < len(path)-1 {
+			out = msgp.AppendMapHeader(out, 1)
+		}
+	}
+
+	out = append(out, enc...)
+
+	return append(out, rest...), nil
+}
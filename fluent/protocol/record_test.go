@@ -0,0 +1,199 @@
+package protocol_test
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/tinylib/msgp/msgp"
+
+	"github.com/IBM/fluent-forward-go/fluent/protocol"
+)
+
+var _ = Describe("RawRecord", func() {
+	var rec protocol.RawRecord
+
+	decode := func(r protocol.RawRecord) map[string]interface{} {
+		v, err := r.Interface()
+		Expect(err).NotTo(HaveOccurred())
+
+		return v.(map[string]interface{})
+	}
+
+	BeforeEach(func() {
+		var err error
+
+		rec, err = protocol.NewRawRecord(map[string]interface{}{
+			"log":     "hello",
+			"level":   int64(-3),
+			"bytes":   uint64(1 << 40),
+			"ratio":   0.25,
+			"matched": true,
+			"kubernetes": map[string]interface{}{
+				"namespace_name": "prod",
+				"labels": map[string]interface{}{
+					"app.kubernetes.io/name": "api",
+				},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Describe("Get", func() {
+		It("looks up values by path", func() {
+			Expect(rec.GetString("kubernetes", "namespace_name")).To(Equal("prod"))
+			Expect(rec.GetString("kubernetes", "labels", "app.kubernetes.io/name")).To(Equal("api"))
+			Expect(rec.GetString("log")).To(Equal("hello"))
+			Expect(rec.GetInt("level")).To(Equal(int64(-3)))
+			Expect(rec.GetUint("bytes")).To(Equal(uint64(1 << 40)))
+			Expect(rec.GetFloat("ratio")).To(Equal(0.25))
+			Expect(rec.GetBool("matched")).To(BeTrue())
+
+			k8s, err := rec.GetRecord("kubernetes")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8s.GetString("namespace_name")).To(Equal("prod"))
+
+			v, err := rec.Get("kubernetes", "labels")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(v.Type()).To(Equal(msgp.MapType))
+			Expect(v.Interface()).To(Equal(map[string]interface{}{"app.kubernetes.io/name": "api"}))
+		})
+
+		It("reports a missing field with its path", func() {
+			_, err := rec.Get("kubernetes", "pod_name")
+			Expect(errors.Is(err, protocol.ErrFieldNotFound)).To(BeTrue())
+			Expect(err).To(MatchError(ContainSubstring("kubernetes.pod_name")))
+
+			_, err = protocol.RawRecord(nil).Get("log")
+			Expect(errors.Is(err, protocol.ErrFieldNotFound)).To(BeTrue())
+		})
+
+		It("reports values of the wrong type", func() {
+			_, err := rec.GetInt("log")
+			Expect(err).To(BeAssignableToTypeOf(msgp.TypeError{}))
+
+			_, err = rec.Get("log", "nested")
+			Expect(err).To(MatchError(ContainSubstring("log.nested")))
+		})
+
+		It("reads binary values and keys as strings", func() {
+			bits := msgp.AppendMapHeader(nil, 1)
+			bits = msgp.AppendBytes(bits, []byte("key"))
+			bits = msgp.AppendBytes(bits, []byte("value"))
+
+			Expect(protocol.RawRecord(bits).GetString("key")).To(Equal("value"))
+		})
+
+		It("rejects truncated records", func() {
+			_, err := rec[:len(rec)-1].Get("nothing")
+			Expect(err).To(MatchError(ContainSubstring(msgp.ErrShortBytes.Error())))
+		})
+	})
+
+	Describe("Set", func() {
+		It("overwrites an existing field without touching the others", func() {
+			updated, err := rec.Set("log", "goodbye")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(updated.GetString("log")).To(Equal("goodbye"))
+			Expect(rec.GetString("log")).To(Equal("hello"))
+
+			expected := decode(rec)
+			expected["log"] = "goodbye"
+			Expect(decode(updated)).To(Equal(expected))
+		})
+
+		It("appends a missing field", func() {
+			updated, err := rec.Set("cluster", "east")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(updated.GetString("cluster")).To(Equal("east"))
+			Expect(decode(updated)).To(HaveLen(len(decode(rec)) + 1))
+		})
+
+		It("sets nested fields, creating maps as needed", func() {
+			updated, err := rec.SetPath([]string{"kubernetes", "namespace_name"}, "staging")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(updated.GetString("kubernetes", "namespace_name")).To(Equal("staging"))
+			Expect(updated.GetString("kubernetes", "labels", "app.kubernetes.io/name")).To(Equal("api"))
+
+			updated, err = updated.SetPath([]string{"kubernetes", "annotations", "team"}, "core")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(updated.GetString("kubernetes", "annotations", "team")).To(Equal("core"))
+
+			fresh, err := protocol.RawRecord(nil).SetPath([]string{"a", "b"}, int64(1))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(decode(fresh)).To(Equal(map[string]interface{}{
+				"a": map[string]interface{}{"b": int64(1)},
+			}))
+		})
+
+		It("refuses to set a field beneath a value that is not a map", func() {
+			_, err := rec.SetPath([]string{"log", "message"}, "x")
+			Expect(err).To(MatchError(ContainSubstring("log.message")))
+		})
+
+		It("grows the map header past fifteen fields", func() {
+			m := map[string]interface{}{}
+			for i := 0; i < 15; i++ {
+				m[fmt.Sprint(i)] = int64(i)
+			}
+
+			r, err := protocol.NewRawRecord(m)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(r[0]).To(Equal(byte(0x8f)))
+
+			r, err = r.Set("15", int64(15))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(r[0]).To(Equal(byte(0xde)))
+			Expect(decode(r)).To(HaveLen(16))
+			Expect(r.GetInt("15")).To(Equal(int64(15)))
+		})
+
+		It("splices raw values from another record", func() {
+			labels, err := rec.Get("kubernetes", "labels")
+			Expect(err).NotTo(HaveOccurred())
+
+			r, err := protocol.RawRecord(nil).Set("labels", labels)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(r.GetString("labels", "app.kubernetes.io/name")).To(Equal("api"))
+		})
+	})
+
+	When("DecodeOptions.RawRecords is set", func() {
+		It("decodes records without unpacking them and encodes them as they were", func() {
+			entries := protocol.EntryList{
+				{Timestamp: protocol.EventTimeNow(), Record: map[string]interface{}{"a": "b"}},
+				{Timestamp: protocol.EventTime{Time: time.Unix(1, 0)}, Record: rec},
+			}
+
+			bits, err := protocol.NewForwardMessage("tag", entries).MarshalMsg(nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			opts := protocol.DefaultDecodeOptions()
+			opts.RawRecords = true
+
+			msg, _, err := opts.DecodeAny(bits)
+			Expect(err).NotTo(HaveOccurred())
+
+			fm := msg.(*protocol.ForwardMessage)
+			Expect(fm.Entries[0].Record).To(BeAssignableToTypeOf(protocol.RawRecord{}))
+			Expect(fm.Entries[0].Record.(protocol.RawRecord).GetString("a")).To(Equal("b"))
+			Expect(fm.Entries[1].Record).To(Equal(rec))
+
+			Expect(msg.MarshalMsg(nil)).To(Equal(bits))
+		})
+	})
+
+	It("round-trips through msgp", func() {
+		bits, err := rec.MarshalMsg(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(bits).To(Equal([]byte(rec)))
+
+		var r protocol.RawRecord
+		rest, err := r.UnmarshalMsg(append(bits, 0xc0))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rest).To(Equal([]byte{0xc0}))
+		Expect(r).To(Equal(rec))
+	})
+})
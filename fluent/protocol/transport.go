@@ -53,9 +53,9 @@ const (
 )
 
 var (
-	compressorPool  sync.Pool
-	chunkReaderPool sync.Pool
-	bufferPool      sync.Pool
+	compressorPool   sync.Pool
+	bufferPool       sync.Pool
+	decodeBufferPool sync.Pool
 )
 
 func init() {
@@ -69,11 +69,11 @@ func init() {
 		return new(GzipCompressor)
 	}
 
-	chunkReaderPool.New = func() interface{} {
-		return new(ChunkReader)
+	bufferPool.New = func() interface{} {
+		return new(bytes.Buffer)
 	}
 
-	bufferPool.New = func() interface{} {
+	decodeBufferPool.New = func() interface{} {
 		return new(bytes.Buffer)
 	}
 }
@@ -127,9 +127,16 @@ func (et *EventTime) UnmarshalBinary(timeBytes []byte) error {
 }
 
 // EntryExt is the basic representation of an individual event, but using the
-// msgpack extension format for the timestamp.
+// msgpack extension format for the timestamp. An entry with Metadata is
+// encoded in the Fluent Bit v2 format, [[time, metadata], record]. Both
+// formats are decoded.
 //
 //msgp:tuple EntryExt
+//msgp:encode ignore EntryExt
+//msgp:decode ignore EntryExt
+//msgp:marshal ignore EntryExt
+//msgp:unmarshal ignore EntryExt
+//msgp:size ignore EntryExt
 type EntryExt struct {
 	// Timestamp can contain the timestamp in either seconds or nanoseconds
 	Timestamp EventTime `msg:"eventTime,extension"`
@@ -137,11 +144,244 @@ type EntryExt struct {
 	// struct. Objects that implement the msgp.Encodable interface will
 	// be the most performant.
 	Record interface{}
+	// Metadata is the optional Fluent Bit v2 entry metadata.
+	Metadata map[string]interface{} `msg:"-"`
+}
+
+// DecodeMsg implements msgp.Decodable
+func (z *EntryExt) DecodeMsg(dc *msgp.Reader) error {
+	return defaultDecodeOptions.Decode(dc, z)
+}
+
+// EncodeMsg implements msgp.Encodable
+func (z EntryExt) EncodeMsg(en *msgp.Writer) error {
+	// array header, size 2
+	if err := en.Append(0x92); err != nil {
+		return err
+	}
+
+	if z.Metadata != nil {
+		if err := en.Append(0x92); err != nil {
+			return err
+		}
+	}
+
+	if err := en.WriteExtension(&z.Timestamp); err != nil {
+		return msgp.WrapError(err, "Timestamp")
+	}
+
+	if z.Metadata != nil {
+		if err := en.WriteMapStrIntf(z.Metadata); err != nil {
+			return msgp.WrapError(err, "Metadata")
+		}
+	}
+
+	if err := en.WriteIntf(z.Record); err != nil {
+		return msgp.WrapError(err, "Record")
+	}
+
+	return nil
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (z EntryExt) MarshalMsg(b []byte) ([]byte, error) {
+	o := msgp.Require(b, z.Msgsize())
+	// array header, size 2
+	o = append(o, 0x92)
+
+	if z.Metadata != nil {
+		o = append(o, 0x92)
+	}
+
+	o, err := msgp.AppendExtension(o, &z.Timestamp)
+	if err != nil {
+		return o, msgp.WrapError(err, "Timestamp")
+	}
+
+	if z.Metadata != nil {
+		if o, err = msgp.AppendMapStrIntf(o, z.Metadata); err != nil {
+			return o, msgp.WrapError(err, "Metadata")
+		}
+	}
+
+	if o, err = msgp.AppendIntf(o, z.Record); err != nil {
+		return o, msgp.WrapError(err, "Record")
+	}
+
+	return o, nil
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (z *EntryExt) UnmarshalMsg(bts []byte) ([]byte, error) {
+	return z.unmarshalLimited(bts, &defaultDecodeOptions)
+}
+
+func (z *EntryExt) unmarshalLimited(bts []byte, opts *DecodeOptions) ([]byte, error) {
+	sz, bts, err := msgp.ReadArrayHeaderBytes(bts)
+	if err != nil {
+		return bts, msgp.WrapError(err)
+	}
+
+	if sz != 2 {
+		return bts, msgp.ArrayError{Wanted: 2, Got: sz}
+	}
+
+	z.Metadata = nil
+	v2 := msgp.NextType(bts) == msgp.ArrayType
+
+	if v2 {
+		if sz, bts, err = msgp.ReadArrayHeaderBytes(bts); err != nil {
+			return bts, msgp.WrapError(err, "Header")
+		}
+
+		if sz != 2 {
+			return bts, msgp.WrapError(msgp.ArrayError{Wanted: 2, Got: sz}, "Header")
+		}
+	}
+
+	if z.Timestamp, bts, err = readEventTimeBytes(bts); err != nil {
+		return bts, msgp.WrapError(err, "Timestamp")
+	}
+
+	if v2 {
+		if z.Metadata, bts, err = opts.ReadMetadataBytes(bts); err != nil {
+			return bts, msgp.WrapError(err, "Metadata")
+		}
+	}
+
+	if z.Record, bts, err = opts.ReadRecordBytes(bts); err != nil {
+		return bts, msgp.WrapError(err, "Record")
+	}
+
+	return bts, nil
+}
+
+// readEventTimeBytes reads a timestamp encoded either as an EventTime
+// extension or, as some clients send it, as an integer number of seconds.
+func readEventTimeBytes(bts []byte) (EventTime, []byte, error) {
+	var (
+		et  EventTime
+		err error
+	)
+
+	if msgp.NextType(bts) == msgp.ExtensionType {
+		bts, err = msgp.ReadExtensionBytes(bts, &et)
+		return et, bts, err
+	}
+
+	var secs int64
+
+	if secs, bts, err = msgp.ReadInt64Bytes(bts); err != nil {
+		return et, bts, err
+	}
+
+	et.Time = time.Unix(secs, 0).UTC()
+
+	return et, bts, nil
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
+func (z EntryExt) Msgsize() int {
+	s := 1 + msgp.ExtensionPrefixSize + z.Timestamp.Len() + msgp.GuessSize(z.Record)
+
+	if z.Metadata != nil {
+		s += 1 + msgp.MapHeaderSize
+		for k, v := range z.Metadata {
+			s += msgp.StringPrefixSize + len(k) + msgp.GuessSize(v)
+		}
+	}
+
+	return s
 }
 
+// EntryList is a list of entries, encoded as an array of EntryExt.
+//
+//msgp:encode ignore EntryList
+//msgp:decode ignore EntryList
+//msgp:marshal ignore EntryList
+//msgp:unmarshal ignore EntryList
+//msgp:size ignore EntryList
 type EntryList []EntryExt
 
+// DecodeMsg implements msgp.Decodable
+func (z *EntryList) DecodeMsg(dc *msgp.Reader) error {
+	return defaultDecodeOptions.Decode(dc, z)
+}
+
+// EncodeMsg implements msgp.Encodable
+func (z EntryList) EncodeMsg(en *msgp.Writer) error {
+	if err := en.WriteArrayHeader(uint32(len(z))); err != nil {
+		return msgp.WrapError(err)
+	}
+
+	for i := range z {
+		if err := z[i].EncodeMsg(en); err != nil {
+			return msgp.WrapError(err, i)
+		}
+	}
+
+	return nil
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (z EntryList) MarshalMsg(b []byte) ([]byte, error) {
+	o := msgp.Require(b, z.Msgsize())
+	o = msgp.AppendArrayHeader(o, uint32(len(z)))
+
+	var err error
+
+	for i := range z {
+		if o, err = z[i].MarshalMsg(o); err != nil {
+			return o, msgp.WrapError(err, i)
+		}
+	}
+
+	return o, nil
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (z *EntryList) UnmarshalMsg(bts []byte) ([]byte, error) {
+	return z.unmarshalLimited(bts, &defaultDecodeOptions)
+}
+
+func (z *EntryList) unmarshalLimited(bts []byte, opts *DecodeOptions) ([]byte, error) {
+	sz, bts, err := opts.readEntriesHeaderBytes(bts)
+	if err != nil {
+		return bts, msgp.WrapError(err)
+	}
+
+	if cap(*z) >= int(sz) {
+		*z = (*z)[:sz]
+	} else {
+		*z = make(EntryList, sz)
+	}
+
+	for i := range *z {
+		if bts, err = (*z)[i].unmarshalLimited(bts, opts); err != nil {
+			return bts, msgp.WrapError(err, i)
+		}
+	}
+
+	return bts, nil
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
+func (z EntryList) Msgsize() int {
+	s := msgp.ArrayHeaderSize
+	for i := range z {
+		s += z[i].Msgsize()
+	}
+
+	return s
+}
+
 func (el *EntryList) UnmarshalPacked(bits []byte) ([]byte, error) {
+	return defaultDecodeOptions.UnmarshalPacked(bits, el)
+}
+
+// UnmarshalPacked unmarshals the concatenated entries of an uncompressed
+// PackedForward event stream into el.
+func (opts *DecodeOptions) UnmarshalPacked(bits []byte, el *EntryList) ([]byte, error) {
 	var (
 		entry EntryExt
 		err   error
@@ -150,7 +390,11 @@ func (el *EntryList) UnmarshalPacked(bits []byte) ([]byte, error) {
 	*el = (*el)[:0]
 
 	for len(bits) > 0 {
-		if bits, err = entry.UnmarshalMsg(bits); err != nil {
+		if err = opts.CheckEntries(len(*el) + 1); err != nil {
+			break
+		}
+
+		if bits, err = entry.unmarshalLimited(bits, opts); err != nil {
 			break
 		}
 
@@ -226,10 +470,17 @@ type MessageOptions struct {
 	Compressed string `msg:"compressed,omitempty"`
 }
 
+//msgp:decode ignore AckMessage
 type AckMessage struct {
 	Ack string `msg:"ack"`
 }
 
+// DecodeMsg implements msgp.Decodable. The message is read into a bounded
+// buffer before it is decoded.
+func (z *AckMessage) DecodeMsg(dc *msgp.Reader) error {
+	return defaultDecodeOptions.Decode(dc, z)
+}
+
 // RawMessage is a ChunkEncoder wrapper for []byte.
 //
 //msgp:encode ignore RawMessage
@@ -6,41 +6,6 @@ import (
 	"github.com/tinylib/msgp/msgp"
 )
 
-// DecodeMsg implements msgp.Decodable
-func (z *AckMessage) DecodeMsg(dc *msgp.Reader) (err error) {
-	var field []byte
-	_ = field
-	var zb0001 uint32
-	zb0001, err = dc.ReadMapHeader()
-	if err != nil {
-		err = msgp.WrapError(err)
-		return
-	}
-	for zb0001 > 0 {
-		zb0001--
-		field, err = dc.ReadMapKeyPtr()
-		if err != nil {
-			err = msgp.WrapError(err)
-			return
-		}
-		switch msgp.UnsafeString(field) {
-		case "ack":
-			z.Ack, err = dc.ReadString()
-			if err != nil {
-				err = msgp.WrapError(err, "Ack")
-				return
-			}
-		default:
-			err = dc.Skip()
-			if err != nil {
-				err = msgp.WrapError(err)
-				return
-			}
-		}
-	}
-	return
-}
-
 // EncodeMsg implements msgp.Encodable
 func (z AckMessage) EncodeMsg(en *msgp.Writer) (err error) {
 	// map header, size 1
@@ -200,235 +165,6 @@ func (z Entry) Msgsize() (s int) {
 	return
 }
 
-// DecodeMsg implements msgp.Decodable
-func (z *EntryExt) DecodeMsg(dc *msgp.Reader) (err error) {
-	var zb0001 uint32
-	zb0001, err = dc.ReadArrayHeader()
-	if err != nil {
-		err = msgp.WrapError(err)
-		return
-	}
-	if zb0001 != 2 {
-		err = msgp.ArrayError{Wanted: 2, Got: zb0001}
-		return
-	}
-	err = dc.ReadExtension(&z.Timestamp)
-	if err != nil {
-		err = msgp.WrapError(err, "Timestamp")
-		return
-	}
-	z.Record, err = dc.ReadIntf()
-	if err != nil {
-		err = msgp.WrapError(err, "Record")
-		return
-	}
-	return
-}
-
-// EncodeMsg implements msgp.Encodable
-func (z EntryExt) EncodeMsg(en *msgp.Writer) (err error) {
-	// array header, size 2
-	err = en.Append(0x92)
-	if err != nil {
-		return
-	}
-	err = en.WriteExtension(&z.Timestamp)
-	if err != nil {
-		err = msgp.WrapError(err, "Timestamp")
-		return
-	}
-	err = en.WriteIntf(z.Record)
-	if err != nil {
-		err = msgp.WrapError(err, "Record")
-		return
-	}
-	return
-}
-
-// MarshalMsg implements msgp.Marshaler
-func (z EntryExt) MarshalMsg(b []byte) (o []byte, err error) {
-	o = msgp.Require(b, z.Msgsize())
-	// array header, size 2
-	o = append(o, 0x92)
-	o, err = msgp.AppendExtension(o, &z.Timestamp)
-	if err != nil {
-		err = msgp.WrapError(err, "Timestamp")
-		return
-	}
-	o, err = msgp.AppendIntf(o, z.Record)
-	if err != nil {
-		err = msgp.WrapError(err, "Record")
-		return
-	}
-	return
-}
-
-// UnmarshalMsg implements msgp.Unmarshaler
-func (z *EntryExt) UnmarshalMsg(bts []byte) (o []byte, err error) {
-	var zb0001 uint32
-	zb0001, bts, err = msgp.ReadArrayHeaderBytes(bts)
-	if err != nil {
-		err = msgp.WrapError(err)
-		return
-	}
-	if zb0001 != 2 {
-		err = msgp.ArrayError{Wanted: 2, Got: zb0001}
-		return
-	}
-	bts, err = msgp.ReadExtensionBytes(bts, &z.Timestamp)
-	if err != nil {
-		err = msgp.WrapError(err, "Timestamp")
-		return
-	}
-	z.Record, bts, err = msgp.ReadIntfBytes(bts)
-	if err != nil {
-		err = msgp.WrapError(err, "Record")
-		return
-	}
-	o = bts
-	return
-}
-
-// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
-func (z EntryExt) Msgsize() (s int) {
-	s = 1 + msgp.ExtensionPrefixSize + z.Timestamp.Len() + msgp.GuessSize(z.Record)
-	return
-}
-
-// DecodeMsg implements msgp.Decodable
-func (z *EntryList) DecodeMsg(dc *msgp.Reader) (err error) {
-	var zb0002 uint32
-	zb0002, err = dc.ReadArrayHeader()
-	if err != nil {
-		err = msgp.WrapError(err)
-		return
-	}
-	if cap((*z)) >= int(zb0002) {
-		(*z) = (*z)[:zb0002]
-	} else {
-		(*z) = make(EntryList, zb0002)
-	}
-	for zb0001 := range *z {
-		var zb0003 uint32
-		zb0003, err = dc.ReadArrayHeader()
-		if err != nil {
-			err = msgp.WrapError(err, zb0001)
-			return
-		}
-		if zb0003 != 2 {
-			err = msgp.ArrayError{Wanted: 2, Got: zb0003}
-			return
-		}
-		err = dc.ReadExtension(&(*z)[zb0001].Timestamp)
-		if err != nil {
-			err = msgp.WrapError(err, zb0001, "Timestamp")
-			return
-		}
-		(*z)[zb0001].Record, err = dc.ReadIntf()
-		if err != nil {
-			err = msgp.WrapError(err, zb0001, "Record")
-			return
-		}
-	}
-	return
-}
-
-// EncodeMsg implements msgp.Encodable
-func (z EntryList) EncodeMsg(en *msgp.Writer) (err error) {
-	err = en.WriteArrayHeader(uint32(len(z)))
-	if err != nil {
-		err = msgp.WrapError(err)
-		return
-	}
-	for zb0004 := range z {
-		// array header, size 2
-		err = en.Append(0x92)
-		if err != nil {
-			return
-		}
-		err = en.WriteExtension(&z[zb0004].Timestamp)
-		if err != nil {
-			err = msgp.WrapError(err, zb0004, "Timestamp")
-			return
-		}
-		err = en.WriteIntf(z[zb0004].Record)
-		if err != nil {
-			err = msgp.WrapError(err, zb0004, "Record")
-			return
-		}
-	}
-	return
-}
-
-// MarshalMsg implements msgp.Marshaler
-func (z EntryList) MarshalMsg(b []byte) (o []byte, err error) {
-	o = msgp.Require(b, z.Msgsize())
-	o = msgp.AppendArrayHeader(o, uint32(len(z)))
-	for zb0004 := range z {
-		// array header, size 2
-		o = append(o, 0x92)
-		o, err = msgp.AppendExtension(o, &z[zb0004].Timestamp)
-		if err != nil {
-			err = msgp.WrapError(err, zb0004, "Timestamp")
-			return
-		}
-		o, err = msgp.AppendIntf(o, z[zb0004].Record)
-		if err != nil {
-			err = msgp.WrapError(err, zb0004, "Record")
-			return
-		}
-	}
-	return
-}
-
-// UnmarshalMsg implements msgp.Unmarshaler
-func (z *EntryList) UnmarshalMsg(bts []byte) (o []byte, err error) {
-	var zb0002 uint32
-	zb0002, bts, err = msgp.ReadArrayHeaderBytes(bts)
-	if err != nil {
-		err = msgp.WrapError(err)
-		return
-	}
-	if cap((*z)) >= int(zb0002) {
-		(*z) = (*z)[:zb0002]
-	} else {
-		(*z) = make(EntryList, zb0002)
-	}
-	for zb0001 := range *z {
-		var zb0003 uint32
-		zb0003, bts, err = msgp.ReadArrayHeaderBytes(bts)
-		if err != nil {
-			err = msgp.WrapError(err, zb0001)
-			return
-		}
-		if zb0003 != 2 {
-			err = msgp.ArrayError{Wanted: 2, Got: zb0003}
-			return
-		}
-		bts, err = msgp.ReadExtensionBytes(bts, &(*z)[zb0001].Timestamp)
-		if err != nil {
-			err = msgp.WrapError(err, zb0001, "Timestamp")
-			return
-		}
-		(*z)[zb0001].Record, bts, err = msgp.ReadIntfBytes(bts)
-		if err != nil {
-			err = msgp.WrapError(err, zb0001, "Record")
-			return
-		}
-	}
-	o = bts
-	return
-}
-
-// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
-func (z EntryList) Msgsize() (s int) {
-	s = msgp.ArrayHeaderSize
-	for zb0004 := range z {
-		s += 1 + msgp.ExtensionPrefixSize + z[zb0004].Timestamp.Len() + msgp.GuessSize(z[zb0004].Record)
-	}
-	return
-}
-
 // DecodeMsg implements msgp.Decodable
 func (z *EventTime) DecodeMsg(dc *msgp.Reader) (err error) {
 	var field []byte
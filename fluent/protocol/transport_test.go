@@ -25,12 +25,14 @@ SOFTWARE.
 package protocol_test
 
 import (
+	"bytes"
 	"fmt"
 	"strings"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/tinylib/msgp/msgp"
 
 	"github.com/IBM/fluent-forward-go/fluent/protocol"
 )
@@ -73,6 +75,80 @@ var _ = Describe("Transport", func() {
 		})
 	})
 
+	Describe("EntryExt", func() {
+		var (
+			entry protocol.EntryExt
+		)
+
+		BeforeEach(func() {
+			entry = protocol.EntryExt{
+				Timestamp: protocol.EventTime{Time: time.Unix(1, 2).UTC()},
+				Record:    map[string]interface{}{"foo": "bar"},
+			}
+		})
+
+		It("Encodes an entry without metadata as [time, record]", func() {
+			bits, err := entry.MarshalMsg(nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			sz, rest, err := msgp.ReadArrayHeaderBytes(bits)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sz).To(BeEquivalentTo(2))
+			Expect(msgp.NextType(rest)).To(Equal(msgp.ExtensionType))
+		})
+
+		Context("When the entry has metadata", func() {
+			BeforeEach(func() {
+				entry.Metadata = map[string]interface{}{"traceparent": "abc"}
+			})
+
+			It("Encodes the Fluent Bit v2 format", func() {
+				bits, err := entry.MarshalMsg(nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				_, rest, err := msgp.ReadArrayHeaderBytes(bits)
+				Expect(err).ToNot(HaveOccurred())
+
+				sz, _, err := msgp.ReadArrayHeaderBytes(rest)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(sz).To(BeEquivalentTo(2))
+			})
+
+			It("Round trips through MarshalMsg and UnmarshalMsg", func() {
+				bits, err := entry.MarshalMsg(nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				var decoded protocol.EntryExt
+				_, err = decoded.UnmarshalMsg(bits)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(decoded.Timestamp.Equal(entry.Timestamp.Time)).To(BeTrue())
+				Expect(decoded.Metadata).To(Equal(entry.Metadata))
+				Expect(decoded.Record).To(Equal(entry.Record))
+			})
+
+			It("Round trips through EncodeMsg and DecodeMsg", func() {
+				var buf bytes.Buffer
+				Expect(msgp.Encode(&buf, entry)).To(Succeed())
+
+				decoded := protocol.EntryExt{Metadata: map[string]interface{}{"stale": true}}
+				Expect(msgp.Decode(&buf, &decoded)).To(Succeed())
+				Expect(decoded.Metadata).To(Equal(entry.Metadata))
+				Expect(decoded.Record).To(Equal(entry.Record))
+			})
+
+			It("Clears stale metadata when decoding a v1 entry", func() {
+				entry.Metadata = nil
+				bits, err := entry.MarshalMsg(nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				decoded := protocol.EntryExt{Metadata: map[string]interface{}{"stale": true}}
+				_, err = decoded.UnmarshalMsg(bits)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(decoded.Metadata).To(BeNil())
+			})
+		})
+	})
+
 	Describe("EntryList", func() {
 		var (
 			e1 protocol.EntryList
@@ -0,0 +1,66 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package server
+
+import (
+	"github.com/IBM/fluent-forward-go/fluent/protocol"
+)
+
+// forwardMessage is a Forward message in any mode, normalized to a tag, a
+// list of entries, and the chunk ID sent by the client.
+type forwardMessage struct {
+	Tag     string
+	Entries protocol.EntryList
+	chunk   string
+}
+
+// Chunk returns the chunk ID the client expects to be acknowledged, or an
+// empty string if no ack was requested.
+func (fm *forwardMessage) Chunk() string {
+	return fm.chunk
+}
+
+// decodeForwardMessage decodes the first message in bits, whatever its
+// mode, and returns the remaining bytes. Input that exceeds one of the
+// limits in opts fails with a protocol.LimitError.
+func decodeForwardMessage(bits []byte, opts *protocol.DecodeOptions) (*forwardMessage, []byte, error) {
+	msg, rest, err := opts.DecodeAny(bits)
+	if err != nil {
+		return nil, bits, err
+	}
+
+	it := msg.Events(opts)
+	fm := &forwardMessage{Tag: it.Tag()}
+
+	if fm.Entries, err = it.Entries(); err != nil {
+		return nil, bits, err
+	}
+
+	// read the chunk that was sent rather than calling Chunk, which would
+	// make one up; GetChunk fails if no ack was requested
+	fm.chunk, _ = protocol.GetChunk(bits[:len(bits)-len(rest)])
+
+	return fm, rest, nil
+}
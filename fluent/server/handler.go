@@ -0,0 +1,106 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package server
+
+import (
+	"context"
+	"sync"
+
+	"github.com/IBM/fluent-forward-go/fluent/protocol"
+)
+
+// EventHandler processes the events carried by a single Forward message.
+// Entries are normalized to EntryExt regardless of the mode the message was
+// sent in. If HandleEvents returns an error, the message is not acknowledged.
+type EventHandler interface {
+	HandleEvents(ctx context.Context, tag string, entries protocol.EntryList) error
+}
+
+// EventHandlerFunc is an adapter that allows an ordinary function to be used
+// as an EventHandler.
+type EventHandlerFunc func(ctx context.Context, tag string, entries protocol.EntryList) error
+
+func (f EventHandlerFunc) HandleEvents(ctx context.Context, tag string, entries protocol.EntryList) error {
+	return f(ctx, tag, entries)
+}
+
+// EventMux routes events to the EventHandler registered for their tag. Events
+// with a tag that has no handler go to the default handler, if one is set,
+// and are otherwise discarded.
+type EventMux struct {
+	handlers       map[string]EventHandler
+	defaultHandler EventHandler
+	mutex          sync.RWMutex
+}
+
+func NewEventMux() *EventMux {
+	return &EventMux{
+		handlers: map[string]EventHandler{},
+	}
+}
+
+// Handle registers the handler for the given tag. It replaces any handler
+// previously registered for the tag.
+func (m *EventMux) Handle(tag string, h EventHandler) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.handlers[tag] = h
+}
+
+// HandleFunc registers the handler function for the given tag.
+func (m *EventMux) HandleFunc(tag string, f func(ctx context.Context, tag string, entries protocol.EntryList) error) {
+	m.Handle(tag, EventHandlerFunc(f))
+}
+
+// HandleDefault registers the handler for events whose tag does not match
+// any other handler.
+func (m *EventMux) HandleDefault(h EventHandler) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.defaultHandler = h
+}
+
+// Handler returns the handler for the given tag, or nil if there is none.
+func (m *EventMux) Handler(tag string) EventHandler {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	if h, ok := m.handlers[tag]; ok {
+		return h
+	}
+
+	return m.defaultHandler
+}
+
+func (m *EventMux) HandleEvents(ctx context.Context, tag string, entries protocol.EntryList) error {
+	h := m.Handler(tag)
+	if h == nil {
+		return nil
+	}
+
+	return h.HandleEvents(ctx, tag, entries)
+}
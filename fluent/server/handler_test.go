@@ -0,0 +1,81 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package server_test
+
+import (
+	"context"
+	"errors"
+
+	"github.com/IBM/fluent-forward-go/fluent/protocol"
+	. "github.com/IBM/fluent-forward-go/fluent/server"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("EventMux", func() {
+	var (
+		mux   *EventMux
+		calls []string
+	)
+
+	handler := func(name string) EventHandlerFunc {
+		return func(_ context.Context, tag string, _ protocol.EntryList) error {
+			calls = append(calls, name+":"+tag)
+			return nil
+		}
+	}
+
+	BeforeEach(func() {
+		calls = nil
+		mux = NewEventMux()
+		mux.Handle("foo", handler("foo"))
+		mux.HandleFunc("bar", handler("bar"))
+	})
+
+	It("routes events by tag", func() {
+		Expect(mux.HandleEvents(context.TODO(), "foo", nil)).ToNot(HaveOccurred())
+		Expect(mux.HandleEvents(context.TODO(), "bar", nil)).ToNot(HaveOccurred())
+		Expect(calls).To(Equal([]string{"foo:foo", "bar:bar"}))
+	})
+
+	It("discards events without a handler", func() {
+		Expect(mux.HandleEvents(context.TODO(), "baz", nil)).ToNot(HaveOccurred())
+		Expect(calls).To(BeEmpty())
+		Expect(mux.Handler("baz")).To(BeNil())
+	})
+
+	It("sends unmatched events to the default handler", func() {
+		mux.HandleDefault(handler("default"))
+		Expect(mux.HandleEvents(context.TODO(), "baz", nil)).ToNot(HaveOccurred())
+		Expect(calls).To(Equal([]string{"default:baz"}))
+	})
+
+	It("returns handler errors", func() {
+		mux.HandleFunc("foo", func(context.Context, string, protocol.EntryList) error {
+			return errors.New("BOOM")
+		})
+		Expect(mux.HandleEvents(context.TODO(), "foo", nil)).To(MatchError("BOOM"))
+	})
+})
@@ -0,0 +1,190 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/IBM/fluent-forward-go/fluent/client"
+	"github.com/IBM/fluent-forward-go/fluent/client/ws"
+	"github.com/IBM/fluent-forward-go/fluent/protocol"
+	"github.com/gorilla/websocket"
+)
+
+type WSRelayOptions struct {
+	// Client forwards the messages to a Fluent endpoint. It is required and
+	// must be connected, and past the handshake if the endpoint requires
+	// one, before the relay starts serving. Set RequireAck on the client to
+	// have the relay ack each message only after the endpoint has.
+	Client *client.Client
+	// TokenValidator, if set, validates the token sent in AuthHeader before
+	// the connection is upgraded. If nil, no authentication is performed.
+	TokenValidator TokenValidator
+	// AuthHeader is the name of the header that carries the token. The
+	// default is AuthorizationHeader.
+	AuthHeader string
+	// AuthScheme, if set, must prefix the token; e.g., "Bearer" expects
+	// "Bearer <token>". The prefix is removed before validation.
+	AuthScheme string
+	// Upgrader is used to upgrade HTTP connections. If nil, a zero-value
+	// websocket.Upgrader is used.
+	Upgrader *websocket.Upgrader
+	// ConnectionOptions configures each websocket connection. Its
+	// ReadHandler is replaced by the WSRelay's own.
+	ConnectionOptions ws.ConnectionOptions
+	// DecodeOptions limits the size and nesting of the messages relayed.
+	// Its MaxMessageSize also limits the size of a frame. A connection that
+	// exceeds a limit is closed with CloseMessageTooBig. If nil,
+	// the limits of protocol.DefaultDecodeOptions are applied.
+	DecodeOptions *protocol.DecodeOptions
+}
+
+// WSRelay is an http.Handler that accepts websocket connections and relays
+// every message received on them, unmodified, to a Fluent endpoint over a
+// client.Client. Messages are not decoded, so chunk IDs and options are
+// preserved. When a message carries a "chunk" option, the relay sends an
+// AckMessage back over the websocket once the client has written the message
+// and, if the client requires acks, once the endpoint has acked it.
+//
+// If a message cannot be relayed, the relay reconnects the client and retries
+// once. If that fails too, the websocket is closed with CloseTryAgainLater
+// so that the peer can reconnect and resend anything that was not acked.
+type WSRelay struct {
+	*wsServer
+	client        *client.Client
+	reconnectLock sync.Mutex
+	// generation counts the reconnects, and reconnectErr is the outcome
+	// of the last one. Both are guarded by reconnectLock.
+	generation   uint64
+	reconnectErr error
+}
+
+func NewWSRelay(opts WSRelayOptions) *WSRelay {
+	return &WSRelay{
+		wsServer: newWSServer(opts.TokenValidator, opts.AuthHeader, opts.AuthScheme,
+			opts.Upgrader, opts.ConnectionOptions, opts.DecodeOptions),
+		client: opts.Client,
+	}
+}
+
+func (rl *WSRelay) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rl.serve(w, r, rl.relayFrame)
+}
+
+// relayFrame forwards each message in the frame separately so that every
+// chunk can be acked.
+func (rl *WSRelay) relayFrame(conn ws.Connection, p []byte) error {
+	for len(p) > 0 {
+		msg, rest, err := rl.decodeOpts.SplitRaw(p)
+		if err != nil {
+			return err
+		}
+
+		if err = rl.relayMessage(conn, msg); err != nil {
+			return err
+		}
+
+		p = rest
+	}
+
+	return nil
+}
+
+func (rl *WSRelay) relayMessage(conn ws.Connection, msg []byte) error {
+	// GetChunk fails if no ack was requested
+	chunk, _ := protocol.GetChunk(msg)
+
+	send := func() error {
+		if chunk == "" {
+			return rl.client.SendRaw(msg)
+		}
+
+		return rl.client.Send(protocol.RawMessage(msg))
+	}
+
+	gen := rl.connGeneration()
+
+	if err := send(); err != nil {
+		rl.logger.Println("relay error, reconnecting:", err)
+
+		if err = rl.reconnect(gen); err == nil {
+			err = send()
+		}
+
+		if err != nil {
+			return &websocket.CloseError{
+				Code: websocket.CloseTryAgainLater,
+				Text: "relay unavailable",
+			}
+		}
+	}
+
+	if chunk == "" {
+		return nil
+	}
+
+	return writeAck(conn, chunk)
+}
+
+func (rl *WSRelay) connGeneration() uint64 {
+	rl.reconnectLock.Lock()
+	defer rl.reconnectLock.Unlock()
+
+	return rl.generation
+}
+
+// reconnect replaces the client's connection and repeats the handshake if
+// the endpoint requires one. gen is the generation the failed send used:
+// when several sends fail on the same connection, only the first reconnects
+// and the others share its outcome.
+func (rl *WSRelay) reconnect(gen uint64) error {
+	rl.reconnectLock.Lock()
+	defer rl.reconnectLock.Unlock()
+
+	if gen != rl.generation {
+		return rl.reconnectErr
+	}
+
+	rl.generation++
+	rl.reconnectErr = rl.redial()
+
+	return rl.reconnectErr
+}
+
+func (rl *WSRelay) redial() error {
+	if err := rl.client.Reconnect(); err != nil {
+		return fmt.Errorf("reconnect: %w", err)
+	}
+
+	if !rl.client.TransportPhase() {
+		if err := rl.client.Handshake(); err != nil {
+			return fmt.Errorf("handshake: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,291 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package server_test
+
+import (
+	"bytes"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IBM/fluent-forward-go/fluent/client"
+	"github.com/IBM/fluent-forward-go/fluent/client/ws"
+	"github.com/IBM/fluent-forward-go/fluent/protocol"
+	. "github.com/IBM/fluent-forward-go/fluent/server"
+	"github.com/gorilla/websocket"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// fakeEndpoint is a minimal Fluent endpoint that records the raw messages it
+// receives and acks those with a chunk.
+type fakeEndpoint struct {
+	ln       net.Listener
+	msgs     chan []byte
+	conns    []net.Conn
+	accepted int
+	lock     sync.Mutex
+}
+
+func newFakeEndpoint() *fakeEndpoint {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).ToNot(HaveOccurred())
+
+	fe := &fakeEndpoint{ln: ln, msgs: make(chan []byte, 100)}
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			fe.lock.Lock()
+			fe.conns = append(fe.conns, conn)
+			fe.accepted++
+			fe.lock.Unlock()
+
+			go fe.serve(conn)
+		}
+	}()
+
+	return fe
+}
+
+func (fe *fakeEndpoint) serve(conn net.Conn) {
+	r := msgp.NewReader(conn)
+
+	for {
+		var buf bytes.Buffer
+		if _, err := r.CopyNext(&buf); err != nil {
+			return
+		}
+
+		fe.msgs <- buf.Bytes()
+
+		if chunk, err := protocol.GetChunk(buf.Bytes()); err == nil {
+			ack := protocol.AckMessage{Ack: chunk}
+			if err := msgp.Encode(conn, &ack); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Accepted returns the number of connections accepted so far.
+func (fe *fakeEndpoint) Accepted() int {
+	fe.lock.Lock()
+	defer fe.lock.Unlock()
+
+	return fe.accepted
+}
+
+// dropConns closes every connection accepted so far.
+func (fe *fakeEndpoint) dropConns() {
+	fe.lock.Lock()
+	defer fe.lock.Unlock()
+
+	for _, conn := range fe.conns {
+		conn.Close()
+	}
+
+	fe.conns = nil
+}
+
+func (fe *fakeEndpoint) Close() {
+	fe.ln.Close()
+	fe.dropConns()
+}
+
+var _ = Describe("WSRelay", func() {
+	var (
+		endpoint  *fakeEndpoint
+		fwdClient *client.Client
+		relay     *WSRelay
+		svr       *httptest.Server
+		cli       *client.WSClient
+		acks      chan string
+		readErrs  chan error
+	)
+
+	BeforeEach(func() {
+		endpoint = newFakeEndpoint()
+		acks = make(chan string, 10)
+		readErrs = make(chan error, 1)
+
+		fwdClient = client.New(client.ConnectionOptions{
+			Factory: &client.ConnFactory{
+				Address: endpoint.ln.Addr().String(),
+			},
+			RequireAck:        true,
+			ConnectionTimeout: time.Second,
+		})
+		Expect(fwdClient.Connect()).ToNot(HaveOccurred())
+
+		relay = NewWSRelay(WSRelayOptions{Client: fwdClient})
+		svr = httptest.NewServer(relay)
+
+		// the read handler can outlive the spec, so it must not
+		// reference variables that the next spec reassigns
+		ackCh, errCh := acks, readErrs
+
+		cli = client.NewWS(client.WSConnectionOptions{
+			Factory: &client.DefaultWSConnectionFactory{
+				URL: "ws" + strings.TrimPrefix(svr.URL, "http"),
+			},
+			ConnectionOptions: ws.ConnectionOptions{
+				ReadHandler: func(conn ws.Connection, _ int, p []byte, err error) error {
+					if err != nil {
+						errCh <- err
+						return err
+					}
+
+					var ack protocol.AckMessage
+					if _, err := ack.UnmarshalMsg(p); err == nil {
+						ackCh <- ack.Ack
+					}
+
+					return nil
+				},
+			},
+		})
+		Expect(cli.Connect()).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		_ = cli.Disconnect()
+		_ = relay.Close()
+		svr.Close()
+		_ = fwdClient.Disconnect()
+		endpoint.Close()
+	})
+
+	It("relays messages unmodified and acks them", func() {
+		msg := protocol.NewMessage("foo", map[string]interface{}{"a": "b"})
+		chunk, err := msg.Chunk()
+		Expect(err).ToNot(HaveOccurred())
+		bits, err := msg.MarshalMsg(nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(cli.Send(msg)).ToNot(HaveOccurred())
+
+		Eventually(endpoint.msgs).Should(Receive(Equal(bits)))
+		Eventually(acks).Should(Receive(Equal(chunk)))
+	})
+
+	It("does not ack messages without a chunk", func() {
+		msg := protocol.NewMessage("foo", map[string]interface{}{"a": "b"})
+		bits, err := msg.MarshalMsg(nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(cli.Send(msg)).ToNot(HaveOccurred())
+
+		Eventually(endpoint.msgs).Should(Receive(Equal(bits)))
+		Consistently(acks).ShouldNot(Receive())
+	})
+
+	It("relays each message in a frame separately", func() {
+		one := protocol.NewMessage("one", map[string]interface{}{"a": "b"})
+		oneChunk, _ := one.Chunk()
+		two := protocol.NewMessage("two", map[string]interface{}{"c": "d"})
+		twoChunk, _ := two.Chunk()
+
+		oneBits, err := one.MarshalMsg(nil)
+		Expect(err).ToNot(HaveOccurred())
+		twoBits, err := two.MarshalMsg(nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(cli.SendRaw(append(append([]byte{}, oneBits...), twoBits...))).ToNot(HaveOccurred())
+
+		Eventually(endpoint.msgs).Should(Receive(Equal(oneBits)))
+		Eventually(endpoint.msgs).Should(Receive(Equal(twoBits)))
+		Eventually(acks).Should(Receive(Equal(oneChunk)))
+		Eventually(acks).Should(Receive(Equal(twoChunk)))
+	})
+
+	It("reconnects when the endpoint connection drops", func() {
+		endpoint.dropConns()
+
+		msg := protocol.NewMessage("foo", map[string]interface{}{"a": "b"})
+		chunk, _ := msg.Chunk()
+		Expect(cli.Send(msg)).ToNot(HaveOccurred())
+
+		Eventually(acks, 3*time.Second).Should(Receive(Equal(chunk)))
+	})
+
+	It("reconnects once when several connections fail at once", func() {
+		const n = 3
+
+		// hold every failed send until all of them have failed, so that
+		// they all try to reconnect
+		var failed sync.WaitGroup
+		failed.Add(n)
+		fwdClient.Hooks.OnSend = func(info client.SendInfo) {
+			if info.Err != nil {
+				failed.Done()
+				failed.Wait()
+			}
+		}
+
+		Eventually(endpoint.Accepted).Should(Equal(1))
+		endpoint.dropConns()
+
+		for i := 0; i < n; i++ {
+			c := client.NewWS(client.WSConnectionOptions{
+				Factory: &client.DefaultWSConnectionFactory{
+					URL: "ws" + strings.TrimPrefix(svr.URL, "http"),
+				},
+			})
+			Expect(c.Connect()).ToNot(HaveOccurred())
+			DeferCleanup(c.Disconnect)
+
+			msg := protocol.NewMessage("foo", map[string]interface{}{"a": "b"})
+			_, _ = msg.Chunk()
+			Expect(c.Send(msg)).ToNot(HaveOccurred())
+		}
+
+		for i := 0; i < n; i++ {
+			Eventually(endpoint.msgs, 3*time.Second).Should(Receive())
+		}
+
+		Expect(endpoint.Accepted()).To(Equal(2))
+	})
+
+	It("closes the websocket when the endpoint is unavailable", func() {
+		endpoint.Close()
+
+		msg := protocol.NewMessage("foo", map[string]interface{}{"a": "b"})
+		_, _ = msg.Chunk()
+		Expect(cli.Send(msg)).ToNot(HaveOccurred())
+
+		var err error
+		Eventually(readErrs, 3*time.Second).Should(Receive(&err))
+		Expect(websocket.IsCloseError(err, websocket.CloseTryAgainLater)).To(BeTrue())
+		Consistently(acks).ShouldNot(Receive())
+	})
+})
@@ -0,0 +1,393 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/IBM/fluent-forward-go/fluent/client/ws"
+	"github.com/IBM/fluent-forward-go/fluent/protocol"
+	"github.com/IBM/fluent-forward-go/fluent/trace"
+	"github.com/tinylib/msgp/msgp"
+)
+
+const (
+	DefaultHandshakeTimeout time.Duration = 30 * time.Second
+)
+
+// ErrServerClosed is returned by Server.Serve after Close is called.
+var ErrServerClosed = errors.New("server closed")
+
+// RawHandler processes a single Forward message exactly as it was received,
+// without decoding it. The slice is only valid until HandleRaw returns. If
+// HandleRaw returns an error, the message is not acknowledged.
+type RawHandler interface {
+	HandleRaw(ctx context.Context, msg []byte) error
+}
+
+// RawHandlerFunc is an adapter that allows an ordinary function to be used
+// as a RawHandler.
+type RawHandlerFunc func(ctx context.Context, msg []byte) error
+
+func (f RawHandlerFunc) HandleRaw(ctx context.Context, msg []byte) error {
+	return f(ctx, msg)
+}
+
+type ServerOptions struct {
+	// Handler receives the events decoded from every message. Either Handler
+	// or RawHandler is required. If both are set, RawHandler is used.
+	Handler EventHandler
+	// RawHandler receives every message without decoding it.
+	RawHandler RawHandler
+	// SharedKey, if set, requires clients to complete the shared key
+	// handshake before sending messages.
+	SharedKey []byte
+	// Hostname is sent to clients in the handshake PONG.
+	Hostname string
+	// HandshakeTimeout bounds the handshake. The default is
+	// DefaultHandshakeTimeout.
+	HandshakeTimeout time.Duration
+	// Logger is an optional debug log writer.
+	Logger ws.Logger
+	// Propagator, if set, extracts the trace context carried by the first
+	// event in each message into the context passed to Handler.
+	Propagator trace.Propagator
+	// DecodeOptions limits the size and shape of the messages accepted. A
+	// connection that exceeds a limit is closed. If nil,
+	// the limits of protocol.DefaultDecodeOptions are applied.
+	DecodeOptions *protocol.DecodeOptions
+}
+
+// Server receives Fluent Forward messages over TCP, TLS, or unix socket
+// connections, such as those opened by Fluentd's and Fluent Bit's forward
+// outputs. Messages are passed to the configured handler and, when the client
+// asked for an ack by setting the "chunk" option, an AckMessage is sent back
+// once the handler returns without error.
+type Server struct {
+	handler          EventHandler
+	rawHandler       RawHandler
+	sharedKey        []byte
+	hostname         string
+	handshakeTimeout time.Duration
+	logger           ws.Logger
+	propagator       trace.Propagator
+	decodeOpts       *protocol.DecodeOptions
+	ctx              context.Context
+	cancel           context.CancelFunc
+	listeners        map[net.Listener]struct{}
+	conns            map[net.Conn]struct{}
+	closed           bool
+	lock             sync.Mutex
+	wg               sync.WaitGroup
+}
+
+func NewServer(opts ServerOptions) *Server {
+	s := &Server{
+		handler:          opts.Handler,
+		rawHandler:       opts.RawHandler,
+		sharedKey:        opts.SharedKey,
+		hostname:         opts.Hostname,
+		handshakeTimeout: opts.HandshakeTimeout,
+		logger:           opts.Logger,
+		propagator:       opts.Propagator,
+		decodeOpts:       opts.DecodeOptions,
+		listeners:        map[net.Listener]struct{}{},
+		conns:            map[net.Conn]struct{}{},
+	}
+
+	if s.handshakeTimeout == 0 {
+		s.handshakeTimeout = DefaultHandshakeTimeout
+	}
+
+	if s.logger == nil {
+		s.logger = &noopLogger{}
+	}
+
+	if s.decodeOpts == nil {
+		opts := protocol.DefaultDecodeOptions()
+		s.decodeOpts = &opts
+	}
+
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+
+	return s
+}
+
+// Serve accepts connections on the listener and serves each one in its own
+// goroutine. It blocks until the listener fails or Close is called, in which
+// case it returns ErrServerClosed.
+func (s *Server) Serve(ln net.Listener) error {
+	if !s.track(ln, true) {
+		return ErrServerClosed
+	}
+
+	defer s.track(ln, false)
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if s.isClosed() {
+				return ErrServerClosed
+			}
+
+			return err
+		}
+
+		if !s.trackConn(conn, true) {
+			_ = conn.Close()
+			return ErrServerClosed
+		}
+
+		s.wg.Add(1)
+
+		go func() {
+			defer s.wg.Done()
+			defer s.trackConn(conn, false)
+
+			s.serveConn(conn)
+		}()
+	}
+}
+
+// ListenAndServe listens on the network address and calls Serve.
+func (s *Server) ListenAndServe(network, address string) error {
+	ln, err := net.Listen(network, address)
+	if err != nil {
+		return err
+	}
+
+	return s.Serve(ln)
+}
+
+// Close stops all listeners, closes every active connection, and waits for
+// the connection handlers to return.
+func (s *Server) Close() error {
+	s.lock.Lock()
+	s.closed = true
+	s.cancel()
+
+	var err error
+
+	for ln := range s.listeners {
+		if cerr := ln.Close(); cerr != nil {
+			err = cerr
+		}
+	}
+
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+
+	s.lock.Unlock()
+
+	s.wg.Wait()
+
+	return err
+}
+
+func (s *Server) isClosed() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.closed
+}
+
+func (s *Server) track(ln net.Listener, add bool) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if add {
+		if s.closed {
+			return false
+		}
+
+		s.listeners[ln] = struct{}{}
+	} else {
+		delete(s.listeners, ln)
+	}
+
+	return true
+}
+
+func (s *Server) trackConn(conn net.Conn, add bool) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if add {
+		if s.closed {
+			return false
+		}
+
+		s.conns[conn] = struct{}{}
+	} else {
+		delete(s.conns, conn)
+	}
+
+	return true
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer conn.Close()
+
+	r := msgp.NewReader(conn)
+
+	if len(s.sharedKey) > 0 {
+		if err := s.handshake(conn, r); err != nil {
+			s.logger.Println("handshake failed:", err)
+			return
+		}
+	}
+
+	ctx := context.WithValue(s.ctx, connKey{}, conn)
+
+	var buf bytes.Buffer
+
+	for {
+		buf.Reset()
+
+		if err := s.decodeOpts.ReadRaw(r, &buf); err != nil {
+			if !errors.Is(err, io.EOF) && !s.isClosed() {
+				s.logger.Println("read error:", err)
+			}
+
+			return
+		}
+
+		if err := s.handleMessage(ctx, conn, buf.Bytes()); err != nil {
+			s.logger.Println("message error:", err)
+			return
+		}
+	}
+}
+
+// connKey is the context key for the connection a message arrived on.
+type connKey struct{}
+
+// connFromContext returns the connection the message being handled arrived
+// on.
+func connFromContext(ctx context.Context) net.Conn {
+	conn, _ := ctx.Value(connKey{}).(net.Conn)
+
+	return conn
+}
+
+// handleMessage dispatches a single message and acks it if requested. It
+// returns an error only if the connection can no longer be used.
+func (s *Server) handleMessage(ctx context.Context, conn net.Conn, msg []byte) error {
+	var (
+		chunk string
+		err   error
+	)
+
+	if s.rawHandler != nil {
+		chunk, _ = protocol.GetChunk(msg)
+		err = s.rawHandler.HandleRaw(ctx, msg)
+	} else {
+		var fm *forwardMessage
+
+		if fm, _, err = decodeForwardMessage(msg, s.decodeOpts); err != nil {
+			return err
+		}
+
+		chunk = fm.Chunk()
+		err = s.handler.HandleEvents(trace.ExtractEntries(ctx, s.propagator, fm.Entries), fm.Tag, fm.Entries)
+	}
+
+	if err != nil {
+		// the message is not acked, so the client can retry it, and the
+		// connection stays open for the messages that follow
+		s.logger.Println("handler error:", err)
+		return nil
+	}
+
+	if chunk == "" {
+		return nil
+	}
+
+	ack := protocol.AckMessage{Ack: chunk}
+
+	return msgp.Encode(conn, &ack)
+}
+
+// handshake performs the server side of the shared key handshake:
+// HELO -> PING -> PONG.
+func (s *Server) handshake(conn net.Conn, r *msgp.Reader) error {
+	if err := conn.SetDeadline(time.Now().Add(s.handshakeTimeout)); err != nil {
+		return err
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	helo := protocol.NewHelo(&protocol.HeloOpts{
+		Nonce:     nonce,
+		Keepalive: true,
+	})
+
+	if err := msgp.Encode(conn, helo); err != nil {
+		return fmt.Errorf("send helo: %w", err)
+	}
+
+	var ping protocol.Ping
+	if err := s.decodeOpts.Decode(r, &ping); err != nil {
+		return fmt.Errorf("read ping: %w", err)
+	}
+
+	if ping.MessageType != protocol.MsgTypePing {
+		return fmt.Errorf("expected %s, but got %s", protocol.MsgTypePing, ping.MessageType)
+	}
+
+	authErr := protocol.ValidatePingDigest(&ping, s.sharedKey, nonce)
+
+	reason := ""
+	if authErr != nil {
+		reason = "shared key mismatch"
+	}
+
+	pong, err := protocol.NewPong(authErr == nil, reason, s.hostname, s.sharedKey, helo, &ping)
+	if err != nil {
+		return err
+	}
+
+	if err = msgp.Encode(conn, pong); err != nil {
+		return fmt.Errorf("send pong: %w", err)
+	}
+
+	if authErr != nil {
+		return fmt.Errorf("authenticate %s: %w", ping.ClientHostname, authErr)
+	}
+
+	return conn.SetDeadline(time.Time{})
+}
@@ -0,0 +1,269 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package server_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/IBM/fluent-forward-go/fluent/client"
+	"github.com/IBM/fluent-forward-go/fluent/protocol"
+	. "github.com/IBM/fluent-forward-go/fluent/server"
+	"github.com/IBM/fluent-forward-go/fluent/trace"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Server", func() {
+	var (
+		opts       ServerOptions
+		svr        *Server
+		ln         net.Listener
+		serveErr   chan error
+		cli        *client.Client
+		cliOpts    client.ConnectionOptions
+		events     chan received
+		handlerErr error
+	)
+
+	BeforeEach(func() {
+		events = make(chan received, 10)
+		handlerErr = nil
+
+		eventCh := events
+
+		opts = ServerOptions{
+			Handler: EventHandlerFunc(func(_ context.Context, tag string, entries protocol.EntryList) error {
+				eventCh <- received{tag, entries}
+				return handlerErr
+			}),
+		}
+
+		cliOpts = client.ConnectionOptions{
+			RequireAck:        true,
+			ConnectionTimeout: time.Second,
+		}
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		ln, err = net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).ToNot(HaveOccurred())
+
+		svr = NewServer(opts)
+		serveErr = make(chan error, 1)
+
+		go func(errCh chan error) {
+			errCh <- svr.Serve(ln)
+		}(serveErr)
+
+		cliOpts.Factory = &client.ConnFactory{
+			Network: "tcp",
+			Address: ln.Addr().String(),
+		}
+
+		cli = client.New(cliOpts)
+		Expect(cli.Connect()).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		_ = cli.Disconnect()
+		Expect(svr.Close()).ToNot(HaveOccurred())
+	})
+
+	It("receives events and acks them", func() {
+		msg := protocol.NewMessage("foo", map[string]interface{}{"a": "b"})
+		Expect(cli.Send(msg)).ToNot(HaveOccurred())
+
+		var r received
+		Eventually(events).Should(Receive(&r))
+		Expect(r.tag).To(Equal("foo"))
+		Expect(r.entries).To(HaveLen(1))
+		Expect(r.entries[0].Record).To(HaveKeyWithValue("a", "b"))
+	})
+
+	It("receives PackedForward messages", func() {
+		entries := protocol.EntryList{
+			{Timestamp: protocol.EventTime{Time: time.Unix(1000, 0)}, Record: map[string]interface{}{"a": "b"}},
+			{Timestamp: protocol.EventTime{Time: time.Unix(2000, 0)}, Record: map[string]interface{}{"c": "d"}},
+		}
+
+		msg, err := protocol.NewCompressedPackedForwardMessage("bar", entries)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cli.Send(msg)).ToNot(HaveOccurred())
+
+		var r received
+		Eventually(events).Should(Receive(&r))
+		Expect(r.tag).To(Equal("bar"))
+		Expect(r.entries).To(HaveLen(2))
+	})
+
+	When("a Propagator is set", func() {
+		var (
+			spans chan trace.SpanContext
+			sc    trace.SpanContext
+		)
+
+		BeforeEach(func() {
+			spans = make(chan trace.SpanContext, 10)
+			spanCh := spans
+
+			opts.Propagator = trace.TraceContext{}
+			opts.Handler = EventHandlerFunc(func(ctx context.Context, _ string, _ protocol.EntryList) error {
+				spanCh <- trace.SpanContextFromContext(ctx)
+				return nil
+			})
+
+			var err error
+			sc, err = trace.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+			Expect(err).ToNot(HaveOccurred())
+
+			cliOpts.Propagator = trace.TraceContext{}
+		})
+
+		It("extracts the trace context from records", func() {
+			ctx := trace.ContextWithSpanContext(context.Background(), sc)
+			Expect(cli.SendMessageContext(ctx, "foo", map[string]interface{}{"a": "b"})).ToNot(HaveOccurred())
+
+			Eventually(spans).Should(Receive(Equal(sc)))
+		})
+
+		When("the trace context is sent as metadata", func() {
+			BeforeEach(func() {
+				cliOpts.TraceMetadata = true
+			})
+
+			It("extracts the trace context from Fluent Bit v2 metadata", func() {
+				ctx := trace.ContextWithSpanContext(context.Background(), sc)
+				entries := protocol.EntryList{
+					{Timestamp: protocol.EventTimeNow(), Record: map[string]interface{}{"a": "b"}},
+				}
+
+				Expect(cli.SendPackedContext(ctx, "foo", entries)).ToNot(HaveOccurred())
+				Eventually(spans).Should(Receive(Equal(sc)))
+
+				Expect(cli.SendMessageContext(ctx, "foo", map[string]interface{}{"a": "b"})).ToNot(HaveOccurred())
+				Eventually(spans).Should(Receive(Equal(sc)))
+			})
+		})
+	})
+
+	When("the handler returns an error", func() {
+		BeforeEach(func() {
+			handlerErr = errors.New("nope")
+		})
+
+		It("does not ack", func() {
+			msg := protocol.NewMessage("foo", map[string]interface{}{"a": "b"})
+			Expect(cli.Send(msg)).To(HaveOccurred())
+		})
+	})
+
+	When("a RawHandler is set", func() {
+		var raw chan []byte
+
+		BeforeEach(func() {
+			raw = make(chan []byte, 10)
+			rawCh := raw
+
+			opts.RawHandler = RawHandlerFunc(func(_ context.Context, msg []byte) error {
+				rawCh <- append([]byte{}, msg...)
+				return nil
+			})
+		})
+
+		It("passes the raw message to it and acks it", func() {
+			msg := protocol.NewMessage("foo", map[string]interface{}{"a": "b"})
+			Expect(cli.Send(msg)).ToNot(HaveOccurred())
+
+			expected, err := msg.MarshalMsg(nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(raw).Should(Receive(Equal(expected)))
+			Expect(events).ToNot(Receive())
+		})
+	})
+
+	When("a shared key is set", func() {
+		BeforeEach(func() {
+			opts.SharedKey = []byte("secret")
+			opts.Hostname = "server"
+			cliOpts.AuthInfo = client.AuthInfo{SharedKey: []byte("secret")}
+		})
+
+		It("completes the handshake", func() {
+			Expect(cli.Handshake()).ToNot(HaveOccurred())
+			Expect(cli.TransportPhase()).To(BeTrue())
+
+			msg := protocol.NewMessage("foo", map[string]interface{}{"a": "b"})
+			Expect(cli.Send(msg)).ToNot(HaveOccurred())
+			Eventually(events).Should(Receive())
+		})
+
+		When("the client key does not match", func() {
+			BeforeEach(func() {
+				cliOpts.AuthInfo = client.AuthInfo{SharedKey: []byte("wrong")}
+			})
+
+			It("fails the handshake", func() {
+				Expect(cli.Handshake()).To(HaveOccurred())
+				Expect(cli.TransportPhase()).To(BeFalse())
+			})
+		})
+	})
+
+	When("DecodeOptions are set", func() {
+		BeforeEach(func() {
+			opts.DecodeOptions = &protocol.DecodeOptions{
+				MaxMessageSize: 1024,
+				MaxTagLength:   8,
+			}
+		})
+
+		It("closes the connection when a tag is too long", func() {
+			msg := protocol.NewMessage("foo", map[string]interface{}{"a": "b"})
+			Expect(cli.Send(msg)).ToNot(HaveOccurred())
+			Eventually(events).Should(Receive())
+
+			msg = protocol.NewMessage("much.too.long", map[string]interface{}{"a": "b"})
+			Expect(cli.Send(msg)).To(HaveOccurred())
+			Consistently(events).ShouldNot(Receive())
+		})
+
+		It("closes the connection when a message is too large", func() {
+			msg := protocol.NewMessage("foo", map[string]interface{}{"a": strings.Repeat("b", 2048)})
+			Expect(cli.Send(msg)).To(HaveOccurred())
+			Consistently(events).ShouldNot(Receive())
+		})
+	})
+
+	It("returns ErrServerClosed from Serve after Close", func() {
+		Expect(svr.Close()).ToNot(HaveOccurred())
+		Eventually(serveErr).Should(Receive(MatchError(ErrServerClosed)))
+	})
+})
@@ -0,0 +1,290 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/IBM/fluent-forward-go/fluent/client"
+	"github.com/IBM/fluent-forward-go/fluent/client/ws"
+	"github.com/IBM/fluent-forward-go/fluent/protocol"
+)
+
+const (
+	DefaultAckTimeout time.Duration = 60 * time.Second
+)
+
+// TunnelOptions configures a Tunnel.
+type TunnelOptions struct {
+	// WSOptions configures the websocket client that carries the messages.
+	// Its ReadHandler is replaced by the Tunnel's own, which listens for
+	// acks from the websocket peer.
+	WSOptions client.WSConnectionOptions
+	// SharedKey, if set, requires local clients to complete the shared key
+	// handshake before sending messages.
+	SharedKey []byte
+	// Hostname is sent to local clients in the handshake PONG.
+	Hostname string
+	// AckTimeout is how long to wait for the websocket peer to ack a message
+	// before giving up on it. The default is DefaultAckTimeout.
+	AckTimeout time.Duration
+	// Logger is an optional debug log writer.
+	Logger ws.Logger
+	// DecodeOptions limits the size and shape of the messages accepted from
+	// local clients. If nil, the limits of protocol.DefaultDecodeOptions
+	// are applied.
+	DecodeOptions *protocol.DecodeOptions
+}
+
+// Tunnel is a local Forward endpoint that tunnels every message it receives
+// over a websocket, e.g. to a WSRelay. It lets unmodified Fluentd and Fluent
+// Bit agents ship events through networks that only allow HTTPS egress.
+//
+// Messages are tunneled unmodified. When a message carries a "chunk" option,
+// the Tunnel waits for the websocket peer to ack it before acking the local
+// client, so acks are propagated end to end.
+type Tunnel struct {
+	*Server
+	client     *client.WSClient
+	ackTimeout time.Duration
+	logger     ws.Logger
+	// waiters are woken up when the ack for a chunk sent by a local
+	// connection arrives. Local clients may reuse chunk IDs, so queues
+	// holds, for each chunk, the connections waiting for it in the order
+	// they sent it.
+	waiters  map[waiterKey]chan struct{}
+	queues   map[string][]net.Conn
+	waitLock sync.Mutex
+	connLock sync.Mutex
+}
+
+// NewTunnel returns a Tunnel. The websocket client is not connected until
+// Serve is called.
+func NewTunnel(opts TunnelOptions) *Tunnel {
+	t := &Tunnel{
+		ackTimeout: opts.AckTimeout,
+		logger:     opts.Logger,
+		waiters:    map[waiterKey]chan struct{}{},
+		queues:     map[string][]net.Conn{},
+	}
+
+	if t.ackTimeout == 0 {
+		t.ackTimeout = DefaultAckTimeout
+	}
+
+	if t.logger == nil {
+		t.logger = &noopLogger{}
+	}
+
+	wsOpts := opts.WSOptions
+	wsOpts.ReadHandler = t.readAck
+	t.client = client.NewWS(wsOpts)
+
+	t.Server = NewServer(ServerOptions{
+		RawHandler:       t,
+		SharedKey:        opts.SharedKey,
+		Hostname:         opts.Hostname,
+		HandshakeTimeout: DefaultHandshakeTimeout,
+		Logger:           opts.Logger,
+		DecodeOptions:    opts.DecodeOptions,
+	})
+
+	return t
+}
+
+// Serve connects the websocket client, if it is not already connected, and
+// then accepts local connections on the listener. See Server.Serve.
+func (t *Tunnel) Serve(ln net.Listener) error {
+	if err := t.connect(); err != nil {
+		_ = ln.Close()
+		return err
+	}
+
+	return t.Server.Serve(ln)
+}
+
+// ListenAndServe listens on the network address and calls Serve.
+func (t *Tunnel) ListenAndServe(network, address string) error {
+	ln, err := net.Listen(network, address)
+	if err != nil {
+		return err
+	}
+
+	return t.Serve(ln)
+}
+
+// Close stops accepting local connections and disconnects the websocket.
+func (t *Tunnel) Close() error {
+	err := t.Server.Close()
+
+	if derr := t.client.Disconnect(); err == nil {
+		err = derr
+	}
+
+	return err
+}
+
+func (t *Tunnel) connect() error {
+	t.connLock.Lock()
+	defer t.connLock.Unlock()
+
+	if t.client.Session() != nil {
+		return nil
+	}
+
+	return t.client.Connect()
+}
+
+func (t *Tunnel) reconnect() error {
+	t.connLock.Lock()
+	defer t.connLock.Unlock()
+
+	return t.client.Reconnect()
+}
+
+// HandleRaw tunnels a single message and, if it carries a chunk, waits for
+// the websocket peer to ack it.
+func (t *Tunnel) HandleRaw(ctx context.Context, msg []byte) error {
+	chunk, _ := protocol.GetChunk(msg)
+
+	var ackCh chan struct{}
+
+	if chunk != "" {
+		key := waiterKey{conn: connFromContext(ctx), chunk: chunk}
+		ackCh = t.addWaiter(key)
+		defer t.removeWaiter(key)
+	}
+
+	if err := t.client.SendRaw(msg); err != nil {
+		t.logger.Println("tunnel error, reconnecting:", err)
+
+		if err = t.reconnect(); err == nil {
+			err = t.client.SendRaw(msg)
+		}
+
+		if err != nil {
+			return fmt.Errorf("tunnel message: %w", err)
+		}
+	}
+
+	if ackCh == nil {
+		return nil
+	}
+
+	timer := time.NewTimer(t.ackTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-ackCh:
+		return nil
+	case <-timer.C:
+		return fmt.Errorf("timed out waiting for ack of chunk %s", chunk)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type waiterKey struct {
+	conn  net.Conn
+	chunk string
+}
+
+func (t *Tunnel) addWaiter(key waiterKey) chan struct{} {
+	t.waitLock.Lock()
+	defer t.waitLock.Unlock()
+
+	ch := make(chan struct{}, 1)
+	t.waiters[key] = ch
+	t.queues[key.chunk] = append(t.queues[key.chunk], key.conn)
+
+	return ch
+}
+
+func (t *Tunnel) removeWaiter(key waiterKey) {
+	t.waitLock.Lock()
+	defer t.waitLock.Unlock()
+
+	delete(t.waiters, key)
+
+	q := t.queues[key.chunk]
+
+	for i, conn := range q {
+		if conn == key.conn {
+			q = append(q[:i:i], q[i+1:]...)
+			break
+		}
+	}
+
+	if len(q) == 0 {
+		delete(t.queues, key.chunk)
+	} else {
+		t.queues[key.chunk] = q
+	}
+}
+
+// readAck is the websocket client's ReadHandler. It wakes up the local
+// connection waiting for each ack that arrives.
+func (t *Tunnel) readAck(_ ws.Connection, _ int, p []byte, err error) error {
+	if err != nil {
+		return err
+	}
+
+	var ack protocol.AckMessage
+	if _, err = ack.UnmarshalMsg(p); err != nil {
+		t.logger.Println("unexpected message from peer:", err)
+		return nil
+	}
+
+	t.waitLock.Lock()
+	defer t.waitLock.Unlock()
+
+	// the ack goes to the connection that has waited longest for it
+	q := t.queues[ack.Ack]
+	if len(q) == 0 {
+		t.logger.Println("ack for unknown chunk:", ack.Ack)
+		return nil
+	}
+
+	key := waiterKey{conn: q[0], chunk: ack.Ack}
+
+	if len(q) == 1 {
+		delete(t.queues, ack.Ack)
+	} else {
+		t.queues[ack.Ack] = q[1:]
+	}
+
+	select {
+	case t.waiters[key] <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+var _ RawHandler = (*Tunnel)(nil)
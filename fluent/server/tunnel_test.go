@@ -0,0 +1,213 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package server_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/IBM/fluent-forward-go/fluent/client"
+	"github.com/IBM/fluent-forward-go/fluent/protocol"
+	. "github.com/IBM/fluent-forward-go/fluent/server"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tunnel", func() {
+	var (
+		handler    *WSHandler
+		wsSvr      *httptest.Server
+		tunnel     *Tunnel
+		tunnelOpts TunnelOptions
+		ln         net.Listener
+		serveErr   chan error
+		agent      *client.Client
+		events     chan received
+		handlerErr error
+	)
+
+	BeforeEach(func() {
+		events = make(chan received, 10)
+		handlerErr = nil
+
+		eventCh := events
+
+		handler = NewWSHandler(WSHandlerOptions{
+			Handler: EventHandlerFunc(func(_ context.Context, tag string, entries protocol.EntryList) error {
+				eventCh <- received{tag, entries}
+				return handlerErr
+			}),
+		})
+		wsSvr = httptest.NewServer(handler)
+
+		tunnelOpts = TunnelOptions{
+			WSOptions: client.WSConnectionOptions{
+				Factory: &client.DefaultWSConnectionFactory{
+					URL: "ws" + strings.TrimPrefix(wsSvr.URL, "http"),
+				},
+			},
+			AckTimeout: 500 * time.Millisecond,
+		}
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		ln, err = net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).ToNot(HaveOccurred())
+
+		tunnel = NewTunnel(tunnelOpts)
+		serveErr = make(chan error, 1)
+
+		go func(errCh chan error) {
+			errCh <- tunnel.Serve(ln)
+		}(serveErr)
+
+		agent = client.New(client.ConnectionOptions{
+			Factory: &client.ConnFactory{
+				Network: "tcp",
+				Address: ln.Addr().String(),
+			},
+			RequireAck:        true,
+			ConnectionTimeout: 2 * time.Second,
+		})
+		Expect(agent.Connect()).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		_ = agent.Disconnect()
+		Expect(tunnel.Close()).ToNot(HaveOccurred())
+		Expect(handler.Close()).ToNot(HaveOccurred())
+		wsSvr.Close()
+	})
+
+	It("tunnels messages and propagates acks", func() {
+		msg := protocol.NewMessage("foo", map[string]interface{}{"a": "b"})
+		Expect(agent.Send(msg)).ToNot(HaveOccurred())
+
+		var r received
+		Eventually(events).Should(Receive(&r))
+		Expect(r.tag).To(Equal("foo"))
+		Expect(r.entries[0].Record).To(HaveKeyWithValue("a", "b"))
+	})
+
+	It("tunnels messages that do not request an ack", func() {
+		msg := protocol.NewMessage("foo", map[string]interface{}{"a": "b"})
+		raw, err := msg.MarshalMsg(nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(agent.SendRaw(raw)).ToNot(HaveOccurred())
+		Eventually(events).Should(Receive())
+	})
+
+	It("tunnels compressed messages", func() {
+		entries := protocol.EntryList{
+			{Timestamp: protocol.EventTime{Time: time.Unix(1000, 0)}, Record: map[string]interface{}{"a": "b"}},
+		}
+
+		msg, err := protocol.NewCompressedPackedForwardMessage("bar", entries)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(agent.Send(msg)).ToNot(HaveOccurred())
+
+		var r received
+		Eventually(events).Should(Receive(&r))
+		Expect(r.tag).To(Equal("bar"))
+	})
+
+	When("local clients send the same chunk", func() {
+		var release chan struct{}
+
+		BeforeEach(func() {
+			// hold the first message at the peer until both are waiting
+			// for their acks
+			Expect(handler.Close()).ToNot(HaveOccurred())
+			wsSvr.Close()
+
+			release = make(chan struct{})
+			releaseCh, eventCh := release, events
+
+			handler = NewWSHandler(WSHandlerOptions{
+				Handler: EventHandlerFunc(func(_ context.Context, tag string, entries protocol.EntryList) error {
+					<-releaseCh
+					eventCh <- received{tag, entries}
+					return nil
+				}),
+			})
+			wsSvr = httptest.NewServer(handler)
+			tunnelOpts.WSOptions.Factory = &client.DefaultWSConnectionFactory{
+				URL: "ws" + strings.TrimPrefix(wsSvr.URL, "http"),
+			}
+			tunnelOpts.AckTimeout = 2 * time.Second
+		})
+
+		It("acks each client", func() {
+			other := client.New(client.ConnectionOptions{
+				Factory: &client.ConnFactory{
+					Network: "tcp",
+					Address: ln.Addr().String(),
+				},
+				RequireAck:        true,
+				ConnectionTimeout: 3 * time.Second,
+			})
+			Expect(other.Connect()).ToNot(HaveOccurred())
+			DeferCleanup(other.Disconnect)
+
+			errs := make(chan error, 2)
+
+			for _, c := range []*client.Client{agent, other} {
+				go func(c *client.Client) {
+					msg := protocol.NewMessage("foo", map[string]interface{}{"a": "b"})
+					msg.Options = &protocol.MessageOptions{Chunk: "same"}
+					errs <- c.Send(msg)
+				}(c)
+			}
+
+			time.Sleep(200 * time.Millisecond)
+			close(release)
+
+			Eventually(errs, 3*time.Second).Should(Receive(BeNil()))
+			Eventually(errs, 3*time.Second).Should(Receive(BeNil()))
+		})
+	})
+
+	When("the websocket peer does not ack", func() {
+		BeforeEach(func() {
+			handlerErr = errors.New("nope")
+		})
+
+		It("does not ack the local client", func() {
+			msg := protocol.NewMessage("foo", map[string]interface{}{"a": "b"})
+			Expect(agent.Send(msg)).To(HaveOccurred())
+		})
+	})
+
+	It("returns ErrServerClosed from Serve after Close", func() {
+		Expect(tunnel.Close()).ToNot(HaveOccurred())
+		Eventually(serveErr).Should(Receive(MatchError(ErrServerClosed)))
+	})
+})
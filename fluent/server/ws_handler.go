@@ -0,0 +1,122 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/IBM/fluent-forward-go/fluent/client/ws"
+	"github.com/IBM/fluent-forward-go/fluent/protocol"
+	"github.com/IBM/fluent-forward-go/fluent/trace"
+	"github.com/gorilla/websocket"
+)
+
+type WSHandlerOptions struct {
+	// Handler receives the events decoded from every message. It is required.
+	Handler EventHandler
+	// TokenValidator, if set, validates the token sent in AuthHeader before
+	// the connection is upgraded. If nil, no authentication is performed.
+	TokenValidator TokenValidator
+	// AuthHeader is the name of the header that carries the token. The
+	// default is AuthorizationHeader.
+	AuthHeader string
+	// AuthScheme, if set, must prefix the token; e.g., "Bearer" expects
+	// "Bearer <token>". The prefix is removed before validation.
+	AuthScheme string
+	// Upgrader is used to upgrade HTTP connections. If nil, a zero-value
+	// websocket.Upgrader is used.
+	Upgrader *websocket.Upgrader
+	// ConnectionOptions configures each websocket connection. Its
+	// ReadHandler is replaced by the WSHandler's own.
+	ConnectionOptions ws.ConnectionOptions
+	// Propagator, if set, extracts the trace context carried by the first
+	// event in each message into the context passed to Handler.
+	Propagator trace.Propagator
+	// DecodeOptions limits the size and shape of the messages accepted. Its
+	// MaxMessageSize also limits the size of a frame. A connection that
+	// exceeds a limit is closed with CloseMessageTooBig. If nil,
+	// the limits of protocol.DefaultDecodeOptions are applied.
+	DecodeOptions *protocol.DecodeOptions
+}
+
+// WSHandler is an http.Handler that receives Fluent Forward messages over
+// websocket connections. Each binary frame must contain one or more complete
+// messages in any of the Forward modes. The events are passed to the
+// configured EventHandler and, when the client asked for an ack by setting
+// the "chunk" option, an AckMessage is sent back in its own frame once the
+// handler returns without error.
+type WSHandler struct {
+	*wsServer
+	handler    EventHandler
+	propagator trace.Propagator
+}
+
+func NewWSHandler(opts WSHandlerOptions) *WSHandler {
+	return &WSHandler{
+		wsServer: newWSServer(opts.TokenValidator, opts.AuthHeader, opts.AuthScheme,
+			opts.Upgrader, opts.ConnectionOptions, opts.DecodeOptions),
+		handler:    opts.Handler,
+		propagator: opts.Propagator,
+	}
+}
+
+func (h *WSHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	h.serve(w, r, func(conn ws.Connection, p []byte) error {
+		return h.handleFrame(ctx, conn, p)
+	})
+}
+
+// handleFrame decodes and dispatches every message in the frame, acking each
+// one that requested it.
+func (h *WSHandler) handleFrame(ctx context.Context, conn ws.Connection, p []byte) error {
+	var (
+		fm  *forwardMessage
+		err error
+	)
+
+	for len(p) > 0 {
+		if fm, p, err = decodeForwardMessage(p, h.decodeOpts); err != nil {
+			return err
+		}
+
+		msgCtx := trace.ExtractEntries(ctx, h.propagator, fm.Entries)
+
+		if err = h.handler.HandleEvents(msgCtx, fm.Tag, fm.Entries); err != nil {
+			h.logger.Println("handler error:", err)
+			continue
+		}
+
+		if chunk := fm.Chunk(); chunk != "" {
+			if err = writeAck(conn, chunk); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,317 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package server_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/IBM/fluent-forward-go/fluent/client"
+	"github.com/IBM/fluent-forward-go/fluent/client/ws"
+	"github.com/IBM/fluent-forward-go/fluent/protocol"
+	. "github.com/IBM/fluent-forward-go/fluent/server"
+	"github.com/gorilla/websocket"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/tinylib/msgp/msgp"
+)
+
+type received struct {
+	tag     string
+	entries protocol.EntryList
+}
+
+var _ = Describe("WSHandler", func() {
+	var (
+		opts       WSHandlerOptions
+		handler    *WSHandler
+		svr        *httptest.Server
+		factory    *client.DefaultWSConnectionFactory
+		cli        *client.WSClient
+		events     chan received
+		acks       chan string
+		closes     chan int
+		handlerErr error
+		entries    protocol.EntryList
+	)
+
+	BeforeEach(func() {
+		events = make(chan received, 10)
+		acks = make(chan string, 10)
+		closes = make(chan int, 10)
+		handlerErr = nil
+
+		opts = WSHandlerOptions{
+			Handler: EventHandlerFunc(func(_ context.Context, tag string, entries protocol.EntryList) error {
+				events <- received{tag, entries}
+				return handlerErr
+			}),
+		}
+
+		factory = &client.DefaultWSConnectionFactory{}
+
+		entries = protocol.EntryList{
+			{
+				Timestamp: protocol.EventTime{Time: time.Unix(1000, 0)},
+				Record:    map[string]interface{}{"first": "Sir"},
+			},
+			{
+				Timestamp: protocol.EventTime{Time: time.Unix(2000, 0)},
+				Record:    map[string]interface{}{"last": "Gawain"},
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		handler = NewWSHandler(opts)
+		svr = httptest.NewServer(handler)
+		factory.URL = "ws" + strings.TrimPrefix(svr.URL, "http")
+
+		// the read handler can outlive the spec, so it must not
+		// reference variables that the next spec reassigns
+		ackCh, closeCh := acks, closes
+
+		cli = client.NewWS(client.WSConnectionOptions{
+			Factory: factory,
+			ConnectionOptions: ws.ConnectionOptions{
+				ReadHandler: func(conn ws.Connection, _ int, p []byte, err error) error {
+					if err != nil {
+						var ce *websocket.CloseError
+						if errors.As(err, &ce) {
+							closeCh <- ce.Code
+						}
+
+						return err
+					}
+
+					var ack protocol.AckMessage
+					if _, err := ack.UnmarshalMsg(p); err == nil {
+						ackCh <- ack.Ack
+					}
+
+					return nil
+				},
+			},
+		})
+	})
+
+	AfterEach(func() {
+		_ = cli.Disconnect()
+		Expect(handler.Close()).ToNot(HaveOccurred())
+		svr.Close()
+	})
+
+	When("connected", func() {
+		JustBeforeEach(func() {
+			Expect(cli.Connect()).ToNot(HaveOccurred())
+		})
+
+		It("receives Message mode", func() {
+			msg := &protocol.Message{Tag: "foo", Timestamp: 1000, Record: map[string]interface{}{"a": "b"}}
+			Expect(cli.Send(msg)).ToNot(HaveOccurred())
+
+			var r received
+			Eventually(events).Should(Receive(&r))
+			Expect(r.tag).To(Equal("foo"))
+			Expect(r.entries).To(HaveLen(1))
+			Expect(r.entries[0].Timestamp.Unix()).To(BeEquivalentTo(1000))
+			Expect(r.entries[0].Record).To(Equal(map[string]interface{}{"a": "b"}))
+			Consistently(acks).ShouldNot(Receive())
+		})
+
+		It("receives MessageExt mode", func() {
+			msg := &protocol.MessageExt{Tag: "foo", Timestamp: entries[0].Timestamp, Record: entries[0].Record}
+			Expect(cli.Send(msg)).ToNot(HaveOccurred())
+
+			var r received
+			Eventually(events).Should(Receive(&r))
+			Expect(r.tag).To(Equal("foo"))
+			Expect(r.entries.Equal(entries[:1])).To(BeTrue())
+		})
+
+		It("receives Forward mode", func() {
+			Expect(cli.Send(protocol.NewForwardMessage("fwd", entries))).ToNot(HaveOccurred())
+
+			var r received
+			Eventually(events).Should(Receive(&r))
+			Expect(r.tag).To(Equal("fwd"))
+			Expect(r.entries.Equal(entries)).To(BeTrue())
+		})
+
+		It("receives PackedForward mode", func() {
+			msg, err := protocol.NewPackedForwardMessage("pkd", entries)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cli.Send(msg)).ToNot(HaveOccurred())
+
+			var r received
+			Eventually(events).Should(Receive(&r))
+			Expect(r.tag).To(Equal("pkd"))
+			Expect(r.entries.Equal(entries)).To(BeTrue())
+		})
+
+		It("receives CompressedPackedForward mode", func() {
+			msg, err := protocol.NewCompressedPackedForwardMessage("cmp", entries)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cli.Send(msg)).ToNot(HaveOccurred())
+
+			var r received
+			Eventually(events).Should(Receive(&r))
+			Expect(r.tag).To(Equal("cmp"))
+			Expect(r.entries.Equal(entries)).To(BeTrue())
+		})
+
+		It("receives entries with integer timestamps", func() {
+			bits := msgp.AppendArrayHeader(nil, 2)
+			bits = msgp.AppendString(bits, "int")
+			bits = msgp.AppendArrayHeader(bits, 1)
+			bits = msgp.AppendArrayHeader(bits, 2)
+			bits = msgp.AppendInt64(bits, 1000)
+			bits = msgp.AppendMapStrStr(bits, map[string]string{"a": "b"})
+			Expect(cli.SendRaw(bits)).ToNot(HaveOccurred())
+
+			var r received
+			Eventually(events).Should(Receive(&r))
+			Expect(r.tag).To(Equal("int"))
+			Expect(r.entries[0].Timestamp.Unix()).To(BeEquivalentTo(1000))
+		})
+
+		It("receives multiple messages in a single frame", func() {
+			bits, err := protocol.NewMessage("one", entries[0].Record).MarshalMsg(nil)
+			Expect(err).ToNot(HaveOccurred())
+			bits, err = protocol.NewMessage("two", entries[1].Record).MarshalMsg(bits)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cli.SendRaw(bits)).ToNot(HaveOccurred())
+
+			var r received
+			Eventually(events).Should(Receive(&r))
+			Expect(r.tag).To(Equal("one"))
+			Eventually(events).Should(Receive(&r))
+			Expect(r.tag).To(Equal("two"))
+		})
+
+		It("acks messages that have a chunk", func() {
+			msg := protocol.NewForwardMessage("fwd", entries)
+			chunk, err := msg.Chunk()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cli.Send(msg)).ToNot(HaveOccurred())
+
+			Eventually(events).Should(Receive())
+			Eventually(acks).Should(Receive(Equal(chunk)))
+		})
+
+		When("the handler returns an error", func() {
+			BeforeEach(func() {
+				handlerErr = errors.New("BOOM")
+			})
+
+			It("does not ack", func() {
+				msg := protocol.NewForwardMessage("fwd", entries)
+				_, err := msg.Chunk()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cli.Send(msg)).ToNot(HaveOccurred())
+
+				Eventually(events).Should(Receive())
+				Consistently(acks).ShouldNot(Receive())
+			})
+		})
+
+		It("closes the connection when a message is invalid", func() {
+			Expect(cli.SendRaw([]byte{0x92, 0x01, 0x02})).ToNot(HaveOccurred())
+			Eventually(func() bool {
+				return cli.Session() == nil || cli.Session().Connection.Closed() || cli.SendRaw([]byte{0xc0}) != nil
+			}).Should(BeTrue())
+			Consistently(events).ShouldNot(Receive())
+		})
+
+		When("DecodeOptions are set", func() {
+			BeforeEach(func() {
+				opts.DecodeOptions = &protocol.DecodeOptions{
+					MaxMessageSize: 1024,
+					MaxEntries:     1,
+				}
+			})
+
+			It("closes the connection with CloseMessageTooBig when a limit is exceeded", func() {
+				msg := protocol.NewForwardMessage("fwd", entries)
+				Expect(cli.Send(msg)).ToNot(HaveOccurred())
+
+				Eventually(closes).Should(Receive(Equal(websocket.CloseMessageTooBig)))
+				Consistently(events).ShouldNot(Receive())
+			})
+
+			It("limits the size of a frame", func() {
+				Expect(cli.SendRaw(make([]byte, 2048))).ToNot(HaveOccurred())
+
+				Eventually(closes).Should(Receive(Equal(websocket.CloseMessageTooBig)))
+				Consistently(events).ShouldNot(Receive())
+			})
+		})
+	})
+
+	When("a TokenValidator is set", func() {
+		BeforeEach(func() {
+			opts.AuthScheme = "Bearer"
+			opts.TokenValidator = TokenValidatorFunc(func(token string) error {
+				if token != "secret" {
+					return errors.New("bad token")
+				}
+
+				return nil
+			})
+		})
+
+		It("accepts valid tokens", func() {
+			factory.AuthScheme = "Bearer"
+			factory.TokenProvider = client.NewIAMAuthInfo("secret")
+			Expect(cli.Connect()).ToNot(HaveOccurred())
+		})
+
+		It("rejects invalid tokens", func() {
+			factory.AuthScheme = "Bearer"
+			factory.TokenProvider = client.NewIAMAuthInfo("guess")
+
+			err := cli.Connect()
+			Expect(err).To(HaveOccurred())
+
+			var connErr *client.WSConnError
+			Expect(errors.As(err, &connErr)).To(BeTrue())
+			Expect(connErr.StatusCode).To(Equal(http.StatusUnauthorized))
+			Expect(connErr.IsRetryable()).To(BeFalse())
+		})
+
+		It("rejects tokens without the scheme", func() {
+			factory.TokenProvider = client.NewIAMAuthInfo("secret")
+			Expect(cli.Connect()).To(HaveOccurred())
+		})
+
+		It("rejects missing tokens", func() {
+			Expect(cli.Connect()).To(HaveOccurred())
+		})
+	})
+})
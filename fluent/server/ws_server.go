@@ -0,0 +1,285 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/IBM/fluent-forward-go/fluent/client/ws"
+	"github.com/IBM/fluent-forward-go/fluent/protocol"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	AuthorizationHeader = "Authorization"
+)
+
+// TokenValidator validates the token presented by a websocket client in the
+// opening handshake. A non-nil error rejects the connection with a 401.
+type TokenValidator interface {
+	ValidateToken(token string) error
+}
+
+// TokenValidatorFunc is an adapter that allows an ordinary function to be
+// used as a TokenValidator.
+type TokenValidatorFunc func(token string) error
+
+func (f TokenValidatorFunc) ValidateToken(token string) error {
+	return f(token)
+}
+
+type noopLogger struct{}
+
+func (l *noopLogger) Println(_ ...interface{}) {}
+
+func (l *noopLogger) Printf(_ string, _ ...interface{}) {}
+
+// frameHandler processes a single binary frame. If it returns a
+// *websocket.CloseError, the connection is closed with that code and text; a
+// *protocol.LimitError closes it with CloseMessageTooBig, and any other
+// error with CloseInvalidFramePayloadData.
+type frameHandler func(conn ws.Connection, p []byte) error
+
+// wsServer implements what WSHandler and WSRelay have in common:
+// authenticating and upgrading requests and tracking the connections.
+type wsServer struct {
+	tokenValidator TokenValidator
+	authHeader     string
+	authScheme     string
+	upgrader       *websocket.Upgrader
+	connOpts       ws.ConnectionOptions
+	decodeOpts     *protocol.DecodeOptions
+	logger         ws.Logger
+	conns          map[ws.Connection]struct{}
+	connsLock      sync.Mutex
+}
+
+func newWSServer(tv TokenValidator, authHeader, authScheme string,
+	upgrader *websocket.Upgrader, connOpts ws.ConnectionOptions,
+	decodeOpts *protocol.DecodeOptions) *wsServer {
+	s := &wsServer{
+		tokenValidator: tv,
+		authHeader:     authHeader,
+		authScheme:     authScheme,
+		upgrader:       upgrader,
+		connOpts:       connOpts,
+		decodeOpts:     decodeOpts,
+		logger:         connOpts.Logger,
+		conns:          map[ws.Connection]struct{}{},
+	}
+
+	if s.authHeader == "" {
+		s.authHeader = AuthorizationHeader
+	}
+
+	if s.upgrader == nil {
+		s.upgrader = &websocket.Upgrader{}
+	}
+
+	if s.logger == nil {
+		s.logger = &noopLogger{}
+	}
+
+	if s.decodeOpts == nil {
+		opts := protocol.DefaultDecodeOptions()
+		s.decodeOpts = &opts
+	}
+
+	return s
+}
+
+// authenticate checks the request's token against the TokenValidator.
+func (s *wsServer) authenticate(r *http.Request) error {
+	if s.tokenValidator == nil {
+		return nil
+	}
+
+	token := r.Header.Get(s.authHeader)
+
+	if s.authScheme != "" {
+		prefix := s.authScheme + " "
+		if !strings.HasPrefix(token, prefix) {
+			return errors.New("missing auth scheme")
+		}
+
+		token = strings.TrimPrefix(token, prefix)
+	}
+
+	if token == "" {
+		return errors.New("missing token")
+	}
+
+	return s.tokenValidator.ValidateToken(token)
+}
+
+// serve authenticates and upgrades the request, then passes every binary
+// frame received on the connection to fh until the connection is closed.
+func (s *wsServer) serve(w http.ResponseWriter, r *http.Request, fh frameHandler) {
+	if err := s.authenticate(r); err != nil {
+		s.logger.Println("authentication failed:", err)
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+
+		return
+	}
+
+	c, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already replied to the client
+		s.logger.Println("upgrade failed:", err)
+		return
+	}
+
+	// frames larger than this are refused by the websocket library, which
+	// closes the connection with CloseMessageTooBig
+	if s.decodeOpts.MaxMessageSize > 0 {
+		c.SetReadLimit(int64(s.decodeOpts.MaxMessageSize))
+	}
+
+	opts := s.connOpts
+	opts.ReadHandler = s.readHandler(fh)
+
+	conn, err := ws.NewConnection(c, opts)
+	if err != nil {
+		s.logger.Println("new connection failed:", err)
+
+		_ = c.Close()
+
+		return
+	}
+
+	s.addConn(conn)
+	defer s.removeConn(conn)
+
+	if err := conn.Listen(); err != nil {
+		s.logger.Println("listen error:", err)
+	}
+}
+
+func (s *wsServer) readHandler(fh frameHandler) ws.ReadHandler {
+	return func(conn ws.Connection, messageType int, p []byte, err error) error {
+		if err != nil {
+			if !conn.Closed() {
+				_ = conn.Close()
+			}
+
+			return err
+		}
+
+		// closing waits for the read loop, which is blocked until this
+		// handler returns, so the connections are closed asynchronously
+		if messageType != websocket.BinaryMessage {
+			go s.closeWithMsg(conn, websocket.CloseUnsupportedData, "binary messages only")
+			return nil
+		}
+
+		if err = fh(conn, p); err != nil {
+			s.logger.Println("frame error:", err)
+
+			var (
+				ce *websocket.CloseError
+				le *protocol.LimitError
+			)
+
+			switch {
+			case errors.As(err, &ce):
+				go s.closeWithMsg(conn, ce.Code, ce.Text)
+			case errors.As(err, &le):
+				go s.closeWithMsg(conn, websocket.CloseMessageTooBig, le.Error())
+			default:
+				go s.closeWithMsg(conn, websocket.CloseInvalidFramePayloadData, "invalid message")
+			}
+		}
+
+		return nil
+	}
+}
+
+func (s *wsServer) closeWithMsg(conn ws.Connection, code int, msg string) {
+	if conn.Closed() {
+		return
+	}
+
+	if err := conn.CloseWithMsg(code, msg); err != nil {
+		s.logger.Println("close error:", err)
+	}
+}
+
+func (s *wsServer) addConn(conn ws.Connection) {
+	s.connsLock.Lock()
+	defer s.connsLock.Unlock()
+
+	s.conns[conn] = struct{}{}
+}
+
+func (s *wsServer) removeConn(conn ws.Connection) {
+	s.connsLock.Lock()
+	defer s.connsLock.Unlock()
+
+	delete(s.conns, conn)
+}
+
+// Close sends a close message to every active connection. It is meant to be
+// registered with http.Server.RegisterOnShutdown, since http.Server does not
+// track hijacked connections.
+func (s *wsServer) Close() error {
+	s.connsLock.Lock()
+
+	conns := make([]ws.Connection, 0, len(s.conns))
+	for conn := range s.conns {
+		conns = append(conns, conn)
+	}
+
+	s.connsLock.Unlock()
+
+	var err error
+
+	for _, conn := range conns {
+		if conn.Closed() {
+			continue
+		}
+
+		if cerr := conn.Close(); cerr != nil {
+			err = cerr
+		}
+	}
+
+	return err
+}
+
+func writeAck(conn ws.Connection, chunk string) error {
+	ack := protocol.AckMessage{Ack: chunk}
+
+	bits, err := ack.MarshalMsg(nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = conn.Write(bits)
+
+	return err
+}
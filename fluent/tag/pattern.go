@@ -0,0 +1,404 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package tag
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// maxAlternatives bounds the number of alternatives a pattern's braces may
+// expand to, so that a pattern like "{a,b}{a,b}{a,b}..." cannot blow up.
+const maxAlternatives = 1024
+
+// Pattern is a compiled Fluentd match pattern. It is safe for concurrent
+// use. See
+// https://docs.fluentd.org/configuration/config-file#how-do-the-match-patterns-work
+//
+// A pattern is one or more patterns separated by whitespace, and matches a
+// tag if any of them does. Each of those is either a glob or a regular
+// expression:
+//
+//   - "*" matches a single tag part, so "a.*" matches "a.b" but not "a" or
+//     "a.b.c".
+//   - "**" matches zero or more tag parts, so "a.**" matches "a", "a.b" and
+//     "a.b.c".
+//   - "{x,y}" matches either x or y, which may themselves be globs.
+//   - "\" escapes the character that follows it.
+//   - "/regexp/" matches tags that the regular expression matches in full.
+//     It uses Go's RE2 syntax, which has no lookahead or lookbehind.
+type Pattern struct {
+	pattern  string
+	matchers []matcher
+}
+
+type matcher interface {
+	match(tag string) bool
+}
+
+// Compile parses a match pattern.
+func Compile(pattern string) (*Pattern, error) {
+	fields := strings.Fields(pattern)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("%w %q: empty pattern", ErrInvalidPattern, pattern)
+	}
+
+	p := &Pattern{pattern: pattern}
+
+	for _, f := range fields {
+		m, err := compile(f)
+		if err != nil {
+			return nil, fmt.Errorf("%w %q: %s", ErrInvalidPattern, f, err)
+		}
+
+		p.matchers = append(p.matchers, m)
+	}
+
+	return p, nil
+}
+
+// MustCompile is like Compile but panics if the pattern cannot be parsed.
+func MustCompile(pattern string) *Pattern {
+	p, err := Compile(pattern)
+	if err != nil {
+		panic(err)
+	}
+
+	return p
+}
+
+// Match reports whether the tag matches the pattern. It compiles the
+// pattern on every call; use Compile to match many tags.
+func Match(pattern, tag string) (bool, error) {
+	p, err := Compile(pattern)
+	if err != nil {
+		return false, err
+	}
+
+	return p.Match(tag), nil
+}
+
+// Match reports whether the tag matches the pattern.
+func (p *Pattern) Match(tag string) bool {
+	for _, m := range p.matchers {
+		if m.match(tag) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// String returns the source text of the pattern.
+func (p *Pattern) String() string {
+	return p.pattern
+}
+
+func compile(pattern string) (matcher, error) {
+	if strings.HasPrefix(pattern, "/") {
+		if len(pattern) < 2 || !strings.HasSuffix(pattern, "/") {
+			return nil, errors.New("unterminated regular expression")
+		}
+
+		re, err := regexp.Compile(`\A(?:` + pattern[1:len(pattern)-1] + `)\z`)
+		if err != nil {
+			return nil, err
+		}
+
+		return regexpMatcher{re}, nil
+	}
+
+	progs, err := expand(parseGlob(pattern))
+	if err != nil {
+		return nil, err
+	}
+
+	return globMatcher(progs), nil
+}
+
+type regexpMatcher struct {
+	re *regexp.Regexp
+}
+
+func (m regexpMatcher) match(tag string) bool {
+	return m.re.MatchString(tag)
+}
+
+type op uint8
+
+const (
+	// opLiteral matches the byte c.
+	opLiteral op = iota
+	// opPart matches the rest of a tag part, like [^.]* does.
+	opPart
+	// opAny matches anything, like .* does.
+	opAny
+	// opParts matches whole tag parts up to and including their trailing
+	// dot, or nothing at the start of the tag, like (?:.*\.|\A) does.
+	opParts
+	// opBoundary matches nothing, but only at the end of a tag part, like
+	// (?![^.]) does.
+	opBoundary
+)
+
+type instr struct {
+	op op
+	c  byte
+}
+
+// node is an instruction, or a brace group if alts is not nil.
+type node struct {
+	instr
+	alts [][]node
+}
+
+// parseGlob translates a glob the way Fluentd's GlobMatchPattern does,
+// down to its quirks: a "." is only emitted once the next character is
+// known not to start a "**", unclosed braces are closed at the end, and a
+// trailing "." or "\" is dropped.
+func parseGlob(pattern string) []node {
+	var (
+		// the alternatives read so far in each open brace
+		stack [][][]node
+		// the top level sequence, and the open alternative of each brace
+		seqs   = [][]node{nil}
+		escape bool
+		dot    bool
+	)
+
+	add := func(n node) {
+		seqs[len(seqs)-1] = append(seqs[len(seqs)-1], n)
+	}
+
+	literal := func(c byte) {
+		add(node{instr: instr{op: opLiteral, c: c}})
+	}
+
+	pop := func() []node {
+		seq := seqs[len(seqs)-1]
+		seqs = seqs[:len(seqs)-1]
+
+		return seq
+	}
+
+	closeBrace := func() {
+		alts := append(stack[len(stack)-1], pop())
+		stack = stack[:len(stack)-1]
+		add(node{alts: alts})
+	}
+
+	for i := 0; i < len(pattern); {
+		c := pattern[i]
+
+		if escape {
+			literal(c)
+			escape = false
+			i++
+
+			continue
+		}
+
+		if strings.HasPrefix(pattern[i:], "**") {
+			if dot {
+				add(node{instr: instr{op: opBoundary}})
+				dot = false
+			}
+
+			if strings.HasPrefix(pattern[i+2:], ".") {
+				add(node{instr: instr{op: opParts}})
+				i += 3
+			} else {
+				add(node{instr: instr{op: opAny}})
+				i += 2
+			}
+
+			continue
+		}
+
+		if dot {
+			literal('.')
+			dot = false
+		}
+
+		switch {
+		case c == '\\':
+			escape = true
+		case c == '.':
+			dot = true
+		case c == '*':
+			add(node{instr: instr{op: opPart}})
+		case c == '{':
+			stack = append(stack, nil)
+			seqs = append(seqs, nil)
+		case c == '}' && len(stack) > 0:
+			closeBrace()
+		case c == ',' && len(stack) > 0:
+			stack[len(stack)-1] = append(stack[len(stack)-1], pop())
+			seqs = append(seqs, nil)
+		default:
+			literal(c)
+		}
+
+		i++
+	}
+
+	for len(stack) > 0 {
+		closeBrace()
+	}
+
+	return seqs[0]
+}
+
+// expand flattens brace groups into one program per alternative.
+func expand(seq []node) ([][]instr, error) {
+	progs := [][]instr{nil}
+
+	for _, n := range seq {
+		if n.alts == nil {
+			for i := range progs {
+				progs[i] = append(progs[i], n.instr)
+			}
+
+			continue
+		}
+
+		var tails [][]instr
+
+		for _, alt := range n.alts {
+			t, err := expand(alt)
+			if err != nil {
+				return nil, err
+			}
+
+			tails = append(tails, t...)
+		}
+
+		if len(progs)*len(tails) > maxAlternatives {
+			return nil, fmt.Errorf("braces expand to more than %d alternatives", maxAlternatives)
+		}
+
+		next := make([][]instr, 0, len(progs)*len(tails))
+
+		for _, p := range progs {
+			for _, t := range tails {
+				prog := make([]instr, 0, len(p)+len(t))
+				prog = append(append(prog, p...), t...)
+				next = append(next, prog)
+			}
+		}
+
+		progs = next
+	}
+
+	return progs, nil
+}
+
+type globMatcher [][]instr
+
+func (m globMatcher) match(tag string) bool {
+	for _, prog := range m {
+		r := run{
+			prog: prog,
+			tag:  tag,
+			memo: make([]int8, (len(prog)+1)*(len(tag)+1)),
+		}
+
+		if r.match(0, 0) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// run matches a program against a tag by backtracking, remembering the
+// outcome for each instruction and offset so it never does the same work
+// twice.
+type run struct {
+	prog []instr
+	tag  string
+	memo []int8
+}
+
+func (r *run) match(pc, i int) bool {
+	key := pc*(len(r.tag)+1) + i
+	if v := r.memo[key]; v != 0 {
+		return v > 0
+	}
+
+	ok := r.step(pc, i)
+
+	r.memo[key] = -1
+	if ok {
+		r.memo[key] = 1
+	}
+
+	return ok
+}
+
+func (r *run) step(pc, i int) bool {
+	if pc == len(r.prog) {
+		return i == len(r.tag)
+	}
+
+	in := r.prog[pc]
+
+	switch in.op {
+	case opLiteral:
+		return i < len(r.tag) && r.tag[i] == in.c && r.match(pc+1, i+1)
+	case opPart:
+		for j := i; ; j++ {
+			if r.match(pc+1, j) {
+				return true
+			}
+
+			if j == len(r.tag) || r.tag[j] == '.' {
+				return false
+			}
+		}
+	case opAny:
+		for j := i; j <= len(r.tag); j++ {
+			if r.match(pc+1, j) {
+				return true
+			}
+		}
+	case opParts:
+		if i == 0 && r.match(pc+1, 0) {
+			return true
+		}
+
+		for j := i; j < len(r.tag); j++ {
+			if r.tag[j] == '.' && r.match(pc+1, j+1) {
+				return true
+			}
+		}
+	case opBoundary:
+		return (i == len(r.tag) || r.tag[i] == '.') && r.match(pc+1, i)
+	}
+
+	return false
+}
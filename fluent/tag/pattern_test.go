@@ -0,0 +1,203 @@
+package tag_test
+
+import (
+	"strings"
+
+	"github.com/IBM/fluent-forward-go/fluent/tag"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Pattern", func() {
+	match := func(pattern, t string) {
+		p, err := tag.Compile(pattern)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(p.Match(t)).To(BeTrue(), "%q should match %q", pattern, t)
+	}
+
+	noMatch := func(pattern, t string) {
+		p, err := tag.Compile(pattern)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(p.Match(t)).To(BeFalse(), "%q should not match %q", pattern, t)
+	}
+
+	// The cases from Fluentd's test/test_match.rb.
+	DescribeTable("Fluentd's glob cases",
+		func(f func(string, string), pattern, t string) {
+			f(pattern, t)
+		},
+		Entry(nil, match, "a", "a"),
+		Entry(nil, match, "a.b", "a.b"),
+		Entry(nil, noMatch, "a", "b"),
+		Entry(nil, noMatch, "a.b", "aab"),
+
+		Entry(nil, match, "a*", "a"),
+		Entry(nil, match, "a*", "ab"),
+		Entry(nil, match, "a*", "abc"),
+		Entry(nil, match, "*a", "a"),
+		Entry(nil, match, "*a", "ba"),
+		Entry(nil, match, "*a", "cba"),
+		Entry(nil, match, "*a*", "a"),
+		Entry(nil, match, "*a*", "ba"),
+		Entry(nil, match, "*a*", "ac"),
+		Entry(nil, match, "*a*", "bac"),
+		Entry(nil, noMatch, "a*", "a.b"),
+		Entry(nil, noMatch, "a*", "ab.c"),
+		Entry(nil, noMatch, "a*", "ba"),
+		Entry(nil, noMatch, "*a", "ab"),
+		Entry(nil, match, "a.*", "a.b"),
+		Entry(nil, match, "a.*", "a.c"),
+		Entry(nil, noMatch, "a.*", "ab"),
+		Entry(nil, match, "a.*.c", "a.b.c"),
+		Entry(nil, match, "a.*.c", "a.c.c"),
+		Entry(nil, noMatch, "a.*.c", "a.c"),
+
+		Entry(nil, match, "a.**", "a"),
+		Entry(nil, noMatch, "a.**", "ab"),
+		Entry(nil, noMatch, "a.**", "abc"),
+		Entry(nil, match, "a.**", "a.b"),
+		Entry(nil, noMatch, "a.**", "ab.c"),
+		Entry(nil, noMatch, "a.**", "ab.d.e"),
+		Entry(nil, match, "a**", "a"),
+		Entry(nil, match, "a**", "ab"),
+		Entry(nil, match, "a**", "abc"),
+		Entry(nil, match, "a**", "a.b"),
+		Entry(nil, match, "a**", "ab.c"),
+		Entry(nil, match, "a**", "ab.d.e"),
+		Entry(nil, match, "**.a", "a"),
+		Entry(nil, noMatch, "**.a", "ba"),
+		Entry(nil, noMatch, "**.a", "c.ba"),
+		Entry(nil, match, "**.a", "b.a"),
+		Entry(nil, match, "**.a", "cb.a"),
+		Entry(nil, match, "**.a", "d.e.a"),
+		Entry(nil, match, "**a", "a"),
+		Entry(nil, match, "**a", "ba"),
+		Entry(nil, match, "**a", "c.ba"),
+		Entry(nil, match, "**a", "b.a"),
+		Entry(nil, match, "**a", "cb.a"),
+		Entry(nil, match, "**a", "d.e.a"),
+
+		Entry(nil, match, "a.{b,c}", "a.b"),
+		Entry(nil, match, "a.{b,c}", "a.c"),
+		Entry(nil, noMatch, "a.{b,c}", "a.d"),
+		Entry(nil, match, "a.{b,c}.**", "a.b"),
+		Entry(nil, match, "a.{b,c}.**", "a.c"),
+		Entry(nil, noMatch, "a.{b,c}.**", "a.d"),
+		Entry(nil, noMatch, "a.{b,c}.**", "a.cd"),
+		Entry(nil, match, "a.{b.**,c}", "a.b"),
+		Entry(nil, match, "a.{b.**,c}", "a.b.c"),
+		Entry(nil, match, "a.{b.**,c}", "a.c"),
+		Entry(nil, noMatch, "a.{b.**,c}", "a.c.d"),
+
+		Entry(nil, match, "a b", "a"),
+		Entry(nil, match, "a b", "b"),
+		Entry(nil, noMatch, "a b", "c"),
+		Entry(nil, match, "a.* b.*", "a.x"),
+		Entry(nil, match, "a.* b.*", "b.x"),
+		Entry(nil, noMatch, "a.* b.*", "c.x"),
+	)
+
+	// The examples from Fluentd's documentation of match patterns.
+	DescribeTable("Fluentd's documented examples",
+		func(f func(string, string), pattern, t string) {
+			f(pattern, t)
+		},
+		Entry(nil, match, "a.*", "a.b"),
+		Entry(nil, noMatch, "a.*", "a"),
+		Entry(nil, noMatch, "a.*", "a.b.c"),
+		Entry(nil, match, "a.**", "a"),
+		Entry(nil, match, "a.**", "a.b"),
+		Entry(nil, match, "a.**", "a.b.c"),
+		Entry(nil, match, "{a,b}", "a"),
+		Entry(nil, match, "{a,b}", "b"),
+		Entry(nil, noMatch, "{a,b}", "c"),
+		Entry(nil, match, "a.{b,c}.*", "a.b.x"),
+		Entry(nil, match, "a.{b,c}.*", "a.c.x"),
+		Entry(nil, noMatch, "a.{b,c}.*", "a.d.x"),
+		Entry(nil, match, "a.{b,c.**}", "a.b"),
+		Entry(nil, match, "a.{b,c.**}", "a.c"),
+		Entry(nil, match, "a.{b,c.**}", "a.c.x.y"),
+		Entry(nil, noMatch, "a.{b,c.**}", "a.b.x"),
+		Entry(nil, match, "a.** b.*", "a"),
+		Entry(nil, match, "a.** b.*", "a.b"),
+		Entry(nil, match, "a.** b.*", "a.b.c"),
+		Entry(nil, match, "a.** b.*", "b.d"),
+		Entry(nil, noMatch, "a.** b.*", "b"),
+		Entry(nil, match, `/a\.[0-9]+/`, "a.42"),
+		Entry(nil, noMatch, `/a\.[0-9]+/`, "a.42.b"),
+		Entry(nil, noMatch, `/a\.[0-9]+/`, "xa.42"),
+	)
+
+	DescribeTable("edge cases",
+		func(f func(string, string), pattern, t string) {
+			f(pattern, t)
+		},
+		Entry("** at a part boundary before a brace", match, "a.{b.**,c}.d", "a.b.d"),
+		Entry(nil, match, "a.{b.**,c}.d", "a.b.x.d"),
+		Entry(nil, match, "a.{b.**,c}.d", "a.c.d"),
+		Entry(nil, noMatch, "a.{b.**,c}.d", "a.bx.d"),
+		Entry("** between parts", match, "a.**.b", "a.b"),
+		Entry(nil, match, "a.**.b", "a.x.y.b"),
+		Entry(nil, noMatch, "a.**.b", "ab"),
+		Entry(nil, noMatch, "a.**.b", "a.xb"),
+		Entry("** followed by a literal", match, "a.**b", "a.b"),
+		Entry(nil, match, "a.**b", "a.xb"),
+		Entry(nil, noMatch, "a.**b", "ab"),
+		Entry("a dot before a brace", noMatch, "a.{**,c}", "a"),
+		Entry(nil, match, "a.{**,c}", "a."),
+		Entry(nil, match, "a.{**,c}", "a.x.y"),
+		Entry("nested braces", match, "{a,b.{c,d}}.e", "b.d.e"),
+		Entry(nil, noMatch, "{a,b.{c,d}}.e", "b.e"),
+		Entry("an unclosed brace", match, "a.{b,c", "a.c"),
+		Entry("a stray brace", match, "a}", "a}"),
+		Entry("a stray comma", match, "a,b", "a,b"),
+		Entry("an escaped star", match, `a.\*`, "a.*"),
+		Entry(nil, noMatch, `a.\*`, "a.b"),
+		Entry("an escaped brace", match, `\{a,b}`, "{a,b}"),
+		Entry("a trailing dot", match, "a.", "a"),
+		Entry("special characters", match, "a+b.c$", "a+b.c$"),
+		Entry(nil, noMatch, "a+b", "aab"),
+		Entry("UTF-8", match, "ログ.*", "ログ.web"),
+		Entry("a regexp with alternatives", match, "/a|b/", "b"),
+		Entry(nil, noMatch, "/a|b/", "ab"),
+	)
+
+	It("rejects invalid patterns", func() {
+		for _, pattern := range []string{"", "  ", "/a", "/(/", `/(?!a\.).*/`} {
+			_, err := tag.Compile(pattern)
+			Expect(err).To(MatchError(tag.ErrInvalidPattern), pattern)
+		}
+	})
+
+	It("limits brace expansion", func() {
+		_, err := tag.Compile(strings.Repeat("{a,b}", 11))
+		Expect(err).To(MatchError(tag.ErrInvalidPattern))
+		Expect(err).To(MatchError(ContainSubstring("more than 1024 alternatives")))
+
+		Expect(tag.MustCompile(strings.Repeat("{a,b}", 10)).Match("abababbbba")).To(BeTrue())
+	})
+
+	It("matches long tags", func() {
+		p := tag.MustCompile("**.*.**.*.**.z")
+		t := strings.Repeat("a.", 500)
+		Expect(p.Match(t + "z")).To(BeTrue())
+		Expect(p.Match(t + "y")).To(BeFalse())
+	})
+
+	It("keeps its source", func() {
+		Expect(tag.MustCompile("a.** b").String()).To(Equal("a.** b"))
+	})
+
+	It("panics in MustCompile", func() {
+		Expect(func() { tag.MustCompile("") }).To(Panic())
+	})
+
+	It("matches without compiling first", func() {
+		ok, err := tag.Match("a.*", "a.b")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeTrue())
+
+		_, err = tag.Match("/(/", "a")
+		Expect(err).To(MatchError(tag.ErrInvalidPattern))
+	})
+})
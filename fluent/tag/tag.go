@@ -0,0 +1,195 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+// Package tag implements Fluentd's tag syntax: match patterns, the
+// tag_parts, tag_prefix and tag_suffix placeholders, and the prefix and
+// suffix rewriting done by plugins such as rewrite_tag_filter.
+//
+// A tag is one or more non-empty parts separated by dots, e.g. "app.web".
+package tag
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	// ErrInvalidTag is returned by Validate for tags that Fluentd cannot
+	// route reliably.
+	ErrInvalidTag = errors.New("invalid tag")
+	// ErrInvalidPattern is returned by Compile for patterns that cannot be
+	// parsed.
+	ErrInvalidPattern = errors.New("invalid match pattern")
+)
+
+// Validate checks that a tag is non-empty, valid UTF-8, free of whitespace
+// and control characters, and has no empty parts, i.e. no leading, trailing
+// or doubled dots. Fluentd recommends restricting tags to lower case
+// letters, digits and underscores, but does not enforce it, and neither
+// does Validate.
+func Validate(tag string) error {
+	if tag == "" {
+		return fmt.Errorf("%w: empty tag", ErrInvalidTag)
+	}
+
+	if !utf8.ValidString(tag) {
+		return fmt.Errorf("%w %q: not valid UTF-8", ErrInvalidTag, tag)
+	}
+
+	for i, r := range tag {
+		if unicode.IsSpace(r) || unicode.IsControl(r) {
+			return fmt.Errorf("%w %q: invalid character %q at offset %d", ErrInvalidTag, tag, r, i)
+		}
+	}
+
+	for i, part := range Parts(tag) {
+		if part == "" {
+			return fmt.Errorf("%w %q: part %d is empty", ErrInvalidTag, tag, i)
+		}
+	}
+
+	return nil
+}
+
+// Parts splits a tag into its parts, like Fluentd's tag_parts placeholder.
+func Parts(tag string) []string {
+	return strings.Split(tag, ".")
+}
+
+// Part returns the part of the tag at index i, like tag_parts[i]. A
+// negative index counts back from the last part. The result is false if
+// the index is out of range.
+func Part(tag string, i int) (string, bool) {
+	parts := Parts(tag)
+
+	i, ok := index(i, len(parts))
+	if !ok {
+		return "", false
+	}
+
+	return parts[i], true
+}
+
+// Prefix returns the parts of the tag up to and including index i, like
+// tag_prefix[i]: Prefix("debug.my.app", 1) is "debug.my". A negative index
+// counts back from the last part. The result is false if the index is out
+// of range.
+func Prefix(tag string, i int) (string, bool) {
+	parts := Parts(tag)
+
+	i, ok := index(i, len(parts))
+	if !ok {
+		return "", false
+	}
+
+	return strings.Join(parts[:i+1], "."), true
+}
+
+// Suffix returns the parts of the tag from index i on, like tag_suffix[i]:
+// Suffix("debug.my.app", 1) is "my.app". A negative index counts back from
+// the last part. The result is false if the index is out of range.
+func Suffix(tag string, i int) (string, bool) {
+	parts := Parts(tag)
+
+	i, ok := index(i, len(parts))
+	if !ok {
+		return "", false
+	}
+
+	return strings.Join(parts[i:], "."), true
+}
+
+func index(i, n int) (int, bool) {
+	if i < 0 {
+		i += n
+	}
+
+	return i, 0 <= i && i < n
+}
+
+// AddPrefix prepends the parts of prefix to the tag, like add_tag_prefix.
+// Either may be empty.
+func AddPrefix(tag, prefix string) string {
+	return join(prefix, tag)
+}
+
+// AddSuffix appends the parts of suffix to the tag, like add_tag_suffix.
+// Either may be empty.
+func AddSuffix(tag, suffix string) string {
+	return join(tag, suffix)
+}
+
+func join(a, b string) string {
+	if a == "" {
+		return b
+	}
+
+	if b == "" {
+		return a
+	}
+
+	return a + "." + b
+}
+
+// StripPrefix removes the leading parts of the tag that equal prefix, like
+// remove_tag_prefix, and reports whether it did. Unlike remove_tag_prefix
+// it only removes whole parts, so "app" is stripped from "app.web" but not
+// from "apps.web". Stripping the whole tag leaves an empty tag.
+func StripPrefix(tag, prefix string) (string, bool) {
+	if prefix == "" {
+		return tag, false
+	}
+
+	if tag == prefix {
+		return "", true
+	}
+
+	if rest := strings.TrimPrefix(tag, prefix+"."); len(rest) < len(tag) {
+		return rest, true
+	}
+
+	return tag, false
+}
+
+// StripSuffix removes the trailing parts of the tag that equal suffix, like
+// remove_tag_suffix, and reports whether it did. It only removes whole
+// parts, and stripping the whole tag leaves an empty tag.
+func StripSuffix(tag, suffix string) (string, bool) {
+	if suffix == "" {
+		return tag, false
+	}
+
+	if tag == suffix {
+		return "", true
+	}
+
+	if rest := strings.TrimSuffix(tag, "."+suffix); len(rest) < len(tag) {
+		return rest, true
+	}
+
+	return tag, false
+}
@@ -0,0 +1,13 @@
+package tag_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestTag(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Tag Suite")
+}
@@ -0,0 +1,108 @@
+package tag_test
+
+import (
+	"github.com/IBM/fluent-forward-go/fluent/tag"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tag", func() {
+	Describe("Validate", func() {
+		It("accepts valid tags", func() {
+			for _, t := range []string{"a", "debug.my.app", "app_1.web-2", "ログ.web", "a.*"} {
+				Expect(tag.Validate(t)).ToNot(HaveOccurred(), t)
+			}
+		})
+
+		DescribeTable("rejects invalid tags",
+			func(t, msg string) {
+				err := tag.Validate(t)
+				Expect(err).To(MatchError(tag.ErrInvalidTag))
+				Expect(err).To(MatchError(ContainSubstring(msg)))
+			},
+			Entry("empty", "", "empty tag"),
+			Entry("leading dot", ".a", "part 0 is empty"),
+			Entry("trailing dot", "a.", "part 1 is empty"),
+			Entry("doubled dot", "a..b", "part 1 is empty"),
+			Entry("space", "a b", "invalid character ' ' at offset 1"),
+			Entry("tab", "a.\tb", `invalid character '\t' at offset 2`),
+			Entry("control", "a\x00", `invalid character '\x00' at offset 1`),
+			Entry("invalid UTF-8", "a\xff", "not valid UTF-8"),
+		)
+	})
+
+	// The examples from Fluentd's record_transformer documentation, for the
+	// tag "debug.my.app".
+	Describe("placeholders", func() {
+		const t = "debug.my.app"
+
+		It("splits parts", func() {
+			Expect(tag.Parts(t)).To(Equal([]string{"debug", "my", "app"}))
+		})
+
+		DescribeTable("Part, Prefix and Suffix",
+			func(f func(string, int) (string, bool), i int, expected string) {
+				s, ok := f(t, i)
+				Expect(ok).To(BeTrue())
+				Expect(s).To(Equal(expected))
+			},
+			Entry("tag_parts[0]", tag.Part, 0, "debug"),
+			Entry("tag_parts[1]", tag.Part, 1, "my"),
+			Entry("tag_parts[-1]", tag.Part, -1, "app"),
+			Entry("tag_prefix[0]", tag.Prefix, 0, "debug"),
+			Entry("tag_prefix[1]", tag.Prefix, 1, "debug.my"),
+			Entry("tag_prefix[2]", tag.Prefix, 2, "debug.my.app"),
+			Entry("tag_prefix[-1]", tag.Prefix, -1, "debug.my.app"),
+			Entry("tag_suffix[0]", tag.Suffix, 0, "debug.my.app"),
+			Entry("tag_suffix[1]", tag.Suffix, 1, "my.app"),
+			Entry("tag_suffix[2]", tag.Suffix, 2, "app"),
+			Entry("tag_suffix[-1]", tag.Suffix, -1, "app"),
+		)
+
+		It("reports indexes out of range", func() {
+			for _, f := range []func(string, int) (string, bool){tag.Part, tag.Prefix, tag.Suffix} {
+				_, ok := f(t, 3)
+				Expect(ok).To(BeFalse())
+				_, ok = f(t, -4)
+				Expect(ok).To(BeFalse())
+			}
+		})
+	})
+
+	Describe("rewriting", func() {
+		It("adds prefixes and suffixes", func() {
+			Expect(tag.AddPrefix("web", "app")).To(Equal("app.web"))
+			Expect(tag.AddPrefix("web", "")).To(Equal("web"))
+			Expect(tag.AddPrefix("", "app")).To(Equal("app"))
+			Expect(tag.AddSuffix("app", "web.1")).To(Equal("app.web.1"))
+			Expect(tag.AddSuffix("app", "")).To(Equal("app"))
+		})
+
+		DescribeTable("StripPrefix",
+			func(t, prefix, expected string, stripped bool) {
+				s, ok := tag.StripPrefix(t, prefix)
+				Expect(ok).To(Equal(stripped))
+				Expect(s).To(Equal(expected))
+			},
+			Entry(nil, "app.web", "app", "web", true),
+			Entry(nil, "app.web.1", "app.web", "1", true),
+			Entry(nil, "app", "app", "", true),
+			Entry("whole parts only", "apps.web", "app", "apps.web", false),
+			Entry(nil, "app.web", "web", "app.web", false),
+			Entry(nil, "app.web", "", "app.web", false),
+		)
+
+		DescribeTable("StripSuffix",
+			func(t, suffix, expected string, stripped bool) {
+				s, ok := tag.StripSuffix(t, suffix)
+				Expect(ok).To(Equal(stripped))
+				Expect(s).To(Equal(expected))
+			},
+			Entry(nil, "app.web", "web", "app", true),
+			Entry(nil, "app.web.1", "web.1", "app", true),
+			Entry(nil, "app", "app", "", true),
+			Entry("whole parts only", "app.myweb", "web", "app.myweb", false),
+			Entry(nil, "app.web", "", "app.web", false),
+		)
+	})
+})
@@ -0,0 +1,293 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+// Package trace propagates trace context, such as W3C Trace Context
+// (https://www.w3.org/TR/trace-context/), through Fluent events so that logs
+// can be correlated with traces end to end. The context is carried either in
+// the record itself or in the Fluent Bit v2 entry metadata.
+package trace
+
+import (
+	"context"
+	"sort"
+
+	"github.com/IBM/fluent-forward-go/fluent/protocol"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// Carrier stores propagated values. It has the same methods as
+// OpenTelemetry's propagation.TextMapCarrier.
+type Carrier interface {
+	Get(key string) string
+	Set(key, value string)
+	Keys() []string
+}
+
+// Propagator injects trace context from a context.Context into a Carrier
+// and extracts it back. It has the same methods as OpenTelemetry's
+// propagation.TextMapPropagator, so an OpenTelemetry propagator can be used
+// with a thin adapter that passes the Carrier through.
+type Propagator interface {
+	Inject(ctx context.Context, carrier Carrier)
+	Extract(ctx context.Context, carrier Carrier) context.Context
+	// Fields returns the keys that Inject sets.
+	Fields() []string
+}
+
+// MapCarrier adapts a record or metadata map to the Carrier interface. Only
+// string values are returned by Get.
+type MapCarrier map[string]interface{}
+
+func (mc MapCarrier) Get(key string) string {
+	s, _ := mc[key].(string)
+	return s
+}
+
+func (mc MapCarrier) Set(key, value string) {
+	mc[key] = value
+}
+
+func (mc MapCarrier) Keys() []string {
+	keys := make([]string, 0, len(mc))
+	for k := range mc {
+		keys = append(keys, k)
+	}
+
+	return keys
+}
+
+// RawRecordCarrier adapts a protocol.RawRecord to the Carrier interface
+// without decoding it. Only string values are returned by Get, and Set
+// replaces the record with an updated copy.
+type RawRecordCarrier struct {
+	Record protocol.RawRecord
+}
+
+func (rc *RawRecordCarrier) Get(key string) string {
+	s, _ := rc.Record.GetString(key)
+	return s
+}
+
+func (rc *RawRecordCarrier) Set(key, value string) {
+	if r, err := rc.Record.Set(key, value); err == nil {
+		rc.Record = r
+	}
+}
+
+func (rc *RawRecordCarrier) Keys() []string {
+	sz, bts, err := msgp.ReadMapHeaderBytes(rc.Record)
+	if err != nil {
+		return nil
+	}
+
+	keys := make([]string, 0, sz)
+
+	for i := uint32(0); i < sz; i++ {
+		var key []byte
+
+		if key, bts, err = msgp.ReadMapKeyZC(bts); err != nil {
+			return keys
+		}
+
+		keys = append(keys, string(key))
+
+		if bts, err = msgp.Skip(bts); err != nil {
+			return keys
+		}
+	}
+
+	return keys
+}
+
+// inject returns the values that p injects for ctx, or nil if there are
+// none.
+func inject(ctx context.Context, p Propagator) MapCarrier {
+	if p == nil {
+		return nil
+	}
+
+	mc := MapCarrier{}
+	p.Inject(ctx, mc)
+
+	if len(mc) == 0 {
+		return nil
+	}
+
+	return mc
+}
+
+// merge returns a copy of m with the values added. The caller's map is
+// never modified.
+func merge(m map[string]interface{}, values MapCarrier) map[string]interface{} {
+	out := make(map[string]interface{}, len(m)+len(values))
+
+	for k, v := range m {
+		out[k] = v
+	}
+
+	for k, v := range values {
+		out[k] = v
+	}
+
+	return out
+}
+
+// injectRecord returns a copy of the record with the values added, and
+// whether the record could carry them.
+func injectRecord(record interface{}, values MapCarrier) (interface{}, bool) {
+	switch r := record.(type) {
+	case map[string]interface{}:
+		return merge(r, values), true
+	case protocol.RawRecord:
+		keys := values.Keys()
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			var err error
+			if r, err = r.Set(k, values[k]); err != nil {
+				return record, false
+			}
+		}
+
+		return r, true
+	}
+
+	return record, false
+}
+
+// CanCarry reports whether InjectRecord can add trace context to the
+// record.
+func CanCarry(record interface{}) bool {
+	switch record.(type) {
+	case map[string]interface{}, protocol.RawRecord:
+		return true
+	}
+
+	return false
+}
+
+// InjectRecord returns a copy of the record with the trace context from ctx
+// added to it. Only records of type map[string]interface{} and
+// protocol.RawRecord, the latter updated with SetPath, can carry the
+// context. Any other record, such as a struct, is returned unchanged, as is
+// the record when ctx has no trace context; use CanCarry to tell the cases
+// apart, or carry the context in the Fluent Bit v2 metadata instead.
+func InjectRecord(ctx context.Context, p Propagator, record interface{}) interface{} {
+	if !CanCarry(record) {
+		return record
+	}
+
+	values := inject(ctx, p)
+	if values == nil {
+		return record
+	}
+
+	out, _ := injectRecord(record, values)
+
+	return out
+}
+
+// InjectEntries returns a copy of the entries with the trace context from ctx
+// added to each one. If metadata is true, the context is added to the Fluent
+// Bit v2 entry metadata; otherwise it is added to the records, as by
+// InjectRecord, and records that cannot carry it are left unchanged. The
+// entries are returned unchanged when ctx has no trace context.
+func InjectEntries(ctx context.Context, p Propagator, entries protocol.EntryList, metadata bool) protocol.EntryList {
+	values := inject(ctx, p)
+	if values == nil {
+		return entries
+	}
+
+	out := make(protocol.EntryList, len(entries))
+
+	for i, e := range entries {
+		if metadata {
+			e.Metadata = merge(e.Metadata, values)
+		} else {
+			e.Record, _ = injectRecord(e.Record, values)
+		}
+
+		out[i] = e
+	}
+
+	return out
+}
+
+// carrier returns the part of the entry that carries trace context,
+// checking the metadata before the record.
+func carrier(p Propagator, entry *protocol.EntryExt) Carrier {
+	carriers := [2]Carrier{MapCarrier(entry.Metadata), nil}
+
+	switch r := entry.Record.(type) {
+	case map[string]interface{}:
+		carriers[1] = MapCarrier(r)
+	case protocol.RawRecord:
+		carriers[1] = &RawRecordCarrier{Record: r}
+	}
+
+	for _, c := range carriers {
+		if c == nil {
+			continue
+		}
+
+		for _, f := range p.Fields() {
+			if c.Get(f) != "" {
+				return c
+			}
+		}
+	}
+
+	return nil
+}
+
+// ExtractEntry returns a copy of ctx with the trace context carried by the
+// entry's metadata or record. If the entry carries none, ctx is returned.
+func ExtractEntry(ctx context.Context, p Propagator, entry protocol.EntryExt) context.Context {
+	if p == nil {
+		return ctx
+	}
+
+	if mc := carrier(p, &entry); mc != nil {
+		return p.Extract(ctx, mc)
+	}
+
+	return ctx
+}
+
+// ExtractEntries returns a copy of ctx with the trace context carried by the
+// first entry that has one. It suits messages whose entries all belong to
+// the same trace; use ExtractEntry to handle entries individually.
+func ExtractEntries(ctx context.Context, p Propagator, entries protocol.EntryList) context.Context {
+	if p == nil {
+		return ctx
+	}
+
+	for i := range entries {
+		if mc := carrier(p, &entries[i]); mc != nil {
+			return p.Extract(ctx, mc)
+		}
+	}
+
+	return ctx
+}
@@ -0,0 +1,198 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package trace_test
+
+import (
+	"context"
+
+	"github.com/IBM/fluent-forward-go/fluent/protocol"
+	"github.com/IBM/fluent-forward-go/fluent/trace"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const (
+	traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+)
+
+var _ = Describe("TraceContext", func() {
+	var (
+		p   trace.TraceContext
+		ctx context.Context
+	)
+
+	BeforeEach(func() {
+		sc, err := trace.ParseTraceparent(traceparent)
+		Expect(err).ToNot(HaveOccurred())
+
+		sc.State = "congo=t61rcWkgMzE"
+		ctx = trace.ContextWithSpanContext(context.Background(), sc)
+	})
+
+	It("injects and extracts the traceparent and tracestate", func() {
+		carrier := trace.MapCarrier{}
+		p.Inject(ctx, carrier)
+
+		Expect(carrier).To(Equal(trace.MapCarrier{
+			"traceparent": traceparent,
+			"tracestate":  "congo=t61rcWkgMzE",
+		}))
+
+		sc := trace.SpanContextFromContext(p.Extract(context.Background(), carrier))
+		Expect(sc).To(Equal(trace.SpanContextFromContext(ctx)))
+		Expect(sc.Sampled()).To(BeTrue())
+	})
+
+	It("injects nothing without a span context", func() {
+		carrier := trace.MapCarrier{}
+		p.Inject(context.Background(), carrier)
+		Expect(carrier).To(BeEmpty())
+	})
+
+	It("ignores an invalid traceparent", func() {
+		ctx := p.Extract(context.Background(), trace.MapCarrier{"traceparent": "nope"})
+		Expect(trace.SpanContextFromContext(ctx).IsValid()).To(BeFalse())
+	})
+
+	DescribeTable("ParseTraceparent",
+		func(s string, valid bool) {
+			_, err := trace.ParseTraceparent(s)
+			if valid {
+				Expect(err).ToNot(HaveOccurred())
+			} else {
+				Expect(err).To(MatchError(trace.ErrInvalidTraceparent))
+			}
+		},
+		Entry("version 00", traceparent, true),
+		Entry("a future version with more fields", "cc-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-what", true),
+		Entry("version 00 with more fields", traceparent+"-what", false),
+		Entry("version ff", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false),
+		Entry("uppercase hex", "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", false),
+		Entry("a zero trace ID", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", false),
+		Entry("a zero span ID", "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false),
+		Entry("too short", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7", false),
+	)
+
+	Describe("records and entries", func() {
+		var (
+			record  map[string]interface{}
+			entries protocol.EntryList
+		)
+
+		BeforeEach(func() {
+			record = map[string]interface{}{"message": "hi"}
+			entries = protocol.EntryList{
+				{Timestamp: protocol.EventTimeNow(), Record: record},
+				{Timestamp: protocol.EventTimeNow(), Record: record},
+			}
+		})
+
+		It("injects into a copy of the record", func() {
+			out := trace.InjectRecord(ctx, p, record)
+
+			Expect(out).To(HaveKeyWithValue("traceparent", traceparent))
+			Expect(out).To(HaveKeyWithValue("message", "hi"))
+			Expect(record).ToNot(HaveKey("traceparent"))
+		})
+
+		It("leaves records that are not maps unchanged", func() {
+			Expect(trace.InjectRecord(ctx, p, "hi")).To(Equal("hi"))
+		})
+
+		It("injects into a raw record", func() {
+			raw, err := protocol.NewRawRecord(record)
+			Expect(err).ToNot(HaveOccurred())
+
+			out, ok := trace.InjectRecord(ctx, p, raw).(protocol.RawRecord)
+			Expect(ok).To(BeTrue())
+			Expect(out.GetString("traceparent")).To(Equal(traceparent))
+			Expect(out.GetString("message")).To(Equal("hi"))
+
+			_, err = raw.Get("traceparent")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("reports which records can carry a trace context", func() {
+			raw, err := protocol.NewRawRecord(record)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(trace.CanCarry(record)).To(BeTrue())
+			Expect(trace.CanCarry(raw)).To(BeTrue())
+			Expect(trace.CanCarry("hi")).To(BeFalse())
+			Expect(trace.CanCarry(nil)).To(BeFalse())
+		})
+
+		It("leaves the record unchanged without a propagator", func() {
+			Expect(trace.InjectRecord(ctx, nil, record)).To(Equal(record))
+		})
+
+		It("injects into the records of copied entries", func() {
+			out := trace.InjectEntries(ctx, p, entries, false)
+
+			Expect(out).To(HaveLen(2))
+			Expect(out[1].Record).To(HaveKeyWithValue("traceparent", traceparent))
+			Expect(out[1].Metadata).To(BeNil())
+			Expect(entries[1].Record).ToNot(HaveKey("traceparent"))
+		})
+
+		It("injects into the metadata of copied entries", func() {
+			out := trace.InjectEntries(ctx, p, entries, true)
+
+			Expect(out[0].Metadata).To(HaveKeyWithValue("traceparent", traceparent))
+			Expect(out[0].Record).ToNot(HaveKey("traceparent"))
+			Expect(entries[0].Metadata).To(BeNil())
+		})
+
+		It("extracts from the first entry that carries a trace context", func() {
+			entries = append(entries, trace.InjectEntries(ctx, p, entries, true)...)
+
+			out := trace.ExtractEntries(context.Background(), p, entries)
+			Expect(trace.SpanContextFromContext(out)).To(Equal(trace.SpanContextFromContext(ctx)))
+		})
+
+		It("extracts from a record", func() {
+			entry := protocol.EntryExt{Record: trace.InjectRecord(ctx, p, record)}
+
+			out := trace.ExtractEntry(context.Background(), p, entry)
+			Expect(trace.SpanContextFromContext(out)).To(Equal(trace.SpanContextFromContext(ctx)))
+		})
+
+		It("extracts from a raw record", func() {
+			raw, err := protocol.NewRawRecord(record)
+			Expect(err).ToNot(HaveOccurred())
+
+			out := trace.InjectEntries(ctx, p, protocol.EntryList{{Record: raw}}, false)
+			Expect(out[0].Record).To(BeAssignableToTypeOf(protocol.RawRecord{}))
+
+			extracted := trace.ExtractEntry(context.Background(), p, out[0])
+			Expect(trace.SpanContextFromContext(extracted)).To(Equal(trace.SpanContextFromContext(ctx)))
+		})
+
+		It("returns the context unchanged if no entry carries one", func() {
+			ctx := context.Background()
+			Expect(trace.ExtractEntries(ctx, p, entries)).To(Equal(ctx))
+		})
+	})
+})
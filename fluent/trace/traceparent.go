@@ -0,0 +1,181 @@
+/*
+MIT License
+
+Copyright contributors to the fluent-forward-go project
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package trace
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+)
+
+const (
+	// TraceparentKey is the key of the W3C traceparent value.
+	TraceparentKey = "traceparent"
+	// TracestateKey is the key of the W3C tracestate value.
+	TracestateKey = "tracestate"
+
+	traceparentLen = 55
+)
+
+var (
+	ErrInvalidTraceparent = errors.New("invalid traceparent")
+)
+
+// SpanContext identifies a span as described by the W3C Trace Context
+// specification.
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Flags   byte
+	// State is the vendor-specific tracestate value.
+	State string
+}
+
+// IsValid reports whether both the trace ID and the span ID are set.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != [16]byte{} && sc.SpanID != [8]byte{}
+}
+
+// Sampled reports whether the sampled flag is set.
+func (sc SpanContext) Sampled() bool {
+	return sc.Flags&0x01 == 0x01
+}
+
+// Traceparent returns the version 00 traceparent value for the span.
+func (sc SpanContext) Traceparent() string {
+	var b [traceparentLen]byte
+
+	b[0], b[1], b[2], b[35], b[52] = '0', '0', '-', '-', '-'
+	hex.Encode(b[3:35], sc.TraceID[:])
+	hex.Encode(b[36:52], sc.SpanID[:])
+	hex.Encode(b[53:], []byte{sc.Flags})
+
+	return string(b[:])
+}
+
+// ParseTraceparent parses a traceparent value. Values with a version other
+// than 00 are accepted as long as they start with a valid version 00 value.
+func ParseTraceparent(s string) (SpanContext, error) {
+	var sc SpanContext
+
+	if len(s) < traceparentLen || s[2] != '-' || s[35] != '-' || s[52] != '-' {
+		return sc, ErrInvalidTraceparent
+	}
+
+	version, ok := decodeHex(s[:2])
+	if !ok || version[0] == 0xff {
+		return sc, ErrInvalidTraceparent
+	}
+
+	if version[0] == 0 && len(s) != traceparentLen ||
+		len(s) > traceparentLen && s[traceparentLen] != '-' {
+		return sc, ErrInvalidTraceparent
+	}
+
+	traceID, ok := decodeHex(s[3:35])
+	if !ok {
+		return sc, ErrInvalidTraceparent
+	}
+
+	spanID, ok := decodeHex(s[36:52])
+	if !ok {
+		return sc, ErrInvalidTraceparent
+	}
+
+	flags, ok := decodeHex(s[53:55])
+	if !ok {
+		return sc, ErrInvalidTraceparent
+	}
+
+	copy(sc.TraceID[:], traceID)
+	copy(sc.SpanID[:], spanID)
+	sc.Flags = flags[0]
+
+	if !sc.IsValid() {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+
+	return sc, nil
+}
+
+// decodeHex decodes lowercase hex only, as the specification requires.
+func decodeHex(s string) ([]byte, bool) {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return nil, false
+		}
+	}
+
+	b, err := hex.DecodeString(s)
+
+	return b, err == nil
+}
+
+type spanContextKey struct{}
+
+// ContextWithSpanContext returns a copy of ctx that carries the span context.
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+// SpanContextFromContext returns the span context carried by ctx. It is
+// invalid if ctx carries none.
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	sc, _ := ctx.Value(spanContextKey{}).(SpanContext)
+	return sc
+}
+
+// TraceContext is a Propagator for the W3C traceparent and tracestate
+// values. It stores the span context in the context.Context with
+// ContextWithSpanContext.
+type TraceContext struct{}
+
+func (TraceContext) Inject(ctx context.Context, carrier Carrier) {
+	sc := SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+
+	carrier.Set(TraceparentKey, sc.Traceparent())
+
+	if sc.State != "" {
+		carrier.Set(TracestateKey, sc.State)
+	}
+}
+
+func (TraceContext) Extract(ctx context.Context, carrier Carrier) context.Context {
+	sc, err := ParseTraceparent(carrier.Get(TraceparentKey))
+	if err != nil {
+		return ctx
+	}
+
+	sc.State = carrier.Get(TracestateKey)
+
+	return ContextWithSpanContext(ctx, sc)
+}
+
+func (TraceContext) Fields() []string {
+	return []string{TraceparentKey, TracestateKey}
+}